@@ -9,15 +9,24 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"github.com/edulustosa/go-pay/internal/api/handlers"
 	"github.com/edulustosa/go-pay/internal/api/router"
+	"github.com/edulustosa/go-pay/internal/config"
+	"github.com/edulustosa/go-pay/internal/database"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/edulustosa/go-pay/internal/services/cursor"
+	"github.com/edulustosa/go-pay/internal/services/keyring"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
 )
 
 func main() {
+	// Adds the request ids to the logs of the requests
+	slog.SetDefault(slog.New(handlers.NewLogHandler(slog.NewJSONHandler(os.Stderr, nil))))
+
 	ctx := context.Background()
 
 	ctx, cancel := signal.NotifyContext(
@@ -40,32 +49,58 @@ func main() {
 }
 
 func run(ctx context.Context) error {
-	pool, err := pgxpool.New(ctx, os.Getenv("POSTGRES_URL"))
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
 	if err != nil {
 		return err
 	}
-	defer pool.Close()
 
-	if err := pool.Ping(ctx); err != nil {
+	documents, err := keyring.Parse(cfg.Documents.Keys, cfg.Documents.KeyID, cfg.Documents.IndexKey)
+	if err != nil {
+		return fmt.Errorf("failed to load the document keys: %w", err)
+	}
+
+	pool, err := database.Connect(ctx, cfg.Database)
+	if err != nil {
 		return err
 	}
+	defer pool.Close()
+
+	f := factories.New(pool, cfg, documents, cursor.NewSigner([]byte(cfg.Auth.JWTSecret)))
 
-	if err := runMigrations(ctx, pool); err != nil {
+	if err := runMigrations(ctx, pool, cfg.Database.MigrationsDir); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	r := router.NewServer(pool)
+	// Encrypts the documents stored in plaintext or with a rotated key
+	encrypted, err := f.MakeUserRepository().EncryptDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt documents: %w", err)
+	}
+	if encrypted > 0 {
+		slog.Info("documents encrypted", "count", encrypted)
+	}
+
+	// Settles the pending withdrawals in background
+	withdrawalService := f.MakeWithdrawalService()
+	go withdrawalService.Run(ctx, cfg.Jobs.SettlementInterval)
+
+	// Compares the balances with the ledger in background
+	reconciliationService := f.MakeReconciliationService(f.ReconciliationConfig())
+	go reconciliationService.Run(ctx, cfg.Jobs.ReconciliationInterval)
+
+	tokens := auth.NewTokenManager([]byte(cfg.Auth.JWTSecret), cfg.Auth.AccessTokenTTL)
+
+	r := router.NewServer(f, tokens, f.MakeAuthService(tokens))
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", os.Getenv("PORT")),
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
 		Handler:      r,
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
 	defer func() {
-		const timeout = 30 * time.Second
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 
 		if err := srv.Shutdown(ctx); err != nil {
@@ -75,7 +110,7 @@ func run(ctx context.Context) error {
 
 	errChan := make(chan error, 1)
 	go func() {
-		slog.Info("server started", "port", os.Getenv("PORT"))
+		slog.Info("server started", "port", cfg.Server.Port)
 		errChan <- srv.ListenAndServe()
 	}()
 
@@ -91,14 +126,14 @@ func run(ctx context.Context) error {
 	return nil
 }
 
-func runMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+func runMigrations(ctx context.Context, pool *pgxpool.Pool, dir string) error {
 	db := stdlib.OpenDBFromPool(pool)
 	defer db.Close()
 
 	provider, err := goose.NewProvider(
 		goose.DialectPostgres,
 		db,
-		os.DirFS("./internal/database/migrations"),
+		os.DirFS(dir),
 	)
 	if err != nil {
 		return err
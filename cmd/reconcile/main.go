@@ -0,0 +1,84 @@
+// Command reconcile compares the balances of every user with the ones
+// rebuilt from their transfers, deposits and withdrawals, printing the
+// report as JSON. It exits with status 2 if there are discrepancies.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/edulustosa/go-pay/internal/config"
+	"github.com/edulustosa/go-pay/internal/database"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/keyring"
+)
+
+// Exit status when discrepancies are found
+const exitDiscrepancies = 2
+
+func main() {
+	path := flag.String("config", os.Getenv("CONFIG_FILE"), "optional file with the configuration")
+	freeze := flag.Bool("freeze", false, "freeze the accounts with discrepancies, overrides RECONCILIATION_FREEZE")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	found, err := run(ctx, *path, *freeze)
+	if err != nil {
+		slog.Error(err.Error())
+
+		cancel()
+		os.Exit(1)
+	}
+
+	if found {
+		cancel()
+		os.Exit(exitDiscrepancies)
+	}
+}
+
+func run(ctx context.Context, path string, freeze bool) (bool, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return false, err
+	}
+
+	// The accounts service reads the users, decrypting their documents
+	documents, err := keyring.Parse(cfg.Documents.Keys, cfg.Documents.KeyID, cfg.Documents.IndexKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to load the document keys: %w", err)
+	}
+
+	pool, err := database.Connect(ctx, cfg.Database)
+	if err != nil {
+		return false, err
+	}
+	defer pool.Close()
+
+	// Lists nothing, so it signs no cursors
+	f := factories.New(pool, cfg, documents, nil)
+
+	reconciliationConfig := f.ReconciliationConfig()
+	reconciliationConfig.Freeze = reconciliationConfig.Freeze || freeze
+
+	reconciliationService := f.MakeReconciliationService(reconciliationConfig)
+	report, err := reconciliationService.Reconcile(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to reconcile balances: %w", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return false, err
+	}
+
+	return len(report.Discrepancies) > 0, nil
+}
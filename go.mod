@@ -4,6 +4,7 @@ go 1.23.0
 
 require (
 	github.com/Rhymond/go-money v1.0.14
+	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/google/uuid v1.6.0
 	github.com/jackc/pgx/v5 v5.7.1
 	github.com/pressly/goose/v3 v3.22.1
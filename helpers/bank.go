@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"errors"
+	"strings"
+)
+
+func onlyDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// Verify if a bank code is a valid 3 digits COMPE code
+func ParseBankCode(code string) error {
+	if len(code) != 3 || !onlyDigits(code) {
+		return errors.New("bank code must have 3 digits")
+	}
+
+	if code == "000" {
+		return errors.New("invalid bank code")
+	}
+
+	return nil
+}
+
+// Verify if a branch has 4 digits and an optional check digit, e.g. 1234-5
+func ParseBranch(branch string) error {
+	number, digit, hasDigit := strings.Cut(branch, "-")
+
+	if len(number) != 4 || !onlyDigits(number) {
+		return errors.New("branch must have 4 digits")
+	}
+
+	if hasDigit && (len(digit) != 1 || (!onlyDigits(digit) && digit != "X")) {
+		return errors.New("branch check digit must be a single digit or X")
+	}
+
+	return nil
+}
+
+// Verify if an account has up to 12 digits followed by its check digit, e.g. 12345-6
+func ParseAccountNumber(account string) error {
+	number, digit, hasDigit := strings.Cut(account, "-")
+
+	if !hasDigit {
+		return errors.New("account must have a check digit separated by a dash")
+	}
+
+	if len(number) > 12 || !onlyDigits(number) {
+		return errors.New("account must have between 1 and 12 digits")
+	}
+
+	if len(digit) != 1 || (!onlyDigits(digit) && digit != "X") {
+		return errors.New("account check digit must be a single digit or X")
+	}
+
+	if strings.Trim(number, "0") == "" {
+		return errors.New("invalid account")
+	}
+
+	return nil
+}
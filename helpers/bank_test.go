@@ -0,0 +1,41 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/edulustosa/go-pay/helpers"
+)
+
+func TestParseBankAccount(t *testing.T) {
+	testCases := []struct {
+		parse func(string) error
+		value string
+		want  bool
+	}{
+		{helpers.ParseBankCode, "001", true},
+		{helpers.ParseBankCode, "260", true},
+		{helpers.ParseBankCode, "1", false},
+		{helpers.ParseBankCode, "000", false},
+		{helpers.ParseBankCode, "abc", false},
+		{helpers.ParseBranch, "1234", true},
+		{helpers.ParseBranch, "1234-5", true},
+		{helpers.ParseBranch, "1234-X", true},
+		{helpers.ParseBranch, "123", false},
+		{helpers.ParseBranch, "1234-56", false},
+		{helpers.ParseAccountNumber, "12345-6", true},
+		{helpers.ParseAccountNumber, "123456789012-X", true},
+		{helpers.ParseAccountNumber, "123456", false},
+		{helpers.ParseAccountNumber, "0000-1", false},
+		{helpers.ParseAccountNumber, "1234567890123-4", false},
+	}
+
+	for _, tc := range testCases {
+		err := tc.parse(tc.value)
+		if err != nil && tc.want {
+			t.Errorf("parse(%s) got %v, want nil", tc.value, err)
+		}
+		if err == nil && !tc.want {
+			t.Errorf("parse(%s) got nil, want error", tc.value)
+		}
+	}
+}
@@ -23,16 +23,43 @@ func calculateDigit(document string, factor int) (int, error) {
 	return rest, nil
 }
 
-// Verify if a document is valid CPF
+// Lengths of the normalized documents
+const (
+	CPFLength  = 11
+	CNPJLength = 14
+)
+
+// Remove the formatting characters from a document, the letters of
+// alphanumeric CNPJs are upper cased.
+func NormalizeDocument(document string) string {
+	document = strings.NewReplacer(".", "", "-", "", "/", "", " ", "").Replace(document)
+	return strings.ToUpper(document)
+}
+
+// Emails are compared ignoring the case, as the providers do, so they
+// are stored trimmed and lower cased.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Verify if a document is a valid CPF or CNPJ
 func ParseDocument(document string) error {
-	document = strings.ReplaceAll(document, ".", "")
-	document = strings.ReplaceAll(document, "-", "")
+	if len(NormalizeDocument(document)) == CNPJLength {
+		return ParseCNPJ(document)
+	}
+
+	return ParseCPF(document)
+}
 
-	if len(document) != 11 {
-		return errors.New("document must have 11 characters")
+// Verify if a document is a valid CPF
+func ParseCPF(document string) error {
+	document = NormalizeDocument(document)
+
+	if len(document) != CPFLength {
+		return errors.New("CPF must have 11 digits")
 	}
 
-	if strings.Repeat(string(document[0]), 11) == document {
+	if strings.Repeat(string(document[0]), CPFLength) == document {
 		return errors.New("document must not have all characters equal")
 	}
 
@@ -51,3 +78,52 @@ func ParseDocument(document string) error {
 
 	return nil
 }
+
+// Calculates a CNPJ check digit, each character is worth its ASCII
+// code minus 48 so the digits keep their value in alphanumeric CNPJs.
+func calculateCNPJDigit(document string) int {
+	total := 0
+	weight := len(document) - 7
+	for _, c := range document {
+		total += int(c-'0') * weight
+
+		weight--
+		if weight < 2 {
+			weight = 9
+		}
+	}
+
+	rest := total % 11
+	if rest < 2 {
+		return 0
+	}
+	return 11 - rest
+}
+
+// Verify if a document is a valid CNPJ, either numeric or in the
+// alphanumeric format, e.g. 12.ABC.345/01DE-35
+func ParseCNPJ(document string) error {
+	document = NormalizeDocument(document)
+
+	if len(document) != CNPJLength {
+		return errors.New("CNPJ must have 14 characters")
+	}
+
+	base, digits := document[:12], document[12:]
+	if strings.Trim(base, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" || !onlyDigits(digits) {
+		return errors.New("CNPJ must have 12 letters or digits followed by 2 digits")
+	}
+
+	if strings.Repeat(string(document[0]), CNPJLength) == document {
+		return errors.New("document must not have all characters equal")
+	}
+
+	digit1 := calculateCNPJDigit(base)
+	digit2 := calculateCNPJDigit(base + strconv.Itoa(digit1))
+
+	if digit1 != int(digits[0]-'0') || digit2 != int(digits[1]-'0') {
+		return errors.New("invalid document")
+	}
+
+	return nil
+}
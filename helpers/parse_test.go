@@ -30,3 +30,39 @@ func TestParseDocument(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCNPJ(t *testing.T) {
+	testCases := []struct {
+		document string
+		want     bool
+	}{
+		{"11.222.333/0001-81", true},
+		{"11222333000181", true},
+		{"11.222.333/0001-82", false},
+		{"12.ABC.345/01DE-35", true},
+		{"12abc34501de35", true},
+		{"12.ABC.345/01DE-36", false},
+		{"12.ABC.345/01DE-3A", false},
+		{"12.A$C.345/01DE-35", false},
+		{"00000000000000", false},
+		{"529.982.247-25", false},
+	}
+
+	for _, tc := range testCases {
+		err := helpers.ParseCNPJ(tc.document)
+		if err != nil && tc.want {
+			t.Errorf("ParseCNPJ(%s) got %v, want nil", tc.document, err)
+		}
+		if err == nil && !tc.want {
+			t.Errorf("ParseCNPJ(%s) got nil, want error", tc.document)
+		}
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	for _, email := range []string{"johndoe@email.com", " JohnDoe@Email.com ", "JOHNDOE@EMAIL.COM"} {
+		if got := helpers.NormalizeEmail(email); got != "johndoe@email.com" {
+			t.Errorf("NormalizeEmail(%q) got %q, want johndoe@email.com", email, got)
+		}
+	}
+}
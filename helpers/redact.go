@@ -0,0 +1,32 @@
+package helpers
+
+import "strings"
+
+// Redacted replaces secrets, like passwords and tokens, in logs
+const Redacted = "[REDACTED]"
+
+// Characters kept at the end of a masked document
+const visibleDocumentChars = 4
+
+// Masks all but the last characters of a CPF or CNPJ, enough
+// for its owner to recognize it.
+func MaskDocument(document string) string {
+	document = NormalizeDocument(document)
+	if len(document) <= visibleDocumentChars {
+		return strings.Repeat("*", len(document))
+	}
+
+	hidden := len(document) - visibleDocumentChars
+	return strings.Repeat("*", hidden) + document[hidden:]
+}
+
+// Masks the local part of an email but its first character,
+// the domain is kept.
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return Redacted
+	}
+
+	return local[:1] + "***@" + domain
+}
@@ -0,0 +1,43 @@
+package helpers_test
+
+import (
+	"testing"
+
+	"github.com/edulustosa/go-pay/helpers"
+)
+
+func TestMaskDocument(t *testing.T) {
+	testCases := []struct {
+		document string
+		want     string
+	}{
+		{"529.982.247-25", "*******4725"},
+		{"11.222.333/0001-81", "**********0181"},
+		{"123", "***"},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := helpers.MaskDocument(tc.document); got != tc.want {
+			t.Errorf("MaskDocument(%s) got %s, want %s", tc.document, got, tc.want)
+		}
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	testCases := []struct {
+		email string
+		want  string
+	}{
+		{"johndoe@email.com", "j***@email.com"},
+		{"j@email.com", "j***@email.com"},
+		{"@email.com", helpers.Redacted},
+		{"not an email", helpers.Redacted},
+	}
+
+	for _, tc := range testCases {
+		if got := helpers.MaskEmail(tc.email); got != tc.want {
+			t.Errorf("MaskEmail(%s) got %s, want %s", tc.email, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/account"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/google/uuid"
+)
+
+// Handles the errors shared by the account status
+// endpoints, returns false if the error is unknown.
+func handleAccountStatusError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, account.ErrUserNotFound) {
+		handleError(w, http.StatusNotFound, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, account.ErrAccountFrozen) ||
+		errors.Is(err, account.ErrNotFrozen) {
+		handleError(w, http.StatusConflict, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
+func HandleFreezeUser(f *factories.Factory) http.HandlerFunc {
+	accountService := f.MakeAccountService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			handleUnauthorized(w, "missing bearer access token")
+			return
+		}
+
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		req, problems, err := decode[dtos.FreezeUserDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		err = accountService.Freeze(r.Context(), userID, req.Reason, principal.UserID)
+		if err != nil {
+			if handleAccountStatusError(w, err) {
+				return
+			}
+
+			slog.Error("failed to freeze account", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		slog.Warn("account frozen", "user", userID, "by", principal.UserID, "reason", req.Reason)
+		recordAudit(r, auditService, models.AuditUserFreeze, userID,
+			JSON{"status": models.StatusActive},
+			JSON{"status": models.StatusFrozen, "reason": req.Reason},
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleUnfreezeUser(f *factories.Factory) http.HandlerFunc {
+	accountService := f.MakeAccountService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			handleUnauthorized(w, "missing bearer access token")
+			return
+		}
+
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if err := accountService.Unfreeze(r.Context(), userID); err != nil {
+			if handleAccountStatusError(w, err) {
+				return
+			}
+
+			slog.Error("failed to unfreeze account", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		slog.Warn("account unfrozen", "user", userID, "by", principal.UserID)
+		recordAudit(r, auditService, models.AuditUserUnfreeze, userID,
+			JSON{"status": models.StatusFrozen},
+			JSON{"status": models.StatusActive},
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
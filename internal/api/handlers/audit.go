@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/audit"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/google/uuid"
+)
+
+// Returns the address of the client connected to the server,
+// forwarding headers are not trusted as clients can set them.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// Records the audit event of an operation that already succeeded, so
+// failures are logged instead of failing the request.
+func recordAudit(
+	r *http.Request,
+	auditService *audit.Service,
+	action models.AuditAction,
+	target uuid.UUID,
+	before, after any,
+) {
+	var actor uuid.UUID
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		actor = principal.UserID
+	}
+
+	recordAuditAs(r, auditService, actor, action, target, before, after)
+}
+
+// recordAuditAs records the event of an operation done without an access
+// token, by the actor proven by other means like a password or a code.
+func recordAuditAs(
+	r *http.Request,
+	auditService *audit.Service,
+	actor uuid.UUID,
+	action models.AuditAction,
+	target uuid.UUID,
+	before, after any,
+) {
+	// Only the ids set by the RequestID middleware fit the column
+	requestID, _ := RequestIDFrom(r.Context())
+	if !validRequestID(requestID) {
+		requestID = ""
+	}
+
+	entry := audit.Entry{
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Before:    before,
+		After:     after,
+		RequestID: requestID,
+		SourceIP:  sourceIP(r),
+	}
+
+	if err := auditService.Record(r.Context(), entry); err != nil {
+		slog.ErrorContext(r.Context(), "failed to record audit event", "error", err, "action", action, "target", target)
+	}
+}
+
+// The balance of a wallet before and after an operation that moved
+// money, recorded along with the operation in its audit event.
+func balanceBefore(change wallet.Change) JSON {
+	return JSON{"currency": change.Currency, "amount": change.Before}
+}
+
+func balanceAfter(change wallet.Change) JSON {
+	return JSON{"currency": change.Currency, "amount": change.After}
+}
+
+// Parses an optional query parameter, returns the zero value
+// of T if the parameter is missing.
+func parseQuery[T any](
+	r *http.Request,
+	name string,
+	parse func(string) (T, error),
+	problems map[string]string,
+	problem string,
+) T {
+	var v T
+
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return v
+	}
+
+	v, err := parse(raw)
+	if err != nil {
+		problems[name] = problem
+	}
+
+	return v
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339, s)
+}
+
+// oneOf returns a parser of the query values restricted to the values given
+func oneOf[T ~string](values ...T) func(string) (T, error) {
+	return func(s string) (T, error) {
+		if !slices.Contains(values, T(s)) {
+			return "", fmt.Errorf("invalid value %q", s)
+		}
+
+		return T(s), nil
+	}
+}
+
+// HandleGetAuditEvents filters the events by the actor and target
+// query parameters and by the [from, to) RFC 3339 time range.
+func HandleGetAuditEvents(f *factories.Factory) http.HandlerFunc {
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		problems := make(map[string]string)
+		actor := parseQuery(r, "actor", uuid.Parse, problems, "must be a valid UUID")
+		target := parseQuery(r, "target", uuid.Parse, problems, "must be a valid UUID")
+		from := parseQuery(r, "from", parseTime, problems, "must be a RFC 3339 timestamp")
+		to := parseQuery(r, "to", parseTime, problems, "must be a RFC 3339 timestamp")
+		if len(problems) > 0 {
+			handleInvalidRequest(w, problems)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		events, err := auditService.Find(r.Context(), actor, target, from, to, page)
+		if err != nil {
+			if errors.Is(err, audit.ErrInvalidRange) {
+				handleInvalidRequest(w, map[string]string{"from": err.Error()})
+				return
+			}
+
+			slog.Error("failed to get audit events", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		eventsDTO := make([]dtos.AuditEventResponseDTO, len(events))
+		for i, event := range events {
+			eventsDTO[i] = dtos.AuditEventResponseDTO{
+				ID:        event.ID,
+				Action:    event.Action,
+				Target:    event.TargetID,
+				Before:    event.Before,
+				After:     event.After,
+				RequestID: event.RequestID,
+				SourceIP:  event.SourceIP,
+				CreatedAt: event.CreatedAt.Time,
+			}
+
+			if event.ActorID.Valid {
+				actor := uuid.UUID(event.ActorID.Bytes)
+				eventsDTO[i].Actor = &actor
+			}
+		}
+
+		encode(w, http.StatusOK, JSON{"events": eventsDTO})
+	}
+}
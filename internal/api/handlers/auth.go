@@ -0,0 +1,468 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/apikey"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/google/uuid"
+)
+
+func handleUnauthorized(w http.ResponseWriter, details string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="go-pay"`)
+	handleError(w, http.StatusUnauthorized, Error{
+		Message: "unauthorized",
+		Details: details,
+	})
+}
+
+// Returns the authenticated user the request acts on behalf of. The user
+// claimed by the request, like the payer of a transfer or the owner of the
+// resource, must be the authenticated one or be left empty.
+func authorizeUser(
+	w http.ResponseWriter,
+	r *http.Request,
+	claimed uuid.UUID,
+) (uuid.UUID, bool) {
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		handleUnauthorized(w, "missing bearer access token")
+		return uuid.Nil, false
+	}
+
+	if claimed != uuid.Nil && claimed != principal.UserID {
+		handleError(w, http.StatusForbidden, Error{
+			Message: "forbidden",
+			Details: "the authenticated user cannot act on behalf of another user",
+		})
+		return uuid.Nil, false
+	}
+
+	return principal.UserID, true
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+type sessionChecker interface {
+	CheckSession(ctx context.Context, principal auth.Principal) error
+}
+
+func authenticateAccessToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	tokens *auth.TokenManager,
+	sessions sessionChecker,
+	token string,
+) (auth.Principal, bool) {
+	principal, err := tokens.Parse(token)
+	if err != nil {
+		handleUnauthorized(w, "invalid or expired access token")
+		return auth.Principal{}, false
+	}
+
+	err = sessions.CheckSession(r.Context(), principal)
+	if errors.Is(err, auth.ErrSessionInactive) || errors.Is(err, auth.ErrAccountInactive) {
+		handleUnauthorized(w, err.Error())
+		return auth.Principal{}, false
+	}
+
+	if err != nil {
+		slog.Error("failed to check session", "error", err, "session", principal.SessionID)
+		handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+		return auth.Principal{}, false
+	}
+
+	return principal, true
+}
+
+// Authenticate only lets through requests with a valid bearer access
+// token of an active session, adding its principal to the request context.
+func Authenticate(
+	tokens *auth.TokenManager,
+	sessions sessionChecker,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				handleUnauthorized(w, "missing bearer access token")
+				return
+			}
+
+			if apikey.IsAPIKey(token) {
+				handleError(w, http.StatusForbidden, Error{
+					Message: "forbidden",
+					Details: "api keys cannot access this endpoint",
+				})
+				return
+			}
+
+			principal, ok := authenticateAccessToken(w, r, tokens, sessions, token)
+			if !ok {
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission only lets through authenticated users whose role was
+// granted the permission, it must wrap a handler behind Authenticate.
+func RequirePermission(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFrom(r.Context())
+			if !ok {
+				handleUnauthorized(w, "missing bearer access token")
+				return
+			}
+
+			if !principal.Can(permission) {
+				handleError(w, http.StatusForbidden, Error{
+					Message: "forbidden",
+					Details: fmt.Sprintf("the authenticated user lacks the %s permission", permission),
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthenticateAPIKey also lets through requests made with an API key
+// granted the given scope, used by the endpoints merchants integrate
+// with from their backends.
+func AuthenticateAPIKey(
+	f *factories.Factory,
+	tokens *auth.TokenManager,
+	sessions sessionChecker,
+	scope models.Scope,
+) func(http.Handler) http.Handler {
+	apiKeyService := f.MakeAPIKeyService()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				handleUnauthorized(w, "missing bearer access token or api key")
+				return
+			}
+
+			var principal auth.Principal
+			if apikey.IsAPIKey(token) {
+				key, err := apiKeyService.Authenticate(r.Context(), token)
+				if err != nil {
+					handleUnauthorized(w, "invalid or revoked api key")
+					return
+				}
+
+				if !key.HasScope(scope) {
+					handleError(w, http.StatusForbidden, Error{
+						Message: "forbidden",
+						Details: fmt.Sprintf("the api key lacks the %s scope", scope),
+					})
+					return
+				}
+
+				principal = auth.Principal{
+					UserID:   key.UserID,
+					Role:     models.RoleMerchant,
+					APIKeyID: key.ID,
+					Scopes:   key.Scopes,
+				}
+			} else if principal, ok = authenticateAccessToken(w, r, tokens, sessions, token); !ok {
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Identifies the device of the request. The address of the connection
+// is used since forwarding headers can be set by anyone.
+func clientFrom(r *http.Request) auth.Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return auth.Client{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
+}
+
+func tokensResponse(tokens auth.Tokens) dtos.AccessTokenResponseDTO {
+	return dtos.AccessTokenResponseDTO{
+		AccessToken:      tokens.Access.Token,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(time.Until(tokens.Access.ExpiresAt).Seconds()),
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresIn: int(time.Until(tokens.RefreshExpiresAt).Seconds()),
+	}
+}
+
+func HandleLogin(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.LoginDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		client := clientFrom(r)
+		issued, err := authService.Login(r.Context(), req, client)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				handleUnauthorized(w, "wrong email, document or password")
+				return
+			}
+
+			slog.Error("failed to login", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAuditAs(r, auditService, issued.UserID, models.AuditSessionCreate, issued.SessionID, nil, JSON{
+			"userAgent": client.UserAgent,
+			"ip":        client.IP,
+		})
+		encode(w, http.StatusOK, tokensResponse(issued))
+	}
+}
+
+func HandleRefresh(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.RefreshTokenDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		issued, err := authService.Refresh(r.Context(), req.RefreshToken, clientFrom(r))
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidRefreshToken) ||
+				errors.Is(err, auth.ErrRefreshTokenReused) {
+				handleUnauthorized(w, "invalid, expired or revoked refresh token")
+				return
+			}
+
+			slog.Error("failed to refresh tokens", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		encode(w, http.StatusOK, tokensResponse(issued))
+	}
+}
+
+func HandleGetSessions(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			handleUnauthorized(w, "missing bearer access token")
+			return
+		}
+
+		sessions, err := authService.FindSessions(r.Context(), principal.UserID)
+		if err != nil {
+			slog.Error("failed to get sessions", "error", err, "user", principal.UserID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		sessionsDTO := make([]dtos.SessionResponseDTO, len(sessions))
+		for i, session := range sessions {
+			sessionsDTO[i] = dtos.SessionResponseDTO{
+				ID:         session.ID,
+				UserAgent:  session.UserAgent,
+				IP:         session.IP,
+				Current:    session.ID == principal.SessionID,
+				LastUsedAt: session.LastUsedAt.Time,
+				ExpiresAt:  session.ExpiresAt.Time,
+				CreatedAt:  session.CreatedAt.Time,
+			}
+		}
+
+		encode(w, http.StatusOK, JSON{"sessions": sessionsDTO})
+	}
+}
+
+func HandleRevokeSession(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		sessionID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		if err := authService.RevokeSession(r.Context(), userID, sessionID); err != nil {
+			if errors.Is(err, auth.ErrSessionNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to revoke session", "error", err, "session", sessionID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditSessionRevoke, sessionID, nil, JSON{"user": userID})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleRevokeSessions(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		revoked, err := authService.RevokeSessions(r.Context(), userID)
+		if err != nil {
+			slog.Error("failed to revoke sessions", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditSessionRevokeAll, userID, nil, JSON{"revoked": revoked})
+		encode(w, http.StatusOK, JSON{"revoked": revoked})
+	}
+}
+
+func HandleForgotPassword(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.ForgotPasswordDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		if err := authService.ForgotPassword(r.Context(), req.Email); err != nil {
+			slog.Error("failed to issue password reset token", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		// Accepted even for unknown emails to not reveal the registered ones
+		encode(w, http.StatusAccepted, JSON{
+			"message": "if the email is registered, a reset code was sent to it",
+		})
+	}
+}
+
+func HandleResetPassword(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.ResetPasswordDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		userID, err := authService.ResetPassword(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidResetToken) {
+				handleError(w, http.StatusBadRequest, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to reset password", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		// The reset code proves the actor owns the email
+		recordAuditAs(r, auditService, userID, models.AuditPasswordReset, userID, nil, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleChangePassword(f *factories.Factory, tokens *auth.TokenManager) http.HandlerFunc {
+	authService := f.MakeAuthService(tokens)
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			handleUnauthorized(w, "missing bearer access token")
+			return
+		}
+
+		req, problems, err := decode[dtos.ChangePasswordDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		if err := authService.ChangePassword(r.Context(), principal, req); err != nil {
+			if handleStepUpError(w, err) {
+				return
+			}
+
+			if errors.Is(err, auth.ErrWrongPassword) {
+				handleError(w, http.StatusForbidden, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				handleUnauthorized(w, "the authenticated user no longer exists")
+				return
+			}
+
+			slog.Error("failed to change password", "error", err, "user", principal.UserID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditPasswordChange, principal.UserID, nil, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -1,19 +1,35 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
-	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/helpers"
+	"github.com/edulustosa/go-pay/internal/config"
+	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/dtos"
 	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/account"
+	"github.com/edulustosa/go-pay/internal/services/apikey"
+	"github.com/edulustosa/go-pay/internal/services/cursor"
+	"github.com/edulustosa/go-pay/internal/services/deposit"
+	"github.com/edulustosa/go-pay/internal/services/fraud"
 	"github.com/edulustosa/go-pay/internal/services/transfer"
 	"github.com/edulustosa/go-pay/internal/services/user"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/edulustosa/go-pay/internal/services/withdrawal"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type JSON map[string]any
@@ -85,14 +101,27 @@ func handleInvalidRequest(w http.ResponseWriter, problems map[string]string) {
 	handleError(w, http.StatusBadRequest, errors...)
 }
 
-func HandleCreateUser(pool *pgxpool.Pool) http.HandlerFunc {
-	usersRepository := repo.NewUserRepository(pool)
-	userService := user.NewService(usersRepository)
+// handleDecodeError responds to the errors of decode, the bodies over the
+// limit of LimitBody without a declared length are only found reading them.
+func handleDecodeError(w http.ResponseWriter, problems map[string]string, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		handleError(w, http.StatusRequestEntityTooLarge, bodyTooLarge(tooLarge.Limit))
+		return
+	}
+
+	handleInvalidRequest(w, problems)
+}
+
+func HandleCreateUser(f *factories.Factory) http.HandlerFunc {
+	userService := f.MakeUserService()
+	verificationService := f.MakeVerificationService()
+	auditService := f.MakeAuditService()
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		req, problems, err := decode[dtos.UserDTO](r)
 		if err != nil {
-			handleInvalidRequest(w, problems)
+			handleDecodeError(w, problems, err)
 			return
 		}
 
@@ -111,77 +140,1378 @@ func HandleCreateUser(pool *pgxpool.Pool) http.HandlerFunc {
 			return
 		}
 
+		// The user can ask for a new token if this one is lost
+		if err := verificationService.Send(r.Context(), userID); err != nil {
+			slog.Error("failed to send verification email", "error", err, "user", userID)
+		}
+
+		if u, err := userService.FindByID(r.Context(), userID); err == nil {
+			recordAudit(r, auditService, models.AuditUserCreate, userID, nil, userResponse(u, uuid.Nil))
+		}
+
 		encode(w, http.StatusCreated, JSON{"id": userID})
 	}
 }
 
-func HandleGetUsers(pool *pgxpool.Pool) http.HandlerFunc {
-	usersRepository := repo.NewUserRepository(pool)
-	userService := user.NewService(usersRepository)
+// parseCursor returns a parser of the cursor tokens signed by the signer
+// for the listing in the scope
+func parseCursor(cursors *cursor.Signer, scope string) func(string) (*models.Cursor, error) {
+	return func(token string) (*models.Cursor, error) {
+		c, err := cursors.Decode(token, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		return &c, nil
+	}
+}
+
+// cursorToken returns an empty token when there is no cursor
+func cursorToken(cursors *cursor.Signer, scope string, c *models.Cursor) string {
+	if c == nil {
+		return ""
+	}
+
+	return cursors.Encode(*c, scope)
+}
+
+// HandleGetUsers filters the users by the role, status, name prefix, email
+// and [createdFrom, createdTo) RFC 3339 creation range query parameters.
+// They are sorted by createdAt, name or email, in asc or desc order.
+//
+// Sorted by createdAt, the users are paged by the cursor query parameter,
+// unless a page number is given. The other sorts are paged by page number.
+func HandleGetUsers(f *factories.Factory, pages config.Pagination) http.HandlerFunc {
+	userService := f.MakeUserService()
+	cursors := f.MakeCursorSigner()
+
+	parseRole := oneOf(
+		models.RoleCommon,
+		models.RoleMerchant,
+		models.RoleAdmin,
+		models.RoleSupport,
+		models.RoleFinance,
+	)
+	parseStatus := oneOf(models.StatusActive, models.StatusFrozen, models.StatusClosed)
+	parseSort := oneOf(models.UserSortCreatedAt, models.UserSortName, models.UserSortEmail)
+	parseOrder := oneOf("asc", "desc")
+	parsePageSize := func(s string) (int, error) {
+		size, err := strconv.Atoi(s)
+		if err == nil && (size < 1 || size > pages.MaxPageSize) {
+			err = errors.New("page size out of range")
+		}
+
+		return size, err
+	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-		if page == 0 {
-			page = 1
+		timestampProblem := "must be a RFC 3339 timestamp"
+
+		problems := make(map[string]string)
+		role := parseQuery(r, "role", parseRole, problems, "must be a valid role")
+		status := parseQuery(r, "status", parseStatus, problems, "must be ACTIVE, FROZEN or CLOSED")
+		from := parseQuery(r, "createdFrom", parseTime, problems, timestampProblem)
+		to := parseQuery(r, "createdTo", parseTime, problems, timestampProblem)
+		sort := parseQuery(r, "sort", parseSort, problems, "must be createdAt, name or email")
+		order := parseQuery(r, "order", parseOrder, problems, "must be asc or desc")
+		pageSize := parseQuery(r, "pageSize", parsePageSize, problems,
+			fmt.Sprintf("must be between 1 and %d", pages.MaxPageSize),
+		)
+
+		// The cursors only page the listing with the filters they came from
+		query := r.URL.Query()
+		scope := cursor.Scope("users",
+			query.Get("role"),
+			query.Get("status"),
+			query.Get("name"),
+			query.Get("email"),
+			query.Get("createdFrom"),
+			query.Get("createdTo"),
+			query.Get("sort"),
+			query.Get("order"),
+		)
+		after := parseQuery(r, "cursor", parseCursor(cursors, scope), problems, "must be a valid cursor")
+
+		byCursor := (sort == "" || sort == models.UserSortCreatedAt) &&
+			!r.URL.Query().Has("page")
+		if after != nil && !byCursor {
+			problems["cursor"] = "can only be used sorting by createdAt and without page"
+		}
+
+		if len(problems) > 0 {
+			handleInvalidRequest(w, problems)
+			return
+		}
+
+		if pageSize == 0 {
+			pageSize = pages.PageSize
+		}
+
+		filter := models.UserFilter{
+			Role:        role,
+			NamePrefix:  r.URL.Query().Get("name"),
+			Email:       r.URL.Query().Get("email"),
+			Status:      status,
+			CreatedFrom: pgtype.Timestamp{Time: from, Valid: !from.IsZero()},
+			CreatedTo:   pgtype.Timestamp{Time: to, Valid: !to.IsZero()},
+			Sort:        sort,
+			Descending:  order == "desc",
+		}
+
+		var (
+			res dtos.UserPageResponseDTO
+			err error
+		)
+		if byCursor {
+			var page models.Page[dtos.UserResponseDTO]
+			page, err = userService.FindManyAfter(r.Context(), filter, after, pageSize)
+			res = dtos.UserPageResponseDTO{
+				Users: page.Items,
+				Pagination: dtos.PaginationDTO{
+					PageSize: pageSize,
+					Total:    page.Total,
+					Next:     cursorToken(cursors, scope, page.Next),
+					Prev:     cursorToken(cursors, scope, page.Prev),
+				},
+			}
+		} else {
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+
+			res, err = userService.FindMany(r.Context(), filter, page, pageSize)
 		}
 
-		users, err := userService.FindMany(r.Context(), page)
 		if err != nil {
+			if errors.Is(err, user.ErrInvalidRange) {
+				handleInvalidRequest(w, map[string]string{"createdFrom": err.Error()})
+				return
+			}
+
 			slog.Error("failed to get users", "error", err)
 			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
 			return
 		}
 
-		encode(w, http.StatusOK, JSON{"users": users})
+		encode(w, http.StatusOK, res)
+	}
+}
+
+// Handles the errors shared by the endpoints that move funds between
+// users, returns false if the error is unknown.
+func handleTransferError(w http.ResponseWriter, err error) bool {
+	if handleStepUpError(w, err) {
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrUserNotFound) ||
+		errors.Is(err, transfer.ErrWalletNotFound) {
+		handleError(w, http.StatusNotFound, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrAccountFrozen) {
+		handleError(w, http.StatusForbidden, Error{
+			Message: err.Error(),
+			Details: "the payer or the payee account is frozen",
+		})
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrKYCLevelRequired) {
+		handleError(w, http.StatusForbidden, Error{
+			Message: err.Error(),
+			Details: "submit the kyc data of the next level to raise the limit",
+		})
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrMerchantNotAllowed) ||
+		errors.Is(err, transfer.ErrEmailNotVerified) {
+		handleError(w, http.StatusForbidden, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrInsufficientFunds) {
+		handleError(w, http.StatusUnprocessableEntity, Error{
+			Message: err.Error(),
+			Details: "payer has insufficient funds",
+		})
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrConversionUnavailable) {
+		handleError(w, http.StatusUnprocessableEntity, Error{
+			Message: err.Error(),
+			Details: "no exchange rate available for the requested currencies",
+		})
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrTransactionDenied) {
+		handleError(w, http.StatusForbidden, Error{
+			Message: err.Error(),
+			Details: "the transaction was flagged by the fraud screening",
+		})
+		return true
+	}
+
+	if errors.Is(err, transfer.ErrTransactionNotAuthorized) {
+		handleError(w, http.StatusUnauthorized, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
+func HandleGetUser(f *factories.Factory) http.HandlerFunc {
+	userService := f.MakeUserService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		viewer, ok := authorizeUser(w, r, userID)
+		if !ok {
+			return
+		}
+
+		u, err := userService.FindByID(r.Context(), userID)
+		if err != nil {
+			handleError(w, http.StatusNotFound, Error{
+				Message: "user not found",
+			})
+			return
+		}
+
+		encode(w, http.StatusOK, userResponse(u, viewer))
+	}
+}
+
+// Only the owner, identified by viewer, sees the whole document
+func userResponse(u models.User, viewer uuid.UUID) dtos.UserResponseDTO {
+	document := u.Document
+	if viewer != u.ID {
+		document = helpers.MaskDocument(document)
+	}
+
+	return dtos.UserResponseDTO{
+		ID:            u.ID,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		Document:      document,
+		DocumentType:  u.DocumentType,
+		Email:         u.Email,
+		Balance:       u.Balance,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerifiedAt.Valid,
+		Status:        u.Status,
 	}
 }
 
-func HandleTransfer(pool *pgxpool.Pool) http.HandlerFunc {
-	transferService := factories.MakeTransferService(pool)
+func HandleUpdateUser(f *factories.Factory) http.HandlerFunc {
+	userService := f.MakeUserService()
+	verificationService := f.MakeVerificationService()
+	auditService := f.MakeAuditService()
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		req, problems, err := decode[dtos.TransactionDTO](r)
+		userID, err := uuid.Parse(r.PathValue("id"))
 		if err != nil {
-			handleInvalidRequest(w, problems)
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
 			return
 		}
 
-		_, err = transferService.NewTransaction(r.Context(), req)
+		viewer, ok := authorizeUser(w, r, userID)
+		if !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.UpdateUserDTO](r)
 		if err != nil {
-			if errors.Is(err, transfer.ErrUserNotFound) {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		// Snapshot for the audit, UpdateProfile reports the missing user
+		before, _ := userService.FindByID(r.Context(), userID)
+
+		u, err := userService.UpdateProfile(r.Context(), userID, req)
+		if err != nil {
+			if errors.Is(err, user.ErrUserNotFound) {
 				handleError(w, http.StatusNotFound, Error{
 					Message: err.Error(),
 				})
 				return
 			}
 
-			if errors.Is(err, transfer.ErrMerchantNotAllowed) {
-				handleError(w, http.StatusForbidden, Error{
+			if errors.Is(err, user.ErrUserAlreadyExists) {
+				handleError(w, http.StatusConflict, Error{
 					Message: err.Error(),
+					Details: "an user with the same email already exists",
 				})
 				return
 			}
 
-			if errors.Is(err, transfer.ErrInsufficientFunds) {
-				handleError(w, http.StatusUnprocessableEntity, Error{
+			slog.Error("failed to update user", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		if req.Email != nil && !u.EmailVerifiedAt.Valid {
+			if err := verificationService.Send(r.Context(), userID); err != nil {
+				slog.Error("failed to send verification email", "error", err, "user", userID)
+			}
+		}
+
+		recordAudit(r, auditService, models.AuditUserUpdate, userID,
+			userResponse(before, uuid.Nil),
+			userResponse(u, uuid.Nil),
+		)
+		encode(w, http.StatusOK, userResponse(u, viewer))
+	}
+}
+
+func HandleCloseUser(f *factories.Factory) http.HandlerFunc {
+	accountService := f.MakeAccountService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		if err := accountService.Close(r.Context(), userID); err != nil {
+			if errors.Is(err, account.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, account.ErrAccountFrozen) {
+				handleError(w, http.StatusConflict, Error{
+					Message: err.Error(),
+					Details: "frozen accounts cannot be closed, contact the support",
+				})
+				return
+			}
+
+			if errors.Is(err, account.ErrBalanceNotZero) {
+				handleError(w, http.StatusConflict, Error{
 					Message: err.Error(),
-					Details: "payer has insufficient funds",
+					Details: "withdraw or transfer the remaining funds before closing the account",
 				})
 				return
 			}
 
-			if errors.Is(err, transfer.ErrTransactionNotAuthorized) {
-				handleError(w, http.StatusUnauthorized, Error{
+			if errors.Is(err, account.ErrPendingHolds) {
+				handleError(w, http.StatusConflict, Error{
 					Message: err.Error(),
+					Details: "wait for the pending deposits, withdrawals and reviews to settle",
+				})
+				return
+			}
+
+			slog.Error("failed to close account", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditUserClose, userID, nil, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleTransfer(f *factories.Factory) http.HandlerFunc {
+	transferService := f.MakeTransferService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.TransactionDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		var ok bool
+		if req.Payer, ok = authorizeUser(w, r, req.Payer); !ok {
+			return
+		}
+
+		receipt, err := transferService.NewTransaction(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, transfer.ErrTransactionUnderReview) {
+				encode(w, http.StatusAccepted, JSON{
+					"message": err.Error(),
 				})
 				return
 			}
 
+			if handleTransferError(w, err) {
+				return
+			}
+
 			slog.Error("failed to make transfer", "error", err, "transfer", req)
 			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
 			return
 		}
 
+		recordAudit(r, auditService, models.AuditTransferCreate, receipt.TransactionID,
+			JSON{
+				"payerBalance": balanceBefore(receipt.Payer),
+				"payeeBalance": balanceBefore(receipt.Payee),
+			},
+			JSON{
+				"payer":         req.Payer,
+				"payee":         req.Payee,
+				"value":         req.Value,
+				"currency":      req.Currency,
+				"payeeCurrency": req.PayeeCurrency,
+				"fxRate":        receipt.FXRate,
+				"fxSpread":      receipt.FXSpread,
+				"payerBalance":  balanceAfter(receipt.Payer),
+				"payeeBalance":  balanceAfter(receipt.Payee),
+			},
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandleGetTransactions pages the transactions by the cursor query
+// parameter, the next or prev cursor of the previous response.
+func HandleGetTransactions(f *factories.Factory, pages config.Pagination) http.HandlerFunc {
+	transferService := f.MakeTransferService()
+	cursors := f.MakeCursorSigner()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		problems := make(map[string]string)
+		scope := cursor.Scope("transactions", userID.String())
+		after := parseQuery(r, "cursor", parseCursor(cursors, scope), problems, "must be a valid cursor")
+		if len(problems) > 0 {
+			handleInvalidRequest(w, problems)
+			return
+		}
+
+		page, err := transferService.FindTransactions(r.Context(), userID, after, pages.PageSize)
+		if err != nil {
+			if errors.Is(err, transfer.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to get transactions", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		transactionsDTO := make([]dtos.TransactionResponseDTO, len(page.Items))
+		for i, t := range page.Items {
+			transactionsDTO[i] = dtos.TransactionResponseDTO{
+				ID:            t.ID,
+				Payer:         t.Payer,
+				Payee:         t.Payee,
+				Amount:        t.Amount,
+				Currency:      t.Currency,
+				PayeeAmount:   t.PayeeAmount,
+				PayeeCurrency: t.PayeeCurrency,
+				CreatedAt:     t.CreatedAt.Time,
+			}
+		}
+
+		encode(w, http.StatusOK, dtos.TransactionPageResponseDTO{
+			Transactions: transactionsDTO,
+			Pagination: dtos.PaginationDTO{
+				PageSize: pages.PageSize,
+				Total:    page.Total,
+				Next:     cursorToken(cursors, scope, page.Next),
+				Prev:     cursorToken(cursors, scope, page.Prev),
+			},
+		})
+	}
+}
+
+func HandleGetFraudReviews(f *factories.Factory) http.HandlerFunc {
+	fraudService := f.MakeFraudService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		reviews, err := fraudService.FindPending(r.Context(), page)
+		if err != nil {
+			slog.Error("failed to get fraud reviews", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		reviewsDTO := make([]dtos.FraudReviewResponseDTO, len(reviews))
+		for i, review := range reviews {
+			reviewsDTO[i] = dtos.FraudReviewResponseDTO{
+				ID:        review.ID,
+				Payer:     review.Payer,
+				Payee:     review.Payee,
+				Amount:    review.Amount,
+				Score:     review.Score,
+				Reasons:   review.Reasons,
+				Status:    review.Status,
+				CreatedAt: review.CreatedAt.Time,
+			}
+		}
+
+		encode(w, http.StatusOK, JSON{"reviews": reviewsDTO})
+	}
+}
+
+// Handles the errors shared by the review resolution endpoints,
+// returns false if the error is unknown.
+func handleReviewError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, fraud.ErrReviewNotFound) {
+		handleError(w, http.StatusNotFound, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, fraud.ErrReviewNotPending) {
+		handleError(w, http.StatusConflict, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
+func HandleApproveFraudReview(f *factories.Factory) http.HandlerFunc {
+	transferService := f.MakeTransferService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		req, problems, err := decode[dtos.ReviewResolutionDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		receipt, err := transferService.ApproveReview(r.Context(), reviewID, req.Note)
+		if err != nil {
+			if handleReviewError(w, err) || handleTransferError(w, err) {
+				return
+			}
+
+			slog.Error("failed to approve fraud review", "error", err, "review", reviewID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditFraudReviewApprove, reviewID,
+			JSON{
+				"status":       models.ReviewPending,
+				"payerBalance": balanceBefore(receipt.Payer),
+				"payeeBalance": balanceBefore(receipt.Payee),
+			},
+			JSON{
+				"status":        models.ReviewApproved,
+				"transactionId": receipt.TransactionID,
+				"note":          req.Note,
+				"fxRate":        receipt.FXRate,
+				"fxSpread":      receipt.FXSpread,
+				"payerBalance":  balanceAfter(receipt.Payer),
+				"payeeBalance":  balanceAfter(receipt.Payee),
+			},
+		)
+		encode(w, http.StatusOK, JSON{"transactionId": receipt.TransactionID})
+	}
+}
+
+func HandleRejectFraudReview(f *factories.Factory) http.HandlerFunc {
+	fraudService := f.MakeFraudService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		req, problems, err := decode[dtos.ReviewResolutionDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		if err := fraudService.Reject(r.Context(), reviewID, req.Note); err != nil {
+			if handleReviewError(w, err) {
+				return
+			}
+
+			slog.Error("failed to reject fraud review", "error", err, "review", reviewID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditFraudReviewReject, reviewID,
+			JSON{"status": models.ReviewPending},
+			JSON{"status": models.ReviewRejected, "note": req.Note},
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleGetWallets(f *factories.Factory) http.HandlerFunc {
+	walletService := f.MakeWalletService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		wallets, err := walletService.FindByUser(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, wallet.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to get wallets", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		walletsDTO := make([]dtos.WalletResponseDTO, len(wallets))
+		for i, userWallet := range wallets {
+			walletsDTO[i] = dtos.WalletResponseDTO{
+				Currency: userWallet.Currency,
+				Balance:  userWallet.Balance,
+			}
+		}
+
+		encode(w, http.StatusOK, JSON{"wallets": walletsDTO})
+	}
+}
+
+func HandleCreateWallet(f *factories.Factory) http.HandlerFunc {
+	walletService := f.MakeWalletService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.WalletDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		walletID, err := walletService.Create(r.Context(), userID, req.Currency)
+		if err != nil {
+			if errors.Is(err, wallet.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, wallet.ErrWalletAlreadyExists) {
+				handleError(w, http.StatusConflict, Error{
+					Message: err.Error(),
+					Details: fmt.Sprintf("user already has a %s wallet", req.Currency),
+				})
+				return
+			}
+
+			slog.Error("failed to create wallet", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditWalletCreate, walletID, nil, JSON{
+			"user":     userID,
+			"currency": req.Currency,
+		})
+		encode(w, http.StatusCreated, JSON{"id": walletID})
+	}
+}
+
+func depositResponse(d models.Deposit, instructions string) dtos.DepositResponseDTO {
+	return dtos.DepositResponseDTO{
+		ID:           d.ID,
+		User:         d.UserID,
+		Amount:       d.Amount,
+		Currency:     d.Currency,
+		Source:       d.Source,
+		Status:       d.Status,
+		Reference:    d.Reference,
+		Instructions: instructions,
+		CreatedAt:    d.CreatedAt.Time,
+	}
+}
+
+func HandleCreateDeposit(f *factories.Factory) http.HandlerFunc {
+	depositService := f.MakeDepositService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.DepositDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		var ok bool
+		if req.User, ok = authorizeUser(w, r, req.User); !ok {
+			return
+		}
+
+		d, funding, balance, err := depositService.Create(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, deposit.ErrUserNotFound) ||
+				errors.Is(err, deposit.ErrWalletNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, deposit.ErrUnsupportedSource) {
+				handleError(w, http.StatusUnprocessableEntity, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, deposit.ErrPaymentDeclined) {
+				handleError(w, http.StatusPaymentRequired, Error{
+					Message: err.Error(),
+					Details: fmt.Sprintf("deposit %s was declined by the card issuer", d.ID),
+				})
+				return
+			}
+
+			slog.Error("failed to create deposit", "error", err, "user", req.User)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		// Only the deposits settled right away moved the balance
+		var before JSON
+		after := JSON{"deposit": depositResponse(d, "")}
+		if d.Status == models.DepositConfirmed {
+			before = JSON{"balance": balanceBefore(balance)}
+			after["balance"] = balanceAfter(balance)
+		}
+		recordAudit(r, auditService, models.AuditDepositCreate, d.ID, before, after)
+
+		status := http.StatusCreated
+		if d.Status == models.DepositPending {
+			status = http.StatusAccepted
+		}
+
+		encode(w, status, depositResponse(d, funding.Instructions))
+	}
+}
+
+func HandleGetDeposit(f *factories.Factory) http.HandlerFunc {
+	depositService := f.MakeDepositService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		depositID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		d, err := depositService.FindByID(r.Context(), depositID)
+		if err == nil && d.UserID != userID {
+			err = deposit.ErrDepositNotFound
+		}
+		if err != nil {
+			if errors.Is(err, deposit.ErrDepositNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to get deposit", "error", err, "deposit", depositID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		encode(w, http.StatusOK, depositResponse(d, ""))
+	}
+}
+
+// HandleConfirmDeposit is called by the funding source
+// once the money of a pending deposit is received.
+func HandleConfirmDeposit(f *factories.Factory) http.HandlerFunc {
+	depositService := f.MakeDepositService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		depositID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		d, balance, err := depositService.Confirm(r.Context(), depositID)
+		if err != nil {
+			if errors.Is(err, deposit.ErrDepositNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, deposit.ErrDepositNotPending) {
+				handleError(w, http.StatusConflict, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to confirm deposit", "error", err, "deposit", depositID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditDepositConfirm, d.ID,
+			JSON{"status": models.DepositPending, "balance": balanceBefore(balance)},
+			JSON{"deposit": depositResponse(d, ""), "balance": balanceAfter(balance)},
+		)
+		encode(w, http.StatusOK, depositResponse(d, ""))
+	}
+}
+
+// SignatureHeader holds the hex HMAC-SHA256 of the body
+// of the notices sent by the funding sources.
+const SignatureHeader = "X-Signature"
+
+func validSignature(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// HandleDepositFunding confirms a deposit on the notice of its funding
+// source, which signs the body with the secret shared with it.
+func HandleDepositFunding(f *factories.Factory, secret string) http.HandlerFunc {
+	depositService := f.MakeDepositService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleInvalidRequest(w, nil)
+			return
+		}
+
+		if !validSignature(secret, body, r.Header.Get(SignatureHeader)) {
+			handleUnauthorized(w, "invalid signature")
+			return
+		}
+
+		depositID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		req, problems, err := decode[dtos.DepositFundingDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		d, balance, err := depositService.ConfirmFunding(r.Context(), depositID, req.Reference)
+		if err != nil {
+			if errors.Is(err, deposit.ErrDepositNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, deposit.ErrDepositNotPending) {
+				handleError(w, http.StatusConflict, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to confirm deposit", "error", err, "deposit", depositID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditDepositConfirm, d.ID,
+			JSON{"status": models.DepositPending, "balance": balanceBefore(balance)},
+			JSON{"deposit": depositResponse(d, ""), "balance": balanceAfter(balance)},
+		)
+		encode(w, http.StatusOK, depositResponse(d, ""))
+	}
+}
+
+func HandleCreateBankAccount(f *factories.Factory) http.HandlerFunc {
+	withdrawalService := f.MakeWithdrawalService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.BankAccountDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		accountID, err := withdrawalService.RegisterBankAccount(r.Context(), userID, req)
+		if err != nil {
+			if errors.Is(err, withdrawal.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, withdrawal.ErrBankAccountExists) {
+				handleError(w, http.StatusConflict, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to create bank account", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditBankAccountCreate, accountID, nil, JSON{
+			"user":     userID,
+			"bankCode": req.BankCode,
+			"branch":   req.Branch,
+		})
+		encode(w, http.StatusCreated, JSON{"id": accountID})
+	}
+}
+
+func HandleGetBankAccounts(f *factories.Factory) http.HandlerFunc {
+	withdrawalService := f.MakeWithdrawalService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		accounts, err := withdrawalService.FindBankAccounts(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, withdrawal.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to get bank accounts", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		accountsDTO := make([]dtos.BankAccountResponseDTO, len(accounts))
+		for i, account := range accounts {
+			accountsDTO[i] = dtos.BankAccountResponseDTO{
+				ID:            account.ID,
+				BankCode:      account.BankCode,
+				Branch:        account.Branch,
+				AccountNumber: account.AccountNumber,
+				AccountType:   account.AccountType,
+			}
+		}
+
+		encode(w, http.StatusOK, JSON{"bankAccounts": accountsDTO})
+	}
+}
+
+func withdrawalResponse(wd models.Withdrawal) dtos.WithdrawalResponseDTO {
+	return dtos.WithdrawalResponseDTO{
+		ID:            wd.ID,
+		User:          wd.UserID,
+		BankAccount:   wd.BankAccountID,
+		Amount:        wd.Amount,
+		Status:        wd.Status,
+		Reference:     wd.Reference,
+		FailureReason: wd.FailureReason,
+		CreatedAt:     wd.CreatedAt.Time,
+	}
+}
+
+func HandleCreateWithdrawal(f *factories.Factory) http.HandlerFunc {
+	withdrawalService := f.MakeWithdrawalService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.WithdrawalDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		var ok bool
+		if req.User, ok = authorizeUser(w, r, req.User); !ok {
+			return
+		}
+
+		wd, balance, err := withdrawalService.Request(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, withdrawal.ErrUserNotFound) ||
+				errors.Is(err, withdrawal.ErrBankAccountNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, withdrawal.ErrAccountFrozen) {
+				handleError(w, http.StatusForbidden, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, withdrawal.ErrKYCLevelRequired) {
+				handleError(w, http.StatusForbidden, Error{
+					Message: err.Error(),
+					Details: "withdrawals require the intermediate kyc level",
+				})
+				return
+			}
+
+			if errors.Is(err, withdrawal.ErrEmailNotVerified) {
+				handleError(w, http.StatusForbidden, Error{
+					Message: err.Error(),
+					Details: "verify your email before withdrawing funds",
+				})
+				return
+			}
+
+			if errors.Is(err, withdrawal.ErrInsufficientFunds) {
+				handleError(w, http.StatusUnprocessableEntity, Error{
+					Message: err.Error(),
+					Details: "user has insufficient funds",
+				})
+				return
+			}
+
+			slog.Error("failed to create withdrawal", "error", err, "user", req.User)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditWithdrawalCreate, wd.ID,
+			JSON{"balance": balanceBefore(balance)},
+			JSON{"withdrawal": withdrawalResponse(wd), "balance": balanceAfter(balance)},
+		)
+		encode(w, http.StatusAccepted, withdrawalResponse(wd))
+	}
+}
+
+func HandleGetWithdrawal(f *factories.Factory) http.HandlerFunc {
+	withdrawalService := f.MakeWithdrawalService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		withdrawalID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		wd, err := withdrawalService.FindByID(r.Context(), withdrawalID)
+		if err == nil && wd.UserID != userID {
+			err = withdrawal.ErrWithdrawalNotFound
+		}
+		if err != nil {
+			if errors.Is(err, withdrawal.ErrWithdrawalNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to get withdrawal", "error", err, "withdrawal", withdrawalID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		encode(w, http.StatusOK, withdrawalResponse(wd))
+	}
+}
+
+// Returns nil for timestamps that are not set, like
+// the ones of events that did not happen yet.
+func optionalTime(t pgtype.Timestamp) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func apiKeyResponse(k models.APIKey, secret string) dtos.APIKeyResponseDTO {
+	return dtos.APIKeyResponseDTO{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Key:        secret,
+		Scopes:     k.Scopes,
+		LastUsedAt: optionalTime(k.LastUsedAt),
+		RevokedAt:  optionalTime(k.RevokedAt),
+		CreatedAt:  k.CreatedAt.Time,
+	}
+}
+
+// Handles the errors shared by the api key endpoints,
+// returns false if the error is unknown.
+func handleAPIKeyError(w http.ResponseWriter, err error) bool {
+	if handleStepUpError(w, err) {
+		return true
+	}
+
+	if errors.Is(err, apikey.ErrUserNotFound) ||
+		errors.Is(err, apikey.ErrAPIKeyNotFound) {
+		handleError(w, http.StatusNotFound, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, apikey.ErrNotMerchant) {
+		handleError(w, http.StatusForbidden, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, apikey.ErrAPIKeyRevoked) {
+		handleError(w, http.StatusConflict, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
+func HandleCreateAPIKey(f *factories.Factory) http.HandlerFunc {
+	apiKeyService := f.MakeAPIKeyService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.APIKeyDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		key, err := apiKeyService.Create(r.Context(), userID, req)
+		if err != nil {
+			if handleAPIKeyError(w, err) {
+				return
+			}
+
+			slog.Error("failed to create api key", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditAPIKeyCreate, key.ID, nil, apiKeyResponse(key.APIKey, ""))
+		encode(w, http.StatusCreated, apiKeyResponse(key.APIKey, key.Secret))
+	}
+}
+
+func HandleGetAPIKeys(f *factories.Factory) http.HandlerFunc {
+	apiKeyService := f.MakeAPIKeyService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		keys, err := apiKeyService.FindByUser(r.Context(), userID)
+		if err != nil {
+			if handleAPIKeyError(w, err) {
+				return
+			}
+
+			slog.Error("failed to get api keys", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		keysDTO := make([]dtos.APIKeyResponseDTO, len(keys))
+		for i, key := range keys {
+			keysDTO[i] = apiKeyResponse(key, "")
+		}
+
+		encode(w, http.StatusOK, JSON{"apiKeys": keysDTO})
+	}
+}
+
+func HandleRotateAPIKey(f *factories.Factory) http.HandlerFunc {
+	apiKeyService := f.MakeAPIKeyService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		keyID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.OTPDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		key, err := apiKeyService.Rotate(r.Context(), userID, keyID, req.Code)
+		if err != nil {
+			if handleAPIKeyError(w, err) {
+				return
+			}
+
+			slog.Error("failed to rotate api key", "error", err, "key", keyID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditAPIKeyRotate, keyID, nil, apiKeyResponse(key.APIKey, ""))
+		encode(w, http.StatusCreated, apiKeyResponse(key.APIKey, key.Secret))
+	}
+}
+
+func HandleRevokeAPIKey(f *factories.Factory) http.HandlerFunc {
+	apiKeyService := f.MakeAPIKeyService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		keyID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		if err := apiKeyService.Revoke(r.Context(), userID, keyID); err != nil {
+			if handleAPIKeyError(w, err) {
+				return
+			}
+
+			slog.Error("failed to revoke api key", "error", err, "key", keyID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditAPIKeyRevoke, keyID, nil, nil)
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
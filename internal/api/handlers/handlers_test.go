@@ -0,0 +1,350 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/api/handlers"
+	"github.com/edulustosa/go-pay/internal/config"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var tokens = auth.NewTokenManager(
+	[]byte("a-secret-with-at-least-32-characters"),
+	time.Minute,
+)
+
+// The handlers must reject the requests before reaching the
+// database, so they are built without a connection pool.
+var factory = factories.New(nil, config.Default(), nil, nil)
+
+// The sessions of the access tokens are kept in memory, only
+// the sessions and the users are needed to check them.
+var (
+	userRepository     = &repo.InMemoryUserRepository{}
+	sessionsRepository = &repo.InMemorySessionsRepository{}
+	sessions           = auth.NewService(userRepository, sessionsRepository, nil, tokens, nil, nil)
+)
+
+// Issues an access token of a new session of the user.
+func accessToken(t *testing.T, userID uuid.UUID, role models.Role) (string, uuid.UUID) {
+	t.Helper()
+
+	user := models.User{ID: userID, Role: role, Status: models.StatusActive}
+	userRepository.Users = append(userRepository.Users, user)
+
+	sessionID := uuid.New()
+	err := sessionsRepository.Create(context.Background(), models.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	token, err := tokens.Issue(user, sessionID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	return token.Token, sessionID
+}
+
+func serve(t *testing.T, h http.Handler, req *http.Request, userID uuid.UUID) *httptest.ResponseRecorder {
+	t.Helper()
+
+	if userID != uuid.Nil {
+		token, _ := accessToken(t, userID, models.RoleCommon)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	handlers.Authenticate(tokens, sessions)(h).ServeHTTP(w, req)
+	return w
+}
+
+func decodeErrors(t *testing.T, w *httptest.ResponseRecorder) handlers.ErrorList {
+	t.Helper()
+
+	var errorList handlers.ErrorList
+	if err := json.NewDecoder(w.Body).Decode(&errorList); err != nil {
+		t.Fatalf("expected error list, got %v", err)
+	}
+
+	return errorList
+}
+
+func TestHandleTransfer_Authorization(t *testing.T) {
+	victim := uuid.New()
+	attacker := uuid.New()
+	h := handlers.HandleTransfer(factory)
+
+	t.Run("should not transfer without an access token", func(t *testing.T) {
+		body := `{"value": 100, "payee": "` + attacker.String() + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(body))
+
+		w := serve(t, h, req, uuid.Nil)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+
+		if errorList := decodeErrors(t, w); len(errorList.Errors) != 1 {
+			t.Errorf("expected 1 error, got %+v", errorList)
+		}
+	})
+
+	t.Run("should not transfer with an api key", func(t *testing.T) {
+		body := `{"value": 100, "payee": "` + attacker.String() + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer gpk_0a1b2c3d_secret")
+
+		w := serve(t, h, req, uuid.Nil)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	t.Run("should not move another user's money", func(t *testing.T) {
+		body := `{"value": 100, "payer": "` + victim.String() + `", "payee": "` + attacker.String() + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(body))
+
+		w := serve(t, h, req, attacker)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+
+		if errorList := decodeErrors(t, w); len(errorList.Errors) != 1 {
+			t.Errorf("expected 1 error, got %+v", errorList)
+		}
+	})
+}
+
+func TestUserScopedEndpoints_Authorization(t *testing.T) {
+	victim := uuid.New()
+	attacker := uuid.New()
+
+	testCases := []struct {
+		name    string
+		pattern string
+		handler http.Handler
+		method  string
+		path    string
+		body    string
+	}{
+		{
+			"get user",
+			"GET /users/{id}",
+			handlers.HandleGetUser(factory),
+			http.MethodGet,
+			"/users/" + victim.String(),
+			"",
+		},
+		{
+			"update user",
+			"PATCH /users/{id}",
+			handlers.HandleUpdateUser(factory),
+			http.MethodPatch,
+			"/users/" + victim.String(),
+			`{"firstName": "Mallory"}`,
+		},
+		{
+			"close user",
+			"DELETE /users/{id}",
+			handlers.HandleCloseUser(factory),
+			http.MethodDelete,
+			"/users/" + victim.String(),
+			"",
+		},
+		{
+			"get wallets",
+			"GET /users/{id}/wallets",
+			handlers.HandleGetWallets(factory),
+			http.MethodGet,
+			"/users/" + victim.String() + "/wallets",
+			"",
+		},
+		{
+			"create wallet",
+			"POST /users/{id}/wallets",
+			handlers.HandleCreateWallet(factory),
+			http.MethodPost,
+			"/users/" + victim.String() + "/wallets",
+			`{"currency": "USD"}`,
+		},
+		{
+			"get bank accounts",
+			"GET /users/{id}/bank-accounts",
+			handlers.HandleGetBankAccounts(factory),
+			http.MethodGet,
+			"/users/" + victim.String() + "/bank-accounts",
+			"",
+		},
+		{
+			"create bank account",
+			"POST /users/{id}/bank-accounts",
+			handlers.HandleCreateBankAccount(factory),
+			http.MethodPost,
+			"/users/" + victim.String() + "/bank-accounts",
+			`{"bankCode": "001", "branch": "1234", "account": "12345-6"}`,
+		},
+		{
+			"create deposit",
+			"POST /deposits",
+			handlers.HandleCreateDeposit(factory),
+			http.MethodPost,
+			"/deposits",
+			`{"user": "` + victim.String() + `", "amount": 10, "source": "BANK_TRANSFER"}`,
+		},
+		{
+			"create withdrawal",
+			"POST /withdrawals",
+			handlers.HandleCreateWithdrawal(factory),
+			http.MethodPost,
+			"/withdrawals",
+			`{"user": "` + victim.String() + `", "bankAccount": "` + uuid.NewString() + `", "amount": 10}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("should forbid "+tc.name+" of another user", func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle(tc.pattern, tc.handler)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			w := serve(t, mux, req, attacker)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_Session(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := handlers.Authenticate(tokens, sessions)(next)
+	ctx := context.Background()
+
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("should reject the access tokens of a revoked session", func(t *testing.T) {
+		userID := uuid.New()
+		token, sessionID := accessToken(t, userID, models.RoleCommon)
+
+		if code := request(token); code != http.StatusNoContent {
+			t.Fatalf("expected status %d, got %d", http.StatusNoContent, code)
+		}
+
+		if err := sessions.RevokeSession(ctx, userID, sessionID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if code := request(token); code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+
+	t.Run("should reject the access tokens of a frozen user", func(t *testing.T) {
+		userID := uuid.New()
+		token, _ := accessToken(t, userID, models.RoleCommon)
+
+		if _, err := userRepository.Freeze(ctx, userID, "chargebacks", uuid.New()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if code := request(token); code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+}
+
+func TestRequirePermission(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := handlers.Authenticate(tokens, sessions)(handlers.RequirePermission(auth.PermissionUsersFreeze)(next))
+
+	testCases := []struct {
+		role models.Role
+		want int
+	}{
+		{models.RoleCommon, http.StatusForbidden},
+		{models.RoleMerchant, http.StatusForbidden},
+		{models.RoleFinance, http.StatusForbidden},
+		{models.RoleSupport, http.StatusNoContent},
+		{models.RoleAdmin, http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		token, _ := accessToken(t, uuid.New(), tc.role)
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/"+uuid.NewString()+"/freeze", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s got status %d, want %d", tc.role, w.Code, tc.want)
+		}
+	}
+}
+
+func TestHandleDepositFunding_Signature(t *testing.T) {
+	secret := "a-webhook-secret-with-32-characters"
+	body := `{"reference": "BT-123"}`
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /webhooks/deposits/{id}/confirm", handlers.HandleDepositFunding(factory, secret))
+
+	mac := hmac.New(sha256.New, []byte("another-secret"))
+	mac.Write([]byte(body))
+
+	testCases := []struct {
+		name      string
+		signature string
+	}{
+		{"missing signature", ""},
+		{"malformed signature", "not-hex"},
+		{"signature of another secret", hex.EncodeToString(mac.Sum(nil))},
+	}
+
+	for _, tc := range testCases {
+		t.Run("should reject the "+tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/webhooks/deposits/"+uuid.NewString()+"/confirm",
+				strings.NewReader(body),
+			)
+			req.Header.Set(handlers.SignatureHeader, tc.signature)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/edulustosa/go-pay/internal/services/kyc"
+	"github.com/google/uuid"
+)
+
+// kycSubmissionResponse only includes the submitted data when
+// withData is set, users don't need it echoed back.
+func kycSubmissionResponse(
+	s models.KYCSubmission,
+	withData bool,
+) *dtos.KYCSubmissionResponseDTO {
+	res := &dtos.KYCSubmissionResponseDTO{
+		ID:         s.ID,
+		User:       s.UserID,
+		Level:      s.Level,
+		Status:     s.Status,
+		ReviewNote: s.ReviewNote,
+		CreatedAt:  s.CreatedAt.Time,
+	}
+
+	if s.ReviewedAt.Valid {
+		res.ReviewedAt = &s.ReviewedAt.Time
+	}
+
+	if !withData {
+		return res
+	}
+
+	if s.BirthDate.Valid {
+		res.BirthDate = s.BirthDate.Time.Format(time.DateOnly)
+		res.Address = &dtos.KYCAddressDTO{
+			Line:       s.AddressLine,
+			City:       s.City,
+			State:      s.State,
+			PostalCode: s.PostalCode,
+		}
+	}
+	res.Documents = s.Documents
+
+	return res
+}
+
+// Handles the errors shared by the kyc review
+// endpoints, returns false if the error is unknown.
+func handleKYCReviewError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, kyc.ErrSubmissionNotFound) {
+		handleError(w, http.StatusNotFound, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, kyc.ErrSubmissionNotPending) {
+		handleError(w, http.StatusConflict, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, kyc.ErrSelfReview) {
+		handleError(w, http.StatusForbidden, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, kyc.ErrReasonRequired) {
+		handleInvalidRequest(w, map[string]string{"note": "must not be empty"})
+		return true
+	}
+
+	return false
+}
+
+func HandleSubmitKYC(f *factories.Factory) http.HandlerFunc {
+	kycService := f.MakeKYCService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.KYCSubmissionDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		submission, err := kycService.Submit(r.Context(), userID, req)
+		if err != nil {
+			if errors.Is(err, kyc.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, kyc.ErrSubmissionPending) ||
+				errors.Is(err, kyc.ErrInvalidLevel) {
+				handleError(w, http.StatusConflict, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, kyc.ErrUnderage) {
+				handleInvalidRequest(w, map[string]string{"birthDate": err.Error()})
+				return
+			}
+
+			slog.Error("failed to submit kyc", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		res := kycSubmissionResponse(submission, false)
+		recordAudit(r, auditService, models.AuditKYCSubmit, submission.ID, nil, res)
+		encode(w, http.StatusCreated, res)
+	}
+}
+
+func HandleGetKYC(f *factories.Factory) http.HandlerFunc {
+	kycService := f.MakeKYCService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		level, submission, err := kycService.Status(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, kyc.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to get kyc status", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		limits := kyc.LimitsFor(level)
+		res := dtos.KYCStatusResponseDTO{
+			Level: level,
+			Limits: dtos.KYCLimitsResponseDTO{
+				MaxTransfer: limits.MaxTransfer,
+				Withdrawals: limits.Withdrawals,
+			},
+		}
+
+		if submission.ID != uuid.Nil {
+			res.Submission = kycSubmissionResponse(submission, false)
+		}
+
+		encode(w, http.StatusOK, res)
+	}
+}
+
+func HandleGetKYCSubmissions(f *factories.Factory) http.HandlerFunc {
+	kycService := f.MakeKYCService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		submissions, err := kycService.FindPending(r.Context(), page)
+		if err != nil {
+			slog.Error("failed to get kyc submissions", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		submissionsDTO := make([]*dtos.KYCSubmissionResponseDTO, len(submissions))
+		for i, submission := range submissions {
+			submissionsDTO[i] = kycSubmissionResponse(submission, true)
+		}
+
+		encode(w, http.StatusOK, JSON{"submissions": submissionsDTO})
+	}
+}
+
+func HandleApproveKYC(f *factories.Factory) http.HandlerFunc {
+	kycService := f.MakeKYCService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			handleUnauthorized(w, "missing bearer access token")
+			return
+		}
+
+		submissionID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		req, problems, err := decode[dtos.ReviewResolutionDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		err = kycService.Approve(r.Context(), submissionID, principal.UserID, req.Note)
+		if err != nil {
+			if handleKYCReviewError(w, err) {
+				return
+			}
+
+			slog.Error("failed to approve kyc", "error", err, "submission", submissionID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditKYCApprove, submissionID,
+			JSON{"status": models.ReviewPending},
+			JSON{"status": models.ReviewApproved, "note": req.Note},
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleRejectKYC(f *factories.Factory) http.HandlerFunc {
+	kycService := f.MakeKYCService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			handleUnauthorized(w, "missing bearer access token")
+			return
+		}
+
+		submissionID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		req, problems, err := decode[dtos.ReviewResolutionDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		err = kycService.Reject(r.Context(), submissionID, principal.UserID, req.Note)
+		if err != nil {
+			if handleKYCReviewError(w, err) {
+				return
+			}
+
+			slog.Error("failed to reject kyc", "error", err, "submission", submissionID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditKYCReject, submissionID,
+			JSON{"status": models.ReviewPending},
+			JSON{"status": models.ReviewRejected, "note": req.Note},
+		)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/mfa"
+	"github.com/google/uuid"
+)
+
+// Handles the errors of operations requiring a fresh two-factor
+// code, returns false if the error is unknown.
+func handleStepUpError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, mfa.ErrCodeRequired) ||
+		errors.Is(err, mfa.ErrInvalidCode) ||
+		errors.Is(err, mfa.ErrEnrollmentNeeded) {
+		handleError(w, http.StatusForbidden, Error{
+			Message: err.Error(),
+			Details: "send a fresh code of the authenticator app in the otp field",
+		})
+		return true
+	}
+
+	if errors.Is(err, mfa.ErrTooManyAttempts) {
+		handleError(w, http.StatusTooManyRequests, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
+// Handles the errors shared by the two-factor
+// endpoints, returns false if the error is unknown.
+func handleMFAError(w http.ResponseWriter, err error) bool {
+	if handleStepUpError(w, err) {
+		return true
+	}
+
+	if errors.Is(err, mfa.ErrUserNotFound) {
+		handleError(w, http.StatusNotFound, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, mfa.ErrAlreadyEnrolled) ||
+		errors.Is(err, mfa.ErrNotEnrolled) {
+		handleError(w, http.StatusConflict, Error{
+			Message: err.Error(),
+		})
+		return true
+	}
+
+	return false
+}
+
+func HandleEnrollTOTP(f *factories.Factory) http.HandlerFunc {
+	mfaService := f.MakeMFAService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		enrollment, err := mfaService.Enroll(r.Context(), userID)
+		if err != nil {
+			if handleMFAError(w, err) {
+				return
+			}
+
+			slog.Error("failed to enroll totp", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		encode(w, http.StatusCreated, dtos.TOTPEnrollmentResponseDTO{
+			Secret: enrollment.Secret,
+			URI:    enrollment.URI,
+		})
+	}
+}
+
+func HandleConfirmTOTP(f *factories.Factory) http.HandlerFunc {
+	mfaService := f.MakeMFAService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.OTPDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		codes, err := mfaService.Confirm(r.Context(), userID, req.Code)
+		if err != nil {
+			if handleMFAError(w, err) {
+				return
+			}
+
+			slog.Error("failed to confirm totp", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditMFAEnable, userID, nil, nil)
+		encode(w, http.StatusOK, dtos.RecoveryCodesResponseDTO{RecoveryCodes: codes})
+	}
+}
+
+func HandleDisableTOTP(f *factories.Factory) http.HandlerFunc {
+	mfaService := f.MakeMFAService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.OTPDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		if err := mfaService.Disable(r.Context(), userID, req.Code); err != nil {
+			if handleMFAError(w, err) {
+				return
+			}
+
+			slog.Error("failed to disable totp", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAudit(r, auditService, models.AuditMFADisable, userID, nil, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleRegenerateRecoveryCodes(f *factories.Factory) http.HandlerFunc {
+	mfaService := f.MakeMFAService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authorizeUser(w, r, uuid.Nil)
+		if !ok {
+			return
+		}
+
+		req, problems, err := decode[dtos.OTPDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		codes, err := mfaService.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+		if err != nil {
+			if handleMFAError(w, err) {
+				return
+			}
+
+			slog.Error("failed to regenerate recovery codes", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		encode(w, http.StatusOK, dtos.RecoveryCodesResponseDTO{RecoveryCodes: codes})
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Middleware wraps a handler, like Authenticate and RequirePermission.
+type Middleware = func(http.Handler) http.Handler
+
+// Chain composes the middlewares, the first one being the outermost,
+// so it runs first on the request and last on the response.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			h = middlewares[i](h)
+		}
+
+		return h
+	}
+}
+
+const RequestIDHeader = "X-Request-Id"
+
+// Longest request id accepted from the clients
+const maxRequestIDSize = 128
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// validRequestID only accepts visible ASCII characters, so the
+// ids of the clients cannot forge lines of the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDSize {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RequestID keeps the X-Request-Id of the request, generating one if it is
+// missing or invalid, adds it to the request context and to the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// statusWriter records the status and the size of the response. Unwrap
+// lets http.ResponseController reach the flusher and deadlines of w.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AccessLog logs every request once it is served, with
+// the status of the response and how long it took.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+
+		next.ServeHTTP(sw, r)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if sw.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		slog.Log(
+			r.Context(),
+			level,
+			"request served",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"bytes", sw.bytes,
+			"latency", time.Since(start),
+			"ip", sourceIP(r),
+		)
+	})
+}
+
+// Recover turns the panics of the handlers into internal server errors,
+// logging them with their stack. If the response was already started
+// it cannot be replaced, so the client only sees it cut short.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			// Aborts the response on purpose, the server handles it
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			slog.ErrorContext(
+				r.Context(),
+				"panic serving request",
+				"panic", rec,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"stack", string(debug.Stack()),
+			)
+
+			if sw.status == 0 {
+				handleError(sw, http.StatusInternalServerError, InternalServerErrMsg)
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+func bodyTooLarge(maxBytes int64) Error {
+	return Error{
+		Message: "request body too large",
+		Details: fmt.Sprintf("the body must have at most %d bytes", maxBytes),
+	}
+}
+
+// LimitBody fails the reads of request bodies larger than maxBytes.
+func LimitBody(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				handleError(w, http.StatusRequestEntityTooLarge, bodyTooLarge(maxBytes))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LogHandler adds the id of the request in the context to the
+// records, for the ones logged with the context of a request.
+type LogHandler struct {
+	slog.Handler
+}
+
+func NewLogHandler(h slog.Handler) *LogHandler {
+	return &LogHandler{h}
+}
+
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := RequestIDFrom(ctx); ok {
+		record.AddAttrs(slog.String("requestId", id))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{h.Handler.WithGroup(name)}
+}
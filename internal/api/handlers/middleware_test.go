@@ -0,0 +1,159 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/edulustosa/go-pay/internal/api/handlers"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mark := func(name string) handlers.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := handlers.Chain(mark("first"), mark("second"))(http.NotFoundHandler())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("expected the first middleware to run first, got %v", order)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := handlers.RequestID(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got, _ = handlers.RequestIDFrom(r.Context())
+	}))
+
+	t.Run("should keep the id of the client", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(handlers.RequestIDHeader, "client-id")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if got != "client-id" || w.Header().Get(handlers.RequestIDHeader) != "client-id" {
+			t.Errorf("expected the id of the client, got %q and %q", got, w.Header().Get(handlers.RequestIDHeader))
+		}
+	})
+
+	t.Run("should generate the missing or invalid ids", func(t *testing.T) {
+		for _, id := range []string{"", "has spaces", strings.Repeat("a", 129)} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(handlers.RequestIDHeader, id)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if got == "" || got == id || w.Header().Get(handlers.RequestIDHeader) != got {
+				t.Errorf("expected a generated id for %q, got %q", id, got)
+			}
+		}
+	})
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("should return an internal server error", func(t *testing.T) {
+		h := handlers.Recover(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic("boom")
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", w.Code)
+		}
+
+		errorList := decodeErrors(t, w)
+		if len(errorList.Errors) != 1 || errorList.Errors[0] != handlers.InternalServerErrMsg {
+			t.Errorf("expected the internal server error message, got %+v", errorList)
+		}
+	})
+
+	t.Run("should keep the response already started", func(t *testing.T) {
+		h := handlers.Recover(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panic("boom")
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if w.Code != http.StatusAccepted || w.Body.Len() != 0 {
+			t.Errorf("expected the started response, got %d %q", w.Code, w.Body)
+		}
+	})
+}
+
+func TestLimitBody(t *testing.T) {
+	h := handlers.LimitBody(16)(handlers.HandleForgotPassword(factory, nil))
+
+	small := `{"email":"x"}`
+	large := `{"email":"johndoe@email.com"}`
+
+	testCases := []struct {
+		name string
+		body io.Reader
+		want int
+	}{
+		// Read whole, only the email is invalid
+		{"small body", strings.NewReader(small), http.StatusBadRequest},
+		{"declared large body", strings.NewReader(large), http.StatusRequestEntityTooLarge},
+		// Without a known length, like chunked bodies, the reads fail
+		{"undeclared large body", io.MultiReader(strings.NewReader(large)), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", tc.body))
+
+		if w.Code != tc.want {
+			t.Errorf("%s got status %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(handlers.NewLogHandler(slog.NewJSONHandler(&buf, nil)))
+
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	h := handlers.Chain(handlers.RequestID, handlers.AccessLog)(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set(handlers.RequestIDHeader, "log-id")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, want := range []string{`"status":418`, `"path":"/users"`, `"requestId":"log-id"`, `"latency"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in %s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "outside a request")
+	if strings.Contains(buf.String(), "requestId") {
+		t.Errorf("expected no request id, got %s", buf.String())
+	}
+}
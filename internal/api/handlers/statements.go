@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/statement"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/google/uuid"
+)
+
+func parseDay(s string) (time.Time, error) {
+	return time.Parse(time.DateOnly, s)
+}
+
+// HandleGetStatement streams the statement of the user from the from day
+// to the to day, both included, in the currency, BRL by default. The
+// format is csv, the default, ofx or pdf. Statements of long periods are
+// streamed for longer than the write timeout of the server, which is
+// extended to writeTimeout.
+func HandleGetStatement(f *factories.Factory, writeTimeout time.Duration) http.HandlerFunc {
+	statementService := f.MakeStatementService()
+	parseFormat := oneOf(statement.FormatCSV, statement.FormatOFX, statement.FormatPDF)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		problems := make(map[string]string)
+		from := parseQuery(r, "from", parseDay, problems, "must be a date as YYYY-MM-DD")
+		to := parseQuery(r, "to", parseDay, problems, "must be a date as YYYY-MM-DD")
+		format := parseQuery(r, "format", parseFormat, problems, "must be csv, ofx or pdf")
+		for name, day := range map[string]time.Time{"from": from, "to": to} {
+			if _, invalid := problems[name]; !invalid && day.IsZero() {
+				problems[name] = "is required"
+			}
+		}
+
+		if len(problems) > 0 {
+			handleInvalidRequest(w, problems)
+			return
+		}
+
+		if format == "" {
+			format = statement.FormatCSV
+		}
+
+		currency := r.URL.Query().Get("currency")
+		if currency == "" {
+			currency = wallet.DefaultCurrency
+		}
+
+		header, err := statementService.Prepare(r.Context(), userID, currency, from, to)
+		if err != nil {
+			if errors.Is(err, statement.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, statement.ErrInvalidRange) {
+				handleInvalidRequest(w, map[string]string{"from": err.Error()})
+				return
+			}
+
+			if errors.Is(err, statement.ErrInvalidCurrency) {
+				handleInvalidRequest(w, map[string]string{
+					"currency": "must be a valid ISO 4217 currency code",
+				})
+				return
+			}
+
+			slog.Error("failed to prepare statement", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		writer, err := statement.NewWriter(format, w)
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"format": "must be csv, ofx or pdf"})
+			return
+		}
+
+		// Not every writer can extend it, the ones of the tests can't
+		rc := http.NewResponseController(w)
+		_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+		filename := fmt.Sprintf(
+			"statement-%s-%s.%s",
+			from.Format(time.DateOnly),
+			to.Format(time.DateOnly),
+			format,
+		)
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+
+		// The status is already sent, the statement is left truncated
+		if err := statementService.Write(r.Context(), header, writer); err != nil {
+			slog.Error("failed to write statement", "error", err, "user", userID)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/verification"
+	"github.com/google/uuid"
+)
+
+func HandleVerifyEmail(f *factories.Factory) http.HandlerFunc {
+	verificationService := f.MakeVerificationService()
+	auditService := f.MakeAuditService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, problems, err := decode[dtos.VerifyEmailDTO](r)
+		if err != nil {
+			handleDecodeError(w, problems, err)
+			return
+		}
+
+		userID, err := verificationService.Verify(r.Context(), req.Token)
+		if err != nil {
+			if errors.Is(err, verification.ErrInvalidToken) {
+				handleError(w, http.StatusBadRequest, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			slog.Error("failed to verify email", "error", err)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		recordAuditAs(r, auditService, userID, models.AuditEmailVerify, userID, nil, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func HandleResendVerification(f *factories.Factory) http.HandlerFunc {
+	verificationService := f.MakeVerificationService()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			handleInvalidRequest(w, map[string]string{"id": "must be a valid UUID"})
+			return
+		}
+
+		if _, ok := authorizeUser(w, r, userID); !ok {
+			return
+		}
+
+		if err := verificationService.Send(r.Context(), userID); err != nil {
+			if errors.Is(err, verification.ErrUserNotFound) {
+				handleError(w, http.StatusNotFound, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, verification.ErrAlreadyVerified) {
+				handleError(w, http.StatusConflict, Error{
+					Message: err.Error(),
+				})
+				return
+			}
+
+			if errors.Is(err, verification.ErrTooManyRequests) {
+				handleError(w, http.StatusTooManyRequests, Error{
+					Message: err.Error(),
+					Details: "wait a minute before asking for a new code",
+				})
+				return
+			}
+
+			slog.Error("failed to send verification email", "error", err, "user", userID)
+			handleError(w, http.StatusInternalServerError, InternalServerErrMsg)
+			return
+		}
+
+		encode(w, http.StatusAccepted, JSON{
+			"message": "a verification code was sent to the user email",
+		})
+	}
+}
@@ -1,18 +1,96 @@
 package router
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/edulustosa/go-pay/internal/api/handlers"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/auth"
 )
 
-func NewServer(pool *pgxpool.Pool) http.Handler {
+type sessionChecker interface {
+	CheckSession(ctx context.Context, principal auth.Principal) error
+}
+
+// NewServer routes the API, the access tokens are accepted while the
+// sessions checker finds their session active.
+func NewServer(
+	f *factories.Factory,
+	tokens *auth.TokenManager,
+	sessions sessionChecker,
+) http.Handler {
+	cfg := f.Config()
 	r := http.NewServeMux()
+	authenticated := handlers.Authenticate(tokens, sessions)
+	readTransactions := handlers.AuthenticateAPIKey(f, tokens, sessions, models.ScopeTransactionsRead)
+	staff := func(permission auth.Permission, h http.Handler) http.Handler {
+		return authenticated(handlers.RequirePermission(permission)(h))
+	}
+
+	r.HandleFunc("POST /auth/login", handlers.HandleLogin(f, tokens))
+	r.HandleFunc("POST /auth/refresh", handlers.HandleRefresh(f, tokens))
+	r.HandleFunc("POST /auth/password/forgot", handlers.HandleForgotPassword(f, tokens))
+	r.HandleFunc("POST /auth/password/reset", handlers.HandleResetPassword(f, tokens))
+	r.Handle("POST /auth/password/change", authenticated(handlers.HandleChangePassword(f, tokens)))
+	r.Handle("GET /sessions", authenticated(handlers.HandleGetSessions(f, tokens)))
+	r.Handle("DELETE /sessions", authenticated(handlers.HandleRevokeSessions(f, tokens)))
+	r.Handle("DELETE /sessions/{id}", authenticated(handlers.HandleRevokeSession(f, tokens)))
+
+	r.Handle("GET /users", staff(auth.PermissionUsersRead, handlers.HandleGetUsers(f, cfg.Pagination)))
+	r.HandleFunc("POST /users", handlers.HandleCreateUser(f))
+	r.Handle("GET /users/{id}", authenticated(handlers.HandleGetUser(f)))
+	r.Handle("PATCH /users/{id}", authenticated(handlers.HandleUpdateUser(f)))
+	r.Handle("DELETE /users/{id}", authenticated(handlers.HandleCloseUser(f)))
+	r.HandleFunc("POST /users/verify-email", handlers.HandleVerifyEmail(f))
+	r.Handle("POST /users/{id}/verify-email/resend", authenticated(handlers.HandleResendVerification(f)))
+	r.Handle("GET /users/{id}/wallets", authenticated(handlers.HandleGetWallets(f)))
+	r.Handle("POST /users/{id}/wallets", authenticated(handlers.HandleCreateWallet(f)))
+	r.Handle("GET /users/{id}/bank-accounts", authenticated(handlers.HandleGetBankAccounts(f)))
+	r.Handle("POST /users/{id}/bank-accounts", authenticated(handlers.HandleCreateBankAccount(f)))
+	r.Handle("GET /users/{id}/transactions", readTransactions(handlers.HandleGetTransactions(f, cfg.Pagination)))
+	r.Handle("GET /users/{id}/statements", readTransactions(handlers.HandleGetStatement(f, cfg.Server.StatementWriteTimeout)))
+	r.Handle("POST /users/{id}/kyc", authenticated(handlers.HandleSubmitKYC(f)))
+	r.Handle("GET /users/{id}/kyc", authenticated(handlers.HandleGetKYC(f)))
+	r.Handle("POST /transfer", authenticated(handlers.HandleTransfer(f)))
+
+	r.Handle("POST /mfa/totp", authenticated(handlers.HandleEnrollTOTP(f)))
+	r.Handle("POST /mfa/totp/confirm", authenticated(handlers.HandleConfirmTOTP(f)))
+	r.Handle("POST /mfa/totp/disable", authenticated(handlers.HandleDisableTOTP(f)))
+	r.Handle("POST /mfa/recovery-codes", authenticated(handlers.HandleRegenerateRecoveryCodes(f)))
+
+	r.Handle("POST /api-keys", authenticated(handlers.HandleCreateAPIKey(f)))
+	r.Handle("GET /api-keys", authenticated(handlers.HandleGetAPIKeys(f)))
+	r.Handle("POST /api-keys/{id}/rotate", authenticated(handlers.HandleRotateAPIKey(f)))
+	r.Handle("DELETE /api-keys/{id}", authenticated(handlers.HandleRevokeAPIKey(f)))
+
+	r.Handle("POST /deposits", authenticated(handlers.HandleCreateDeposit(f)))
+	r.Handle("GET /deposits/{id}", authenticated(handlers.HandleGetDeposit(f)))
+	r.Handle("POST /deposits/{id}/confirm", staff(auth.PermissionDepositsConfirm, handlers.HandleConfirmDeposit(f)))
+	if secret := cfg.External.DepositWebhookSecret; secret != "" {
+		r.HandleFunc("POST /webhooks/deposits/{id}/confirm", handlers.HandleDepositFunding(f, secret))
+	}
+
+	r.Handle("POST /withdrawals", authenticated(handlers.HandleCreateWithdrawal(f)))
+	r.Handle("GET /withdrawals/{id}", authenticated(handlers.HandleGetWithdrawal(f)))
+
+	r.Handle("POST /admin/users/{id}/freeze", staff(auth.PermissionUsersFreeze, handlers.HandleFreezeUser(f)))
+	r.Handle("POST /admin/users/{id}/unfreeze", staff(auth.PermissionUsersFreeze, handlers.HandleUnfreezeUser(f)))
+	r.Handle("GET /admin/audit-events", staff(auth.PermissionAuditRead, handlers.HandleGetAuditEvents(f)))
+
+	r.Handle("GET /fraud/reviews", staff(auth.PermissionFraudRead, handlers.HandleGetFraudReviews(f)))
+	r.Handle("POST /fraud/reviews/{id}/approve", staff(auth.PermissionFraudResolve, handlers.HandleApproveFraudReview(f)))
+	r.Handle("POST /fraud/reviews/{id}/reject", staff(auth.PermissionFraudResolve, handlers.HandleRejectFraudReview(f)))
 
-	r.HandleFunc("GET /users", handlers.HandleGetUsers(pool))
-	r.HandleFunc("POST /users", handlers.HandleCreateUser(pool))
-	r.HandleFunc("POST /transfer", handlers.HandleTransfer(pool))
+	r.Handle("GET /kyc/submissions", staff(auth.PermissionKYCReview, handlers.HandleGetKYCSubmissions(f)))
+	r.Handle("POST /kyc/submissions/{id}/approve", staff(auth.PermissionKYCReview, handlers.HandleApproveKYC(f)))
+	r.Handle("POST /kyc/submissions/{id}/reject", staff(auth.PermissionKYCReview, handlers.HandleRejectKYC(f)))
 
-	return r
+	return handlers.Chain(
+		handlers.RequestID,
+		handlers.AccessLog,
+		handlers.Recover,
+		handlers.LimitBody(cfg.Server.MaxBodyBytes),
+	)(r)
 }
@@ -0,0 +1,61 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/api/router"
+	"github.com/edulustosa/go-pay/internal/config"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/factories"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/google/uuid"
+)
+
+var tokens = auth.NewTokenManager(
+	[]byte("a-secret-with-at-least-32-characters"),
+	time.Minute,
+)
+
+// The routes are checked without the database, every session is active
+type sessionsStub struct{}
+
+func (sessionsStub) CheckSession(_ context.Context, _ auth.Principal) error {
+	return nil
+}
+
+// Confirming a deposit credits the wallet without moving any money,
+// so the users must not reach it, not even for their own deposits.
+func TestNewServer_DepositConfirmation(t *testing.T) {
+	srv := router.NewServer(factories.New(nil, config.Default(), nil, nil), tokens, sessionsStub{})
+	path := "/deposits/" + uuid.NewString() + "/confirm"
+
+	t.Run("should forbid the users", func(t *testing.T) {
+		for _, role := range []models.Role{models.RoleCommon, models.RoleMerchant} {
+			token, _ := tokens.Issue(models.User{ID: uuid.New(), Role: role}, uuid.New())
+			req := httptest.NewRequest(http.MethodPost, path, nil)
+			req.Header.Set("Authorization", "Bearer "+token.Token)
+
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("%s got status %d, want %d", role, w.Code, http.StatusForbidden)
+			}
+		}
+	})
+
+	t.Run("should not register the webhook without a secret", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks"+path, nil)
+
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
@@ -0,0 +1,385 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config of the server and of the commands. Every field has an
+// environment variable, listed in .env.example.
+type Config struct {
+	Server     Server
+	Database   Database
+	Auth       Auth
+	Documents  Documents
+	External   External
+	Transfers  Transfers
+	Fraud      Fraud
+	Jobs       Jobs
+	Pagination Pagination
+}
+
+type Server struct {
+	Port            int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	// Statements are streamed, so they may take longer than WriteTimeout
+	StatementWriteTimeout time.Duration
+
+	// Largest request body accepted, in bytes
+	MaxBodyBytes int64
+}
+
+type Database struct {
+	URL           string
+	MaxConns      int
+	MigrationsDir string
+}
+
+type Auth struct {
+	// JWTSecret signs the access tokens and the pagination cursors
+	JWTSecret      string
+	AccessTokenTTL time.Duration
+
+	// Invalid two-factor codes in a row locking the codes for MFALockout
+	MFAMaxAttempts int
+	MFALockout     time.Duration
+}
+
+// Documents holds the keys of the keyring encrypting the user documents.
+type Documents struct {
+	// Comma separated list of id:base64 keys
+	Keys     string
+	KeyID    string
+	IndexKey string
+}
+
+// External services, the requests to them time out after Timeout.
+type External struct {
+	NotificationURL string
+	AuthorizerURL   string
+	FXRatesFile     string
+	Timeout         time.Duration
+
+	// DepositWebhookSecret signs the notices of the funding sources
+	// confirming deposits, the webhook is disabled without it
+	DepositWebhookSecret string
+}
+
+type Transfers struct {
+	// Transfers with a BRL equivalent above it require a two-factor code
+	StepUpAmount float64
+
+	// Fraction of the converted amount kept on cross-currency transfers
+	ExchangeSpread float64
+}
+
+// Fraud holds the thresholds of the screening of the transfers, the
+// amounts are in BRL and a rule with a zero score is disabled.
+type Fraud struct {
+	ReviewScore int
+	DenyScore   int
+
+	VelocityMaxTransfers int
+	VelocityWindow       time.Duration
+	VelocityScore        int
+
+	NewPayeeMinAmount float64
+	NewPayeeScore     int
+
+	NewAccountMaxAge    time.Duration
+	NewAccountMinAmount float64
+	NewAccountScore     int
+
+	RoundTripWindow time.Duration
+	RoundTripScore  int
+}
+
+// Jobs run in background by the server.
+type Jobs struct {
+	SettlementInterval     time.Duration
+	WithdrawalClaimTimeout time.Duration
+	ReconciliationInterval time.Duration
+	ReconciliationRecheck  time.Duration
+	ReconciliationFreeze   bool
+}
+
+// Pagination of the listings, clients may ask for pages
+// up to MaxPageSize on the ones that let them.
+type Pagination struct {
+	PageSize    int
+	MaxPageSize int
+}
+
+// minSecretSize is the least number of characters of JWT_SECRET
+const minSecretSize = 32
+
+// Load reads the configuration from the environment and from the optional
+// file at path, with KEY=VALUE lines as in .env.example. The environment
+// takes precedence over the file and empty values are ignored, so the
+// defaults apply. Every invalid or missing value is reported at once.
+func Load(path string) (*Config, error) {
+	values, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, errs := load(func(key string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+
+		return values[key]
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return c, nil
+}
+
+// Default returns the defaults, without the required values,
+// so it is only usable where they are not, like in tests.
+func Default() *Config {
+	c, _ := load(func(string) string { return "" })
+	return c
+}
+
+func load(lookup func(key string) string) (*Config, []error) {
+	l := &loader{lookup: lookup}
+	c := &Config{
+		Server: Server{
+			Port:                  l.int("PORT", 8080),
+			ReadTimeout:           l.duration("SERVER_READ_TIMEOUT", 5*time.Second),
+			WriteTimeout:          l.duration("SERVER_WRITE_TIMEOUT", 5*time.Second),
+			IdleTimeout:           l.duration("SERVER_IDLE_TIMEOUT", time.Minute),
+			ShutdownTimeout:       l.duration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+			StatementWriteTimeout: l.duration("STATEMENT_WRITE_TIMEOUT", 5*time.Minute),
+			MaxBodyBytes:          int64(l.int("MAX_BODY_BYTES", 1<<20)),
+		},
+		Database: Database{
+			URL:           l.required("POSTGRES_URL"),
+			MaxConns:      l.int("POSTGRES_MAX_CONNS", 10),
+			MigrationsDir: l.string("MIGRATIONS_DIR", "./internal/database/migrations"),
+		},
+		Auth: Auth{
+			JWTSecret:      l.required("JWT_SECRET"),
+			AccessTokenTTL: l.duration("ACCESS_TOKEN_TTL", 15*time.Minute),
+			MFAMaxAttempts: l.int("MFA_MAX_ATTEMPTS", 5),
+			MFALockout:     l.duration("MFA_LOCKOUT", 15*time.Minute),
+		},
+		Documents: Documents{
+			Keys:     l.required("DOCUMENT_KEYS"),
+			KeyID:    l.required("DOCUMENT_KEY_ID"),
+			IndexKey: l.required("DOCUMENT_INDEX_KEY"),
+		},
+		External: External{
+			NotificationURL:      l.url("NOTIFICATION_URL", "https://util.devi.tools/api/v1/notify"),
+			AuthorizerURL:        l.url("AUTHORIZER_URL", "https://util.devi.tools/api/v2/authorize"),
+			FXRatesFile:          l.string("FX_RATES_FILE", ""),
+			Timeout:              l.duration("EXTERNAL_TIMEOUT", 10*time.Second),
+			DepositWebhookSecret: l.string("DEPOSIT_WEBHOOK_SECRET", ""),
+		},
+		Transfers: Transfers{
+			StepUpAmount:   l.float("STEP_UP_TRANSFER_AMOUNT", 5000),
+			ExchangeSpread: l.float("EXCHANGE_SPREAD", 0.01),
+		},
+		Fraud: Fraud{
+			ReviewScore:          l.int("FRAUD_REVIEW_SCORE", 50),
+			DenyScore:            l.int("FRAUD_DENY_SCORE", 80),
+			VelocityMaxTransfers: l.int("FRAUD_VELOCITY_MAX_TRANSFERS", 10),
+			VelocityWindow:       l.duration("FRAUD_VELOCITY_WINDOW", 10*time.Minute),
+			VelocityScore:        l.int("FRAUD_VELOCITY_SCORE", 50),
+			NewPayeeMinAmount:    l.float("FRAUD_NEW_PAYEE_MIN_AMOUNT", 5000),
+			NewPayeeScore:        l.int("FRAUD_NEW_PAYEE_SCORE", 30),
+			NewAccountMaxAge:     l.duration("FRAUD_NEW_ACCOUNT_MAX_AGE", 24*time.Hour),
+			NewAccountMinAmount:  l.float("FRAUD_NEW_ACCOUNT_MIN_AMOUNT", 1000),
+			NewAccountScore:      l.int("FRAUD_NEW_ACCOUNT_SCORE", 50),
+			RoundTripWindow:      l.duration("FRAUD_ROUND_TRIP_WINDOW", time.Hour),
+			RoundTripScore:       l.int("FRAUD_ROUND_TRIP_SCORE", 30),
+		},
+		Jobs: Jobs{
+			SettlementInterval:     l.duration("SETTLEMENT_INTERVAL", 10*time.Second),
+			WithdrawalClaimTimeout: l.duration("WITHDRAWAL_CLAIM_TIMEOUT", 5*time.Minute),
+			ReconciliationInterval: l.duration("RECONCILIATION_INTERVAL", time.Hour),
+			ReconciliationRecheck:  l.duration("RECONCILIATION_RECHECK", 5*time.Second),
+			ReconciliationFreeze:   l.bool("RECONCILIATION_FREEZE", false),
+		},
+		Pagination: Pagination{
+			PageSize:    l.int("PAGE_SIZE", 20),
+			MaxPageSize: l.int("MAX_PAGE_SIZE", 100),
+		},
+	}
+
+	l.check(len(c.Auth.JWTSecret) >= minSecretSize || c.Auth.JWTSecret == "",
+		"JWT_SECRET", fmt.Sprintf("must have at least %d characters", minSecretSize))
+	l.check(len(c.External.DepositWebhookSecret) >= minSecretSize || c.External.DepositWebhookSecret == "",
+		"DEPOSIT_WEBHOOK_SECRET", fmt.Sprintf("must have at least %d characters", minSecretSize))
+	l.check(c.Auth.MFAMaxAttempts > 0, "MFA_MAX_ATTEMPTS", "must be positive")
+	l.check(c.Server.Port > 0 && c.Server.Port <= 65535,
+		"PORT", "must be between 1 and 65535")
+	l.check(c.Server.MaxBodyBytes > 0, "MAX_BODY_BYTES", "must be positive")
+	l.check(c.Database.MaxConns > 0 && c.Database.MaxConns <= math.MaxInt32,
+		"POSTGRES_MAX_CONNS", "must be a positive 32-bit integer")
+	l.check(c.Transfers.StepUpAmount >= 0, "STEP_UP_TRANSFER_AMOUNT", "must not be negative")
+	l.check(c.Transfers.ExchangeSpread >= 0 && c.Transfers.ExchangeSpread < 1,
+		"EXCHANGE_SPREAD", "must be at least 0 and less than 1")
+	l.check(c.Fraud.ReviewScore > 0, "FRAUD_REVIEW_SCORE", "must be positive")
+	l.check(c.Fraud.DenyScore >= c.Fraud.ReviewScore,
+		"FRAUD_DENY_SCORE", "must be at least FRAUD_REVIEW_SCORE")
+	l.check(c.Fraud.VelocityMaxTransfers > 0, "FRAUD_VELOCITY_MAX_TRANSFERS", "must be positive")
+	for key, score := range map[string]int{
+		"FRAUD_VELOCITY_SCORE":    c.Fraud.VelocityScore,
+		"FRAUD_NEW_PAYEE_SCORE":   c.Fraud.NewPayeeScore,
+		"FRAUD_NEW_ACCOUNT_SCORE": c.Fraud.NewAccountScore,
+		"FRAUD_ROUND_TRIP_SCORE":  c.Fraud.RoundTripScore,
+	} {
+		l.check(score >= 0, key, "must not be negative")
+	}
+	l.check(c.Jobs.WithdrawalClaimTimeout > c.External.Timeout,
+		"WITHDRAWAL_CLAIM_TIMEOUT", "must be longer than EXTERNAL_TIMEOUT")
+	l.check(c.Pagination.MaxPageSize > 0, "MAX_PAGE_SIZE", "must be positive")
+	l.check(c.Pagination.PageSize > 0 && c.Pagination.PageSize <= c.Pagination.MaxPageSize,
+		"PAGE_SIZE", "must be between 1 and MAX_PAGE_SIZE")
+
+	return c, l.errs
+}
+
+// readFile parses the KEY=VALUE lines of the file, skipping the blank
+// ones and the comments. The values may be quoted.
+func readFile(path string) (map[string]string, error) {
+	values := make(map[string]string)
+	if path == "" {
+		return values, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the config file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		values[strings.TrimSpace(key)] = value
+	}
+
+	return values, scanner.Err()
+}
+
+// loader parses the values, collecting the errors
+// so they are all reported together.
+type loader struct {
+	lookup func(key string) string
+	errs   []error
+}
+
+func (l *loader) check(ok bool, key, problem string) {
+	if !ok {
+		l.errs = append(l.errs, fmt.Errorf("%s %s", key, problem))
+	}
+}
+
+func (l *loader) string(key, fallback string) string {
+	if value := l.lookup(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func (l *loader) required(key string) string {
+	value := l.lookup(key)
+	l.check(value != "", key, "is required")
+
+	return value
+}
+
+// parse returns the parsed value of the key, or the
+// fallback if it is not set or is invalid.
+func parse[T any](
+	l *loader,
+	key string,
+	fallback T,
+	problem string,
+	convert func(string) (T, error),
+) T {
+	value := l.lookup(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := convert(value)
+	if err != nil {
+		l.check(false, key, problem)
+		return fallback
+	}
+
+	return parsed
+}
+
+func (l *loader) int(key string, fallback int) int {
+	return parse(l, key, fallback, "must be an integer", strconv.Atoi)
+}
+
+func (l *loader) float(key string, fallback float64) float64 {
+	return parse(l, key, fallback, "must be a number", func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	return parse(l, key, fallback, "must be true or false", strconv.ParseBool)
+}
+
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	return parse(l, key, fallback, "must be a positive duration, e.g. 30s or 5m",
+		func(s string) (time.Duration, error) {
+			d, err := time.ParseDuration(s)
+			if err == nil && d <= 0 {
+				err = errors.New("not positive")
+			}
+
+			return d, err
+		},
+	)
+}
+
+func (l *loader) url(key, fallback string) string {
+	return parse(l, key, fallback, "must be an absolute http or https URL",
+		func(s string) (string, error) {
+			u, err := url.Parse(s)
+			if err == nil && (u.Scheme != "http" && u.Scheme != "https" || u.Host == "") {
+				err = errors.New("not an http URL")
+			}
+
+			return s, err
+		},
+	)
+}
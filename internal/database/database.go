@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/edulustosa/go-pay/internal/config"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Connect opens the pool and checks the database is reachable.
+func Connect(ctx context.Context, cfg config.Database) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid POSTGRES_URL: %w", err)
+	}
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'ReviewStatus') THEN
+        CREATE TYPE "ReviewStatus" AS ENUM('PENDING', 'APPROVED', 'REJECTED');
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS fraud_reviews (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "payer" UUID NOT NULL,
+    "payee" UUID NOT NULL,
+    "amount" DECIMAL NOT NULL,
+    "score" INTEGER NOT NULL,
+    "reasons" TEXT[] NOT NULL DEFAULT '{}',
+    "status" "ReviewStatus" NOT NULL DEFAULT 'PENDING',
+    "transaction_id" UUID,
+    "resolution_note" TEXT NOT NULL DEFAULT '',
+    "resolved_at" TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (payer) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (payee) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (transaction_id) REFERENCES transactions (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS transactions_payer_created_at_idx ON transactions (payer, created_at);
+CREATE INDEX IF NOT EXISTS transactions_payer_payee_idx ON transactions (payer, payee);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transactions_payer_payee_idx;
+DROP INDEX IF EXISTS transactions_payer_created_at_idx;
+DROP TABLE IF EXISTS fraud_reviews;
+DROP TYPE IF EXISTS "ReviewStatus";
+-- +goose StatementEnd
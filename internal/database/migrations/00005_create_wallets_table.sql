@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The BRL balance stays in users.balance, wallets hold every other currency.
+CREATE TABLE IF NOT EXISTS wallets (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "currency" CHAR(3) NOT NULL,
+    "balance" DECIMAL NOT NULL DEFAULT 0,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, currency),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS "currency" CHAR(3) NOT NULL DEFAULT 'BRL',
+    ADD COLUMN IF NOT EXISTS "payee_amount" DECIMAL,
+    ADD COLUMN IF NOT EXISTS "payee_currency" CHAR(3) NOT NULL DEFAULT 'BRL',
+    ADD COLUMN IF NOT EXISTS "fx_rate" DECIMAL NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS "fx_spread" DECIMAL NOT NULL DEFAULT 0;
+
+UPDATE transactions SET payee_amount = amount WHERE payee_amount IS NULL;
+ALTER TABLE transactions ALTER COLUMN "payee_amount" SET NOT NULL;
+
+ALTER TABLE fraud_reviews
+    ADD COLUMN IF NOT EXISTS "currency" CHAR(3) NOT NULL DEFAULT 'BRL',
+    ADD COLUMN IF NOT EXISTS "payee_currency" CHAR(3) NOT NULL DEFAULT 'BRL';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE fraud_reviews
+    DROP COLUMN IF EXISTS "payee_currency",
+    DROP COLUMN IF EXISTS "currency";
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS "fx_spread",
+    DROP COLUMN IF EXISTS "fx_rate",
+    DROP COLUMN IF EXISTS "payee_currency",
+    DROP COLUMN IF EXISTS "payee_amount",
+    DROP COLUMN IF EXISTS "currency";
+
+DROP TABLE IF EXISTS wallets;
+-- +goose StatementEnd
@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'FundingSource') THEN
+        CREATE TYPE "FundingSource" AS ENUM('BANK_TRANSFER', 'CARD', 'INITIAL_BALANCE');
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'DepositStatus') THEN
+        CREATE TYPE "DepositStatus" AS ENUM('PENDING', 'CONFIRMED', 'FAILED');
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS deposits (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "amount" DECIMAL NOT NULL,
+    "currency" CHAR(3) NOT NULL DEFAULT 'BRL',
+    "source" "FundingSource" NOT NULL,
+    "status" "DepositStatus" NOT NULL DEFAULT 'PENDING',
+    "reference" VARCHAR(255) NOT NULL DEFAULT '',
+    "confirmed_at" TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- Balances given at signup have no record, so they are kept as
+-- confirmed deposits to make every balance traceable.
+INSERT INTO deposits (user_id, amount, source, status, confirmed_at, created_at, updated_at)
+SELECT
+    u.id,
+    u.balance
+        - COALESCE((SELECT SUM(t.payee_amount) FROM transactions t
+            WHERE t.payee = u.id AND t.payee_currency = 'BRL'), 0)
+        + COALESCE((SELECT SUM(t.amount) FROM transactions t
+            WHERE t.payer = u.id AND t.currency = 'BRL'), 0),
+    'INITIAL_BALANCE',
+    'CONFIRMED',
+    u.created_at,
+    u.created_at,
+    u.created_at
+FROM users u
+WHERE u.balance
+    - COALESCE((SELECT SUM(t.payee_amount) FROM transactions t
+        WHERE t.payee = u.id AND t.payee_currency = 'BRL'), 0)
+    + COALESCE((SELECT SUM(t.amount) FROM transactions t
+        WHERE t.payer = u.id AND t.currency = 'BRL'), 0) > 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS deposits;
+DROP TYPE IF EXISTS "DepositStatus";
+DROP TYPE IF EXISTS "FundingSource";
+-- +goose StatementEnd
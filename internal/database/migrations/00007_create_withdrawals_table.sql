@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'AccountType') THEN
+        CREATE TYPE "AccountType" AS ENUM('CHECKING', 'SAVINGS', 'PAYMENT');
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'WithdrawalStatus') THEN
+        CREATE TYPE "WithdrawalStatus" AS ENUM('PENDING', 'PROCESSING', 'COMPLETED', 'FAILED');
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS bank_accounts (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "bank_code" CHAR(3) NOT NULL,
+    "branch" VARCHAR(6) NOT NULL,
+    "account_number" VARCHAR(14) NOT NULL,
+    "account_type" "AccountType" NOT NULL DEFAULT 'CHECKING',
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, bank_code, branch, account_number),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS withdrawals (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "bank_account_id" UUID NOT NULL,
+    "amount" DECIMAL NOT NULL,
+    "status" "WithdrawalStatus" NOT NULL DEFAULT 'PENDING',
+    "reference" VARCHAR(255) NOT NULL DEFAULT '',
+    "failure_reason" TEXT NOT NULL DEFAULT '',
+    "settled_at" TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (bank_account_id) REFERENCES bank_accounts (id) ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS withdrawals_status_idx ON withdrawals (status, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS withdrawals;
+DROP TABLE IF EXISTS bank_accounts;
+DROP TYPE IF EXISTS "WithdrawalStatus";
+DROP TYPE IF EXISTS "AccountType";
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS api_keys (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "name" VARCHAR(100) NOT NULL,
+    "prefix" VARCHAR(16) NOT NULL UNIQUE,
+    "key_hash" CHAR(64) NOT NULL,
+    "scopes" TEXT[] NOT NULL DEFAULT '{}',
+    "last_used_at" TIMESTAMP,
+    "revoked_at" TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_idx ON api_keys (user_id, created_at);
+CREATE INDEX IF NOT EXISTS transactions_payee_idx ON transactions (payee, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transactions_payee_idx;
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
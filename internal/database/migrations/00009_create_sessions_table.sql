@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sessions (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "refresh_token_hash" CHAR(64) NOT NULL,
+    "user_agent" VARCHAR(255) NOT NULL DEFAULT '',
+    "ip" VARCHAR(45) NOT NULL DEFAULT '',
+    "expires_at" TIMESTAMP NOT NULL,
+    "last_used_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "revoked_at" TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_idx ON sessions (user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sessions;
+-- +goose StatementEnd
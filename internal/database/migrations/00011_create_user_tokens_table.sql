@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'TokenPurpose') THEN
+        CREATE TYPE "TokenPurpose" AS ENUM('PASSWORD_RESET');
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "purpose" "TokenPurpose" NOT NULL,
+    "token_hash" CHAR(64) NOT NULL UNIQUE,
+    "expires_at" TIMESTAMP NOT NULL,
+    "used_at" TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_user_idx ON user_tokens (user_id, purpose, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_tokens;
+DROP TYPE IF EXISTS "TokenPurpose";
+-- +goose StatementEnd
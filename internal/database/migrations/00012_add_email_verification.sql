@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE "TokenPurpose" ADD VALUE IF NOT EXISTS 'EMAIL_VERIFICATION';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "email_verified_at" TIMESTAMP;
+
+-- Users created before the verification existed keep transacting
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS "email_verified_at";
+DELETE FROM user_tokens WHERE purpose = 'EMAIL_VERIFICATION';
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'DocumentType') THEN
+        CREATE TYPE "DocumentType" AS ENUM('CPF', 'CNPJ');
+    END IF;
+END $$;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "document_type" "DocumentType" NOT NULL DEFAULT 'CPF';
+
+UPDATE users SET document_type = 'CNPJ' WHERE length(document) = 14;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS "document_type";
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_type WHERE typname = 'DocumentType') THEN
+        DROP TYPE "DocumentType";
+    END IF;
+END $$;
+-- +goose StatementEnd
@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "deleted_at" TIMESTAMP;
+
+-- Closed accounts release their email and document
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_document_key,
+    DROP CONSTRAINT IF EXISTS users_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_document_idx ON users (document) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (email) WHERE deleted_at IS NULL;
+
+-- Users are soft deleted, the financial history must never be removed with them
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_payer_fkey,
+    DROP CONSTRAINT IF EXISTS transactions_payee_fkey,
+    ADD CONSTRAINT transactions_payer_fkey FOREIGN KEY (payer) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    ADD CONSTRAINT transactions_payee_fkey FOREIGN KEY (payee) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+ALTER TABLE fraud_reviews
+    DROP CONSTRAINT IF EXISTS fraud_reviews_payer_fkey,
+    DROP CONSTRAINT IF EXISTS fraud_reviews_payee_fkey,
+    ADD CONSTRAINT fraud_reviews_payer_fkey FOREIGN KEY (payer) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    ADD CONSTRAINT fraud_reviews_payee_fkey FOREIGN KEY (payee) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+ALTER TABLE deposits
+    DROP CONSTRAINT IF EXISTS deposits_user_id_fkey,
+    ADD CONSTRAINT deposits_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+ALTER TABLE withdrawals
+    DROP CONSTRAINT IF EXISTS withdrawals_user_id_fkey,
+    ADD CONSTRAINT withdrawals_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+ALTER TABLE bank_accounts
+    DROP CONSTRAINT IF EXISTS bank_accounts_user_id_fkey,
+    ADD CONSTRAINT bank_accounts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bank_accounts
+    DROP CONSTRAINT IF EXISTS bank_accounts_user_id_fkey,
+    ADD CONSTRAINT bank_accounts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE withdrawals
+    DROP CONSTRAINT IF EXISTS withdrawals_user_id_fkey,
+    ADD CONSTRAINT withdrawals_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE deposits
+    DROP CONSTRAINT IF EXISTS deposits_user_id_fkey,
+    ADD CONSTRAINT deposits_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE fraud_reviews
+    DROP CONSTRAINT IF EXISTS fraud_reviews_payer_fkey,
+    DROP CONSTRAINT IF EXISTS fraud_reviews_payee_fkey,
+    ADD CONSTRAINT fraud_reviews_payer_fkey FOREIGN KEY (payer) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    ADD CONSTRAINT fraud_reviews_payee_fkey FOREIGN KEY (payee) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_payer_fkey,
+    DROP CONSTRAINT IF EXISTS transactions_payee_fkey,
+    ADD CONSTRAINT transactions_payer_fkey FOREIGN KEY (payer) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    ADD CONSTRAINT transactions_payee_fkey FOREIGN KEY (payee) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS users_email_idx;
+DROP INDEX IF EXISTS users_document_idx;
+
+-- Fails if a closed account shares its email or document with another user
+ALTER TABLE users
+    ADD CONSTRAINT users_document_key UNIQUE (document),
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE users DROP COLUMN IF EXISTS "deleted_at";
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Staff accounts are promoted directly in the database, never through the API
+ALTER TYPE "Role" ADD VALUE IF NOT EXISTS 'ADMIN';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'AccountStatus') THEN
+        CREATE TYPE "AccountStatus" AS ENUM('ACTIVE', 'FROZEN', 'CLOSED');
+    END IF;
+END $$;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS "status" "AccountStatus" NOT NULL DEFAULT 'ACTIVE',
+    ADD COLUMN IF NOT EXISTS "frozen_reason" TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "frozen_by" UUID,
+    ADD COLUMN IF NOT EXISTS "frozen_at" TIMESTAMP,
+    ADD FOREIGN KEY (frozen_by) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+UPDATE users SET status = 'CLOSED' WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS "frozen_at",
+    DROP COLUMN IF EXISTS "frozen_by",
+    DROP COLUMN IF EXISTS "frozen_reason",
+    DROP COLUMN IF EXISTS "status";
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_type WHERE typname = 'AccountStatus') THEN
+        DROP TYPE "AccountStatus";
+    END IF;
+END $$;
+
+-- Enum values cannot be removed, ADMIN stays in the "Role" type
+-- +goose StatementEnd
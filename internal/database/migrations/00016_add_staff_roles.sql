@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE "Role" ADD VALUE IF NOT EXISTS 'SUPPORT';
+ALTER TYPE "Role" ADD VALUE IF NOT EXISTS 'FINANCE';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Enum values cannot be removed, staff users are demoted instead
+UPDATE users SET role = 'COMMON' WHERE role IN ('SUPPORT', 'FINANCE');
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'KYCLevel') THEN
+        CREATE TYPE "KYCLevel" AS ENUM('BASIC', 'INTERMEDIATE', 'FULL');
+    END IF;
+END $$;
+
+-- Existing users must submit their data as well to unlock the higher levels
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "kyc_level" "KYCLevel" NOT NULL DEFAULT 'BASIC';
+
+CREATE TABLE IF NOT EXISTS kyc_submissions (
+    "id" UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid (),
+    "user_id" UUID NOT NULL,
+    "level" "KYCLevel" NOT NULL,
+    "birth_date" DATE,
+    "address_line" VARCHAR(255) NOT NULL DEFAULT '',
+    "city" VARCHAR(255) NOT NULL DEFAULT '',
+    "state" VARCHAR(2) NOT NULL DEFAULT '',
+    "postal_code" VARCHAR(8) NOT NULL DEFAULT '',
+    "documents" JSONB NOT NULL DEFAULT '[]',
+    "status" "ReviewStatus" NOT NULL DEFAULT 'PENDING',
+    "reviewed_by" UUID,
+    "review_note" TEXT NOT NULL DEFAULT '',
+    "reviewed_at" TIMESTAMP,
+    "created_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (reviewed_by) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS kyc_submissions_user_id_idx ON kyc_submissions (user_id, created_at);
+
+-- A single submission awaits review per user
+CREATE UNIQUE INDEX IF NOT EXISTS kyc_submissions_pending_idx ON kyc_submissions (user_id) WHERE status = 'PENDING';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS kyc_submissions;
+ALTER TABLE users DROP COLUMN IF EXISTS "kyc_level";
+DROP TYPE IF EXISTS "KYCLevel";
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The documents are encrypted and indexed by the application when it
+-- starts, as the keys are not available to the migrations
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "document_index" VARCHAR(64);
+
+-- Ciphertexts are randomized, so uniqueness is enforced by the blind index
+DROP INDEX IF EXISTS users_document_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS users_document_index_idx ON users (document_index) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The documents must be decrypted before rolling back, it can't be done here
+DROP INDEX IF EXISTS users_document_index_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS users_document_idx ON users (document) WHERE deleted_at IS NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS "document_index";
+-- +goose StatementEnd
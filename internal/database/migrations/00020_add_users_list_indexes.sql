@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Indexes of the sorts and filters of the staff users listing
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS users_name_idx ON users (first_name, last_name, id);
+CREATE INDEX IF NOT EXISTS users_lower_email_idx ON users (lower(email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_lower_email_idx;
+DROP INDEX IF EXISTS users_name_idx;
+DROP INDEX IF EXISTS users_created_at_idx;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- When a worker claimed the withdrawal to pay it out, the claims older than
+-- the timeout are given back to the queue, as their worker may have died
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS "claimed_at" TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS withdrawals_claimed_at_idx ON withdrawals (claimed_at)
+WHERE status = 'PROCESSING';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS withdrawals_claimed_at_idx;
+ALTER TABLE withdrawals DROP COLUMN IF EXISTS "claimed_at";
+-- +goose StatementEnd
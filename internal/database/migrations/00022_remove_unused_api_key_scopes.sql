@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The refunds and charges scopes are kept on the keys that were granted
+-- them, they are reserved for the routes still to come
+SELECT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 1;
+-- +goose StatementEnd
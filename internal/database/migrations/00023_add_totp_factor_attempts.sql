@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Invalid codes in a row, the factor is locked for a while after too many
+ALTER TABLE totp_factors ADD COLUMN IF NOT EXISTS "failed_attempts" INT NOT NULL DEFAULT 0;
+ALTER TABLE totp_factors ADD COLUMN IF NOT EXISTS "locked_until" TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE totp_factors DROP COLUMN IF EXISTS "locked_until";
+ALTER TABLE totp_factors DROP COLUMN IF EXISTS "failed_attempts";
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Emails differing only in the case belong to the same mailbox. Fails if
+-- two open accounts already share an email like that.
+CREATE UNIQUE INDEX IF NOT EXISTS users_lower_email_key ON users (lower(email)) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS users_lower_email_idx;
+DROP INDEX IF EXISTS users_email_idx;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_idx ON users (email) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS users_lower_email_idx ON users (lower(email));
+DROP INDEX IF EXISTS users_lower_email_key;
+-- +goose StatementEnd
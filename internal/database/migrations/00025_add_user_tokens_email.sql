@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS "email" TEXT NOT NULL DEFAULT '';
+
+UPDATE user_tokens SET email = users.email
+FROM users WHERE users.id = user_tokens.user_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS "email";
+-- +goose StatementEnd
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"log/slog"
+	"time"
+
+	"github.com/edulustosa/go-pay/helpers"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -10,26 +14,464 @@ type Role string
 const (
 	RoleCommon   Role = "COMMON"
 	RoleMerchant Role = "MERCHANT"
+	RoleAdmin    Role = "ADMIN"
+	RoleSupport  Role = "SUPPORT"
+	RoleFinance  Role = "FINANCE"
+)
+
+type AccountStatus string
+
+const (
+	StatusActive AccountStatus = "ACTIVE"
+	StatusFrozen AccountStatus = "FROZEN"
+	StatusClosed AccountStatus = "CLOSED"
+)
+
+// DocumentType is the kind of the user document, CPF for individuals
+// and CNPJ for companies.
+type DocumentType string
+
+const (
+	DocumentCPF  DocumentType = "CPF"
+	DocumentCNPJ DocumentType = "CNPJ"
 )
 
 type User struct {
+	ID              uuid.UUID
+	FirstName       string
+	LastName        string
+	Document        string
+	Email           string
+	PasswordHash    string
+	Balance         float64
+	Role            Role
+	CreatedAt       pgtype.Timestamp
+	UpdatedAt       pgtype.Timestamp
+	EmailVerifiedAt pgtype.Timestamp
+	DocumentType    DocumentType
+	DeletedAt       pgtype.Timestamp
+	Status          AccountStatus
+	FrozenReason    string
+	FrozenBy        pgtype.UUID
+	FrozenAt        pgtype.Timestamp
+	KYCLevel        KYCLevel
+}
+
+// LogValue keeps the password hash out of the logs and masks
+// the personal data of the user.
+func (u User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", u.ID.String()),
+		slog.String("document", helpers.MaskDocument(u.Document)),
+		slog.String("email", helpers.MaskEmail(u.Email)),
+		slog.String("role", string(u.Role)),
+		slog.String("status", string(u.Status)),
+	)
+}
+
+// Cursor is a position in a listing ordered by (created_at, id). The page
+// after it is fetched, or the one before it when Backward is set.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Backward  bool
+}
+
+// Page is a page of a listing paginated by cursors, Next and
+// Prev are nil when there are no pages after or before it.
+type Page[T any] struct {
+	Items []T
+	Total int
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+type UserSort string
+
+const (
+	UserSortCreatedAt UserSort = "createdAt"
+	UserSortName      UserSort = "name"
+	UserSortEmail     UserSort = "email"
+)
+
+// UserFilter narrows the users listed, zero fields match any user. Closed
+// users are only listed when filtering by their status. The creation range
+// includes CreatedFrom and excludes CreatedTo.
+type UserFilter struct {
+	Role        Role
+	NamePrefix  string
+	Email       string
+	Status      AccountStatus
+	CreatedFrom pgtype.Timestamp
+	CreatedTo   pgtype.Timestamp
+	Sort        UserSort
+	Descending  bool
+}
+
+// Transaction amounts are in the payer currency, the payee amount is
+// the converted value credited to the payee when the currencies differ.
+type Transaction struct {
+	ID            uuid.UUID
+	Amount        float64
+	Payer         uuid.UUID
+	Payee         uuid.UUID
+	CreatedAt     pgtype.Timestamp
+	UpdatedAt     pgtype.Timestamp
+	Currency      string
+	PayeeAmount   float64
+	PayeeCurrency string
+	FXRate        float64
+	FXSpread      float64
+}
+
+type EntryKind string
+
+const (
+	EntryTransferOut        EntryKind = "TRANSFER_OUT"
+	EntryTransferIn         EntryKind = "TRANSFER_IN"
+	EntryDeposit            EntryKind = "DEPOSIT"
+	EntryWithdrawal         EntryKind = "WITHDRAWAL"
+	EntryWithdrawalReversal EntryKind = "WITHDRAWAL_REVERSAL"
+)
+
+// StatementEntry is a change of an user balance made by a transfer,
+// deposit or withdrawal, the one of the ID. Debits are negative.
+type StatementEntry struct {
 	ID           uuid.UUID
-	FirstName    string
-	LastName     string
-	Document     string
-	Email        string
-	PasswordHash string
-	Balance      float64
-	Role         Role
+	Kind         EntryKind
+	Amount       float64
 	CreatedAt    pgtype.Timestamp
-	UpdatedAt    pgtype.Timestamp
+	Counterparty pgtype.UUID
 }
 
-type Transaction struct {
+// Discrepancy is a balance of an user which differs from the
+// one rebuilt from the entries of its statement.
+type Discrepancy struct {
+	UserID   uuid.UUID `json:"userId"`
+	Currency string    `json:"currency"`
+	Expected float64   `json:"expected"`
+	Actual   float64   `json:"actual"`
+}
+
+// Wallet holds the balance of an user in a currency other than BRL,
+// which is kept in the user balance.
+type Wallet struct {
 	ID        uuid.UUID
-	Amount    float64
-	Payer     uuid.UUID
-	Payee     uuid.UUID
+	UserID    uuid.UUID
+	Currency  string
+	Balance   float64
 	CreatedAt pgtype.Timestamp
 	UpdatedAt pgtype.Timestamp
 }
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "PENDING"
+	ReviewApproved ReviewStatus = "APPROVED"
+	ReviewRejected ReviewStatus = "REJECTED"
+)
+
+// FraudReview is a transfer held by the fraud screening for manual review.
+type FraudReview struct {
+	ID             uuid.UUID
+	Payer          uuid.UUID
+	Payee          uuid.UUID
+	Amount         float64
+	Score          int
+	Reasons        []string
+	Status         ReviewStatus
+	TransactionID  pgtype.UUID
+	ResolutionNote string
+	ResolvedAt     pgtype.Timestamp
+	CreatedAt      pgtype.Timestamp
+	UpdatedAt      pgtype.Timestamp
+	Currency       string
+	PayeeCurrency  string
+}
+
+type FundingSource string
+
+const (
+	SourceBankTransfer   FundingSource = "BANK_TRANSFER"
+	SourceCard           FundingSource = "CARD"
+	SourceInitialBalance FundingSource = "INITIAL_BALANCE"
+)
+
+type DepositStatus string
+
+const (
+	DepositPending   DepositStatus = "PENDING"
+	DepositConfirmed DepositStatus = "CONFIRMED"
+	DepositFailed    DepositStatus = "FAILED"
+)
+
+type Deposit struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Amount      float64
+	Currency    string
+	Source      FundingSource
+	Status      DepositStatus
+	Reference   string
+	ConfirmedAt pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+}
+
+type AccountType string
+
+const (
+	AccountChecking AccountType = "CHECKING"
+	AccountSavings  AccountType = "SAVINGS"
+	AccountPayment  AccountType = "PAYMENT"
+)
+
+type BankAccount struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	BankCode      string
+	Branch        string
+	AccountNumber string
+	AccountType   AccountType
+	CreatedAt     pgtype.Timestamp
+	UpdatedAt     pgtype.Timestamp
+}
+
+type WithdrawalStatus string
+
+const (
+	WithdrawalPending    WithdrawalStatus = "PENDING"
+	WithdrawalProcessing WithdrawalStatus = "PROCESSING"
+	WithdrawalCompleted  WithdrawalStatus = "COMPLETED"
+	WithdrawalFailed     WithdrawalStatus = "FAILED"
+)
+
+// Withdrawal amounts are always in BRL and held from the
+// user balance while the withdrawal is not settled.
+type Withdrawal struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	BankAccountID uuid.UUID
+	Amount        float64
+	Status        WithdrawalStatus
+	Reference     string
+	FailureReason string
+	SettledAt     pgtype.Timestamp
+	CreatedAt     pgtype.Timestamp
+	UpdatedAt     pgtype.Timestamp
+	ClaimedAt     pgtype.Timestamp
+}
+
+type Scope string
+
+const (
+	ScopeTransactionsRead Scope = "transactions:read"
+	// ScopeRefundsWrite and ScopeChargesWrite are reserved, no route
+	// checks them yet, merchants can request them ahead of the refunds
+	// and charges endpoints.
+	ScopeRefundsWrite Scope = "refunds:write"
+	ScopeChargesWrite Scope = "charges:write"
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []Scope{
+	ScopeTransactionsRead,
+	ScopeRefundsWrite,
+	ScopeChargesWrite,
+}
+
+// APIKey authenticates the backend of a merchant. Only the hash of the
+// secret is stored, the prefix identifies the key to its owner and to us.
+type APIKey struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []Scope
+	LastUsedAt pgtype.Timestamp
+	RevokedAt  pgtype.Timestamp
+	CreatedAt  pgtype.Timestamp
+	UpdatedAt  pgtype.Timestamp
+}
+
+func (k APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Session is a login of an user on a device, kept alive by refreshing
+// its access token. Only the hash of the current refresh token is stored.
+type Session struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	RefreshTokenHash string
+	UserAgent        string
+	IP               string
+	ExpiresAt        pgtype.Timestamp
+	LastUsedAt       pgtype.Timestamp
+	RevokedAt        pgtype.Timestamp
+	CreatedAt        pgtype.Timestamp
+	UpdatedAt        pgtype.Timestamp
+}
+
+// TOTPFactor is the authenticator of an user, it only protects the
+// account once confirmed with a first code. The last used step keeps
+// a code from being accepted twice.
+type TOTPFactor struct {
+	UserID       uuid.UUID
+	Secret       string
+	ConfirmedAt  pgtype.Timestamp
+	LastUsedStep int64
+	CreatedAt    pgtype.Timestamp
+	UpdatedAt    pgtype.Timestamp
+
+	// Invalid codes in a row, after too many the factor
+	// rejects every code until LockedUntil
+	FailedAttempts int
+	LockedUntil    pgtype.Timestamp
+}
+
+// RecoveryCode replaces a TOTP code once, when the authenticator is lost.
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    pgtype.Timestamp
+	CreatedAt pgtype.Timestamp
+}
+
+type TokenPurpose string
+
+const (
+	PurposePasswordReset     TokenPurpose = "PASSWORD_RESET"
+	PurposeEmailVerification TokenPurpose = "EMAIL_VERIFICATION"
+)
+
+// UserToken is a single use secret sent to the user, like the one to
+// reset a forgotten password. Only its hash is stored.
+type UserToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   TokenPurpose
+	TokenHash string
+	ExpiresAt pgtype.Timestamp
+	UsedAt    pgtype.Timestamp
+	CreatedAt pgtype.Timestamp
+
+	// Email the token was sent to
+	Email string
+}
+
+// KYCLevel is how much of its identity an user proved, each level
+// unlocks higher limits and more features.
+type KYCLevel string
+
+const (
+	KYCBasic        KYCLevel = "BASIC"
+	KYCIntermediate KYCLevel = "INTERMEDIATE"
+	KYCFull         KYCLevel = "FULL"
+)
+
+type KYCDocumentKind string
+
+const (
+	DocumentIDFront KYCDocumentKind = "ID_FRONT"
+	DocumentIDBack  KYCDocumentKind = "ID_BACK"
+	DocumentSelfie  KYCDocumentKind = "SELFIE"
+)
+
+// KYCDocument is the metadata of an image kept in the document
+// storage, the images themselves are never stored in the database.
+type KYCDocument struct {
+	Kind        KYCDocumentKind `json:"kind"`
+	StorageKey  string          `json:"storageKey"`
+	ContentType string          `json:"contentType"`
+	Size        int64           `json:"size"`
+	SHA256      string          `json:"sha256"`
+}
+
+// KYCSubmission is the data sent by an user to reach the next KYC level,
+// the user is only promoted once the support staff approves it.
+type KYCSubmission struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Level       KYCLevel
+	BirthDate   pgtype.Date
+	AddressLine string
+	City        string
+	State       string
+	PostalCode  string
+	Documents   []KYCDocument
+	Status      ReviewStatus
+	ReviewedBy  pgtype.UUID
+	ReviewNote  string
+	ReviewedAt  pgtype.Timestamp
+	CreatedAt   pgtype.Timestamp
+	UpdatedAt   pgtype.Timestamp
+}
+
+// AuditAction names an operation as <resource>.<verb>
+type AuditAction string
+
+const (
+	AuditUserCreate         AuditAction = "user.create"
+	AuditUserUpdate         AuditAction = "user.update"
+	AuditUserClose          AuditAction = "user.close"
+	AuditUserFreeze         AuditAction = "user.freeze"
+	AuditUserUnfreeze       AuditAction = "user.unfreeze"
+	AuditPasswordChange     AuditAction = "user.password_change"
+	AuditPasswordReset      AuditAction = "user.password_reset"
+	AuditEmailVerify        AuditAction = "user.email_verify"
+	AuditSessionCreate      AuditAction = "session.create"
+	AuditSessionRevoke      AuditAction = "session.revoke"
+	AuditSessionRevokeAll   AuditAction = "session.revoke_all"
+	AuditTransferCreate     AuditAction = "transfer.create"
+	AuditFraudReviewApprove AuditAction = "fraud_review.approve"
+	AuditFraudReviewReject  AuditAction = "fraud_review.reject"
+	AuditWalletCreate       AuditAction = "wallet.create"
+	AuditDepositCreate      AuditAction = "deposit.create"
+	AuditDepositConfirm     AuditAction = "deposit.confirm"
+	AuditBankAccountCreate  AuditAction = "bank_account.create"
+	AuditWithdrawalCreate   AuditAction = "withdrawal.create"
+	AuditWithdrawalComplete AuditAction = "withdrawal.complete"
+	AuditWithdrawalFail     AuditAction = "withdrawal.fail"
+	AuditAPIKeyCreate       AuditAction = "api_key.create"
+	AuditAPIKeyRotate       AuditAction = "api_key.rotate"
+	AuditAPIKeyRevoke       AuditAction = "api_key.revoke"
+	AuditKYCSubmit          AuditAction = "kyc.submit"
+	AuditKYCApprove         AuditAction = "kyc.approve"
+	AuditKYCReject          AuditAction = "kyc.reject"
+	AuditMFAEnable          AuditAction = "mfa.enable"
+	AuditMFADisable         AuditAction = "mfa.disable"
+)
+
+// AuditEvent records who changed what, it is never updated or deleted.
+// The actor is null for anonymous requests, like the sign up, and the
+// snapshots are the JSON of the target before and after the operation.
+type AuditEvent struct {
+	ID        uuid.UUID
+	ActorID   pgtype.UUID
+	Action    AuditAction
+	TargetID  uuid.UUID
+	Before    []byte
+	After     []byte
+	RequestID string
+	SourceIP  string
+	CreatedAt pgtype.Timestamp
+}
+
+// AuditFilter narrows the audit events searched, null fields match any
+// event. The time range includes From and excludes To.
+type AuditFilter struct {
+	Actor  pgtype.UUID
+	Target pgtype.UUID
+	From   pgtype.Timestamp
+	To     pgtype.Timestamp
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeysRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeysRepository(db *pgxpool.Pool) *APIKeysRepository {
+	return &APIKeysRepository{
+		db,
+	}
+}
+
+func scanAPIKey(row pgx.Row) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+
+	return key, err
+}
+
+const createAPIKey = `
+	INSERT INTO api_keys (
+		"user_id",
+		"name",
+		"prefix",
+		"key_hash",
+		"scopes"
+	) VALUES ($1, $2, $3, $4, $5)
+	RETURNING "id";
+`
+
+func (r *APIKeysRepository) Create(
+	ctx context.Context,
+	key models.APIKey,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := r.db.QueryRow(
+		ctx,
+		createAPIKey,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.Scopes,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findAPIKeyByID = "SELECT * FROM api_keys WHERE id = $1"
+
+func (r *APIKeysRepository) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.APIKey, error) {
+	row := r.db.QueryRow(ctx, findAPIKeyByID, id)
+	return scanAPIKey(row)
+}
+
+const findAPIKeyByPrefix = "SELECT * FROM api_keys WHERE prefix = $1"
+
+func (r *APIKeysRepository) FindByPrefix(
+	ctx context.Context,
+	prefix string,
+) (models.APIKey, error) {
+	row := r.db.QueryRow(ctx, findAPIKeyByPrefix, prefix)
+	return scanAPIKey(row)
+}
+
+const findAPIKeysByUser = `
+	SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at
+`
+
+func (r *APIKeysRepository) FindByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.APIKey, error) {
+	rows, err := r.db.Query(ctx, findAPIKeysByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+const revokeAPIKey = `
+	UPDATE api_keys
+	SET revoked_at = NOW(), updated_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL;
+`
+
+// Revoke returns false if the key was already revoked.
+func (r *APIKeysRepository) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, revokeAPIKey, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const revokeAPIKeysByUser = `
+	UPDATE api_keys
+	SET revoked_at = NOW(), updated_at = NOW()
+	WHERE user_id = $1 AND revoked_at IS NULL;
+`
+
+func (r *APIKeysRepository) RevokeByUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, revokeAPIKeysByUser, userID)
+	return err
+}
+
+const touchAPIKey = "UPDATE api_keys SET last_used_at = NOW() WHERE id = $1"
+
+func (r *APIKeysRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.Exec(ctx, touchAPIKey, id)
+	return err
+}
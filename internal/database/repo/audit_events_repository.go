@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditEventsRepository only inserts and reads, the table
+// rejects updates and deletes of the events.
+type AuditEventsRepository struct {
+	db       *pgxpool.Pool
+	pageSize int
+}
+
+func NewAuditEventsRepository(db *pgxpool.Pool, pageSize int) *AuditEventsRepository {
+	return &AuditEventsRepository{
+		db,
+		pageSize,
+	}
+}
+
+func scanAuditEvent(row pgx.Row) (models.AuditEvent, error) {
+	var event models.AuditEvent
+	err := row.Scan(
+		&event.ID,
+		&event.ActorID,
+		&event.Action,
+		&event.TargetID,
+		&event.Before,
+		&event.After,
+		&event.RequestID,
+		&event.SourceIP,
+		&event.CreatedAt,
+	)
+
+	return event, err
+}
+
+const createAuditEvent = `
+	INSERT INTO audit_events (
+		"actor_id",
+		"action",
+		"target_id",
+		"before",
+		"after",
+		"request_id",
+		"source_ip"
+	) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING "id";
+`
+
+func (r *AuditEventsRepository) Create(
+	ctx context.Context,
+	event models.AuditEvent,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := r.db.QueryRow(
+		ctx,
+		createAuditEvent,
+		event.ActorID,
+		event.Action,
+		event.TargetID,
+		event.Before,
+		event.After,
+		event.RequestID,
+		event.SourceIP,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findAuditEvents = `
+	SELECT * FROM audit_events
+	WHERE ($1::uuid IS NULL OR actor_id = $1)
+		AND ($2::uuid IS NULL OR target_id = $2)
+		AND ($3::timestamp IS NULL OR created_at >= $3)
+		AND ($4::timestamp IS NULL OR created_at < $4)
+	ORDER BY created_at DESC
+	LIMIT $5 OFFSET $6;
+`
+
+func (r *AuditEventsRepository) Find(
+	ctx context.Context,
+	filter models.AuditFilter,
+	page int,
+) ([]models.AuditEvent, error) {
+	rows, err := r.db.Query(
+		ctx,
+		findAuditEvents,
+		filter.Actor,
+		filter.Target,
+		filter.From,
+		filter.To,
+		r.pageSize,
+		(page-1)*r.pageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]models.AuditEvent, 0, r.pageSize)
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BankAccountsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBankAccountsRepository(db *pgxpool.Pool) *BankAccountsRepository {
+	return &BankAccountsRepository{
+		db,
+	}
+}
+
+func scanBankAccount(row pgx.Row) (models.BankAccount, error) {
+	var account models.BankAccount
+	err := row.Scan(
+		&account.ID,
+		&account.UserID,
+		&account.BankCode,
+		&account.Branch,
+		&account.AccountNumber,
+		&account.AccountType,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+
+	return account, err
+}
+
+const createBankAccount = `
+	INSERT INTO bank_accounts (
+		"user_id",
+		"bank_code",
+		"branch",
+		"account_number",
+		"account_type"
+	) VALUES ($1, $2, $3, $4, $5)
+	RETURNING "id";
+`
+
+func (r *BankAccountsRepository) Create(
+	ctx context.Context,
+	account models.BankAccount,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := r.db.QueryRow(
+		ctx,
+		createBankAccount,
+		account.UserID,
+		account.BankCode,
+		account.Branch,
+		account.AccountNumber,
+		account.AccountType,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findBankAccountByID = "SELECT * FROM bank_accounts WHERE id = $1"
+
+func (r *BankAccountsRepository) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.BankAccount, error) {
+	row := r.db.QueryRow(ctx, findBankAccountByID, id)
+	return scanBankAccount(row)
+}
+
+const findBankAccountsByUser = `
+	SELECT * FROM bank_accounts WHERE user_id = $1 ORDER BY created_at
+`
+
+func (r *BankAccountsRepository) FindByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.BankAccount, error) {
+	rows, err := r.db.Query(ctx, findBankAccountsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []models.BankAccount
+	for rows.Next() {
+		account, err := scanBankAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DepositsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDepositsRepository(db *pgxpool.Pool) *DepositsRepository {
+	return &DepositsRepository{
+		db,
+	}
+}
+
+func scanDeposit(row pgx.Row) (models.Deposit, error) {
+	var deposit models.Deposit
+	err := row.Scan(
+		&deposit.ID,
+		&deposit.UserID,
+		&deposit.Amount,
+		&deposit.Currency,
+		&deposit.Source,
+		&deposit.Status,
+		&deposit.Reference,
+		&deposit.ConfirmedAt,
+		&deposit.CreatedAt,
+		&deposit.UpdatedAt,
+	)
+
+	return deposit, err
+}
+
+const createDeposit = `
+	INSERT INTO deposits (
+		"user_id",
+		"amount",
+		"currency",
+		"source",
+		"status",
+		"reference",
+		"confirmed_at"
+	) VALUES (
+		$1, $2, $3, $4, $5, $6,
+		CASE WHEN $5 = 'CONFIRMED'::"DepositStatus" THEN NOW() END
+	)
+	RETURNING "id";
+`
+
+func (r *DepositsRepository) Create(
+	ctx context.Context,
+	deposit models.Deposit,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		createDeposit,
+		deposit.UserID,
+		deposit.Amount,
+		deposit.Currency,
+		deposit.Source,
+		deposit.Status,
+		deposit.Reference,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findDepositByID = "SELECT * FROM deposits WHERE id = $1"
+
+func (r *DepositsRepository) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.Deposit, error) {
+	row := conn(ctx, r.db).QueryRow(ctx, findDepositByID, id)
+	return scanDeposit(row)
+}
+
+const settleDeposit = `
+	UPDATE deposits SET
+		status = $2,
+		confirmed_at = CASE WHEN $2 = 'CONFIRMED'::"DepositStatus" THEN NOW() END,
+		updated_at = NOW()
+	WHERE id = $1 AND status = 'PENDING';
+`
+
+// Settle moves a pending deposit to its final status,
+// returns false if the deposit was not pending.
+func (r *DepositsRepository) Settle(
+	ctx context.Context,
+	deposit models.Deposit,
+) (bool, error) {
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		settleDeposit,
+		deposit.ID,
+		deposit.Status,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const hasPendingDeposits = `
+	SELECT EXISTS (
+		SELECT 1 FROM deposits WHERE user_id = $1 AND status = 'PENDING'
+	);
+`
+
+// HasPending reports if the user has deposits not confirmed yet.
+func (r *DepositsRepository) HasPending(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var pending bool
+	err := conn(ctx, r.db).QueryRow(ctx, hasPendingDeposits, userID).Scan(&pending)
+	return pending, err
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FraudReviewsRepository struct {
+	db       *pgxpool.Pool
+	pageSize int
+}
+
+func NewFraudReviewsRepository(db *pgxpool.Pool, pageSize int) *FraudReviewsRepository {
+	return &FraudReviewsRepository{
+		db,
+		pageSize,
+	}
+}
+
+func scanFraudReview(row pgx.Row) (models.FraudReview, error) {
+	var review models.FraudReview
+	err := row.Scan(
+		&review.ID,
+		&review.Payer,
+		&review.Payee,
+		&review.Amount,
+		&review.Score,
+		&review.Reasons,
+		&review.Status,
+		&review.TransactionID,
+		&review.ResolutionNote,
+		&review.ResolvedAt,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Currency,
+		&review.PayeeCurrency,
+	)
+
+	return review, err
+}
+
+const createFraudReview = `
+	INSERT INTO fraud_reviews (
+		"payer",
+		"payee",
+		"amount",
+		"score",
+		"reasons",
+		"currency",
+		"payee_currency"
+	) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING "id";
+`
+
+func (r *FraudReviewsRepository) Create(
+	ctx context.Context,
+	review models.FraudReview,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		createFraudReview,
+		review.Payer,
+		review.Payee,
+		review.Amount,
+		review.Score,
+		review.Reasons,
+		review.Currency,
+		review.PayeeCurrency,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findFraudReviewByID = "SELECT * FROM fraud_reviews WHERE id = $1"
+
+func (r *FraudReviewsRepository) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.FraudReview, error) {
+	row := conn(ctx, r.db).QueryRow(ctx, findFraudReviewByID, id)
+	return scanFraudReview(row)
+}
+
+const findFraudReviewsByStatus = `
+	SELECT * FROM fraud_reviews
+	WHERE status = $1
+	ORDER BY created_at
+	LIMIT $2 OFFSET $3;
+`
+
+func (r *FraudReviewsRepository) FindByStatus(
+	ctx context.Context,
+	status models.ReviewStatus,
+	page int,
+) ([]models.FraudReview, error) {
+	rows, err := conn(ctx, r.db).Query(
+		ctx,
+		findFraudReviewsByStatus,
+		status,
+		r.pageSize,
+		(page-1)*r.pageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make([]models.FraudReview, 0, r.pageSize)
+	for rows.Next() {
+		review, err := scanFraudReview(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+const resolveFraudReview = `
+	UPDATE fraud_reviews SET
+		status = $2,
+		transaction_id = $3,
+		resolution_note = $4,
+		resolved_at = NOW(),
+		updated_at = NOW()
+	WHERE id = $1 AND status = 'PENDING';
+`
+
+// Resolve moves a pending review to the status of the given one,
+// reporting false if it was already resolved meanwhile.
+func (r *FraudReviewsRepository) Resolve(
+	ctx context.Context,
+	review models.FraudReview,
+) (bool, error) {
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		resolveFraudReview,
+		review.ID,
+		review.Status,
+		review.TransactionID,
+		review.ResolutionNote,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const linkFraudReview = `
+	UPDATE fraud_reviews SET
+		transaction_id = $2,
+		updated_at = NOW()
+	WHERE id = $1 AND status = 'APPROVED';
+`
+
+// Link sets the transaction made after the approval of the review.
+func (r *FraudReviewsRepository) Link(
+	ctx context.Context,
+	id, transactionID uuid.UUID,
+) error {
+	_, err := conn(ctx, r.db).Exec(ctx, linkFraudReview, id, transactionID)
+	return err
+}
+
+const hasPendingReviews = `
+	SELECT EXISTS (
+		SELECT 1 FROM fraud_reviews
+		WHERE (payer = $1 OR payee = $1) AND status = 'PENDING'
+	);
+`
+
+// HasPending reports if the user is the payer or the payee of a
+// transfer held for review.
+func (r *FraudReviewsRepository) HasPending(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var pending bool
+	err := conn(ctx, r.db).QueryRow(ctx, hasPendingReviews, userID).Scan(&pending)
+	return pending, err
+}
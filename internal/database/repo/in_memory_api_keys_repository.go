@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryAPIKeysRepository struct {
+	Keys []models.APIKey
+}
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+func (r *InMemoryAPIKeysRepository) Create(
+	_ context.Context,
+	key models.APIKey,
+) (uuid.UUID, error) {
+	for _, k := range r.Keys {
+		if k.Prefix == key.Prefix {
+			return uuid.Nil, ErrInsertionOnUnique
+		}
+	}
+
+	key.ID = uuid.New()
+	key.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	key.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Keys = append(r.Keys, key)
+	return key.ID, nil
+}
+
+func (r *InMemoryAPIKeysRepository) FindByID(
+	_ context.Context,
+	id uuid.UUID,
+) (models.APIKey, error) {
+	for _, key := range r.Keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+
+	return models.APIKey{}, ErrAPIKeyNotFound
+}
+
+func (r *InMemoryAPIKeysRepository) FindByPrefix(
+	_ context.Context,
+	prefix string,
+) (models.APIKey, error) {
+	for _, key := range r.Keys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+
+	return models.APIKey{}, ErrAPIKeyNotFound
+}
+
+func (r *InMemoryAPIKeysRepository) FindByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	for _, key := range r.Keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (r *InMemoryAPIKeysRepository) Revoke(_ context.Context, id uuid.UUID) (bool, error) {
+	for i, key := range r.Keys {
+		if key.ID == id && !key.RevokedAt.Valid {
+			r.Keys[i].RevokedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Keys[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryAPIKeysRepository) RevokeByUser(_ context.Context, userID uuid.UUID) error {
+	for i, key := range r.Keys {
+		if key.UserID == userID && !key.RevokedAt.Valid {
+			r.Keys[i].RevokedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Keys[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+		}
+	}
+
+	return nil
+}
+
+func (r *InMemoryAPIKeysRepository) Touch(_ context.Context, id uuid.UUID) error {
+	for i, key := range r.Keys {
+		if key.ID == id {
+			r.Keys[i].LastUsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return nil
+		}
+	}
+
+	return ErrAPIKeyNotFound
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Page size of Find
+const itemsPerPage = 20
+
+type InMemoryAuditEventsRepository struct {
+	Events []models.AuditEvent
+}
+
+func (r *InMemoryAuditEventsRepository) Create(
+	_ context.Context,
+	event models.AuditEvent,
+) (uuid.UUID, error) {
+	event.ID = uuid.New()
+	event.CreatedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+	r.Events = append(r.Events, event)
+	return event.ID, nil
+}
+
+func (r *InMemoryAuditEventsRepository) Find(
+	_ context.Context,
+	filter models.AuditFilter,
+	page int,
+) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	for i := len(r.Events) - 1; i >= 0; i-- {
+		event := r.Events[i]
+
+		if filter.Actor.Valid && event.ActorID != filter.Actor {
+			continue
+		}
+
+		if filter.Target.Valid && event.TargetID != filter.Target.Bytes {
+			continue
+		}
+
+		if filter.From.Valid && event.CreatedAt.Time.Before(filter.From.Time) {
+			continue
+		}
+
+		if filter.To.Valid && !event.CreatedAt.Time.Before(filter.To.Time) {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	start := (page - 1) * itemsPerPage
+	if start >= len(events) {
+		return []models.AuditEvent{}, nil
+	}
+
+	end := min(page*itemsPerPage, len(events))
+	return events[start:end], nil
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryBankAccountsRepository struct {
+	Accounts []models.BankAccount
+}
+
+var ErrBankAccountNotFound = errors.New("bank account not found")
+
+func (r *InMemoryBankAccountsRepository) Create(
+	_ context.Context,
+	account models.BankAccount,
+) (uuid.UUID, error) {
+	for _, a := range r.Accounts {
+		if a.UserID == account.UserID &&
+			a.BankCode == account.BankCode &&
+			a.Branch == account.Branch &&
+			a.AccountNumber == account.AccountNumber {
+			return uuid.Nil, ErrInsertionOnUnique
+		}
+	}
+
+	account.ID = uuid.New()
+	account.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	account.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Accounts = append(r.Accounts, account)
+	return account.ID, nil
+}
+
+func (r *InMemoryBankAccountsRepository) FindByID(
+	_ context.Context,
+	id uuid.UUID,
+) (models.BankAccount, error) {
+	for _, account := range r.Accounts {
+		if account.ID == id {
+			return account, nil
+		}
+	}
+
+	return models.BankAccount{}, ErrBankAccountNotFound
+}
+
+func (r *InMemoryBankAccountsRepository) FindByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.BankAccount, error) {
+	var accounts []models.BankAccount
+	for _, account := range r.Accounts {
+		if account.UserID == userID {
+			accounts = append(accounts, account)
+		}
+	}
+
+	return accounts, nil
+}
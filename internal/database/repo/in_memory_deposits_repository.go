@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryDepositsRepository struct {
+	Deposits []models.Deposit
+}
+
+var ErrDepositNotFound = errors.New("deposit not found")
+
+func (r *InMemoryDepositsRepository) Create(
+	_ context.Context,
+	deposit models.Deposit,
+) (uuid.UUID, error) {
+	deposit.ID = uuid.New()
+	if deposit.Status == models.DepositConfirmed {
+		deposit.ConfirmedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	}
+	deposit.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	deposit.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Deposits = append(r.Deposits, deposit)
+	return deposit.ID, nil
+}
+
+func (r *InMemoryDepositsRepository) FindByID(
+	_ context.Context,
+	id uuid.UUID,
+) (models.Deposit, error) {
+	for _, deposit := range r.Deposits {
+		if deposit.ID == id {
+			return deposit, nil
+		}
+	}
+
+	return models.Deposit{}, ErrDepositNotFound
+}
+
+func (r *InMemoryDepositsRepository) Settle(
+	_ context.Context,
+	deposit models.Deposit,
+) (bool, error) {
+	for i := range r.Deposits {
+		if r.Deposits[i].ID != deposit.ID {
+			continue
+		}
+
+		if r.Deposits[i].Status != models.DepositPending {
+			return false, nil
+		}
+
+		r.Deposits[i].Status = deposit.Status
+		r.Deposits[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+		if deposit.Status == models.DepositConfirmed {
+			r.Deposits[i].ConfirmedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		}
+		return true, nil
+	}
+
+	return false, ErrDepositNotFound
+}
+
+func (r *InMemoryDepositsRepository) HasPending(_ context.Context, userID uuid.UUID) (bool, error) {
+	for _, deposit := range r.Deposits {
+		if deposit.UserID == userID && deposit.Status == models.DepositPending {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
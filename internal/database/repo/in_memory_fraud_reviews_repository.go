@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryFraudReviewsRepository struct {
+	Reviews []models.FraudReview
+}
+
+var ErrFraudReviewNotFound = errors.New("fraud review not found")
+
+func (r *InMemoryFraudReviewsRepository) Create(
+	_ context.Context,
+	review models.FraudReview,
+) (uuid.UUID, error) {
+	review.ID = uuid.New()
+	review.Status = models.ReviewPending
+	review.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	review.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Reviews = append(r.Reviews, review)
+	return review.ID, nil
+}
+
+func (r *InMemoryFraudReviewsRepository) FindByID(
+	_ context.Context,
+	id uuid.UUID,
+) (models.FraudReview, error) {
+	for _, review := range r.Reviews {
+		if review.ID == id {
+			return review, nil
+		}
+	}
+
+	return models.FraudReview{}, ErrFraudReviewNotFound
+}
+
+func (r *InMemoryFraudReviewsRepository) FindByStatus(
+	_ context.Context,
+	status models.ReviewStatus,
+	page int,
+) ([]models.FraudReview, error) {
+	var reviews []models.FraudReview
+	for _, review := range r.Reviews {
+		if review.Status == status {
+			reviews = append(reviews, review)
+		}
+	}
+
+	start := (page - 1) * 20
+	if start >= len(reviews) {
+		return []models.FraudReview{}, nil
+	}
+
+	end := page * 20
+	if end > len(reviews) {
+		end = len(reviews)
+	}
+
+	return reviews[start:end], nil
+}
+
+func (r *InMemoryFraudReviewsRepository) Resolve(
+	_ context.Context,
+	review models.FraudReview,
+) (bool, error) {
+	for i := range r.Reviews {
+		if r.Reviews[i].ID == review.ID {
+			if r.Reviews[i].Status != models.ReviewPending {
+				return false, nil
+			}
+
+			r.Reviews[i].Status = review.Status
+			r.Reviews[i].TransactionID = review.TransactionID
+			r.Reviews[i].ResolutionNote = review.ResolutionNote
+			r.Reviews[i].ResolvedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Reviews[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryFraudReviewsRepository) Link(
+	_ context.Context,
+	id, transactionID uuid.UUID,
+) error {
+	for i := range r.Reviews {
+		if r.Reviews[i].ID == id && r.Reviews[i].Status == models.ReviewApproved {
+			r.Reviews[i].TransactionID = pgtype.UUID{Bytes: transactionID, Valid: true}
+			r.Reviews[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (r *InMemoryFraudReviewsRepository) HasPending(_ context.Context, userID uuid.UUID) (bool, error) {
+	for _, review := range r.Reviews {
+		if (review.Payer == userID || review.Payee == userID) &&
+			review.Status == models.ReviewPending {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryKYCSubmissionsRepository struct {
+	Submissions []models.KYCSubmission
+}
+
+var ErrKYCSubmissionNotFound = errors.New("kyc submission not found")
+
+func (r *InMemoryKYCSubmissionsRepository) Create(
+	_ context.Context,
+	submission models.KYCSubmission,
+) (uuid.UUID, error) {
+	submission.ID = uuid.New()
+	submission.Status = models.ReviewPending
+	submission.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	submission.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Submissions = append(r.Submissions, submission)
+	return submission.ID, nil
+}
+
+func (r *InMemoryKYCSubmissionsRepository) FindByID(
+	_ context.Context,
+	id uuid.UUID,
+) (models.KYCSubmission, error) {
+	for _, submission := range r.Submissions {
+		if submission.ID == id {
+			return submission, nil
+		}
+	}
+
+	return models.KYCSubmission{}, ErrKYCSubmissionNotFound
+}
+
+func (r *InMemoryKYCSubmissionsRepository) FindLatestByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) (models.KYCSubmission, error) {
+	for i := len(r.Submissions) - 1; i >= 0; i-- {
+		if r.Submissions[i].UserID == userID {
+			return r.Submissions[i], nil
+		}
+	}
+
+	return models.KYCSubmission{}, ErrKYCSubmissionNotFound
+}
+
+func (r *InMemoryKYCSubmissionsRepository) FindByStatus(
+	_ context.Context,
+	status models.ReviewStatus,
+	page int,
+) ([]models.KYCSubmission, error) {
+	var submissions []models.KYCSubmission
+	for _, submission := range r.Submissions {
+		if submission.Status == status {
+			submissions = append(submissions, submission)
+		}
+	}
+
+	start := (page - 1) * 20
+	if start >= len(submissions) {
+		return []models.KYCSubmission{}, nil
+	}
+
+	end := page * 20
+	if end > len(submissions) {
+		end = len(submissions)
+	}
+
+	return submissions[start:end], nil
+}
+
+func (r *InMemoryKYCSubmissionsRepository) HasPending(_ context.Context, userID uuid.UUID) (bool, error) {
+	for _, submission := range r.Submissions {
+		if submission.UserID == userID && submission.Status == models.ReviewPending {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryKYCSubmissionsRepository) Resolve(
+	_ context.Context,
+	submission models.KYCSubmission,
+) (bool, error) {
+	for i := range r.Submissions {
+		if r.Submissions[i].ID == submission.ID && r.Submissions[i].Status == models.ReviewPending {
+			r.Submissions[i].Status = submission.Status
+			r.Submissions[i].ReviewedBy = submission.ReviewedBy
+			r.Submissions[i].ReviewNote = submission.ReviewNote
+			r.Submissions[i].ReviewedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Submissions[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+// InMemoryReconciliationRepository rebuilds the balances from the
+// ledger of the statements and compares them with the ones of the
+// users and wallets repositories.
+type InMemoryReconciliationRepository struct {
+	Statements *InMemoryStatementsRepository
+	Users      *InMemoryUserRepository
+	Wallets    *InMemoryWalletsRepository
+}
+
+type balanceKey struct {
+	userID   uuid.UUID
+	currency string
+}
+
+func (r *InMemoryReconciliationRepository) find(
+	all bool,
+	userID uuid.UUID,
+) []models.Discrepancy {
+	expected := make(map[balanceKey]float64)
+	for _, entry := range r.Statements.Ledger {
+		if all || entry.UserID == userID {
+			expected[balanceKey{entry.UserID, entry.Currency}] += entry.Amount
+		}
+	}
+
+	actual := make(map[balanceKey]float64)
+	for _, user := range r.Users.Users {
+		if all || user.ID == userID {
+			actual[balanceKey{user.ID, "BRL"}] = user.Balance
+		}
+	}
+	for _, wallet := range r.Wallets.Wallets {
+		if all || wallet.UserID == userID {
+			actual[balanceKey{wallet.UserID, wallet.Currency}] = wallet.Balance
+		}
+	}
+
+	keys := make(map[balanceKey]bool)
+	for key := range expected {
+		keys[key] = true
+	}
+	for key := range actual {
+		keys[key] = true
+	}
+
+	// The float sums are compared in minor units to ignore their rounding
+	var discrepancies []models.Discrepancy
+	for key := range keys {
+		e := money.NewFromFloat(expected[key], key.currency)
+		a := money.NewFromFloat(actual[key], key.currency)
+		if e.Amount() != a.Amount() {
+			discrepancies = append(discrepancies, models.Discrepancy{
+				UserID:   key.userID,
+				Currency: key.currency,
+				Expected: e.AsMajorUnits(),
+				Actual:   a.AsMajorUnits(),
+			})
+		}
+	}
+
+	slices.SortFunc(discrepancies, func(a, b models.Discrepancy) int {
+		if c := bytes.Compare(a.UserID[:], b.UserID[:]); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Currency, b.Currency)
+	})
+
+	return discrepancies
+}
+
+func (r *InMemoryReconciliationRepository) Discrepancies(
+	_ context.Context,
+	each func(models.Discrepancy) error,
+) error {
+	for _, discrepancy := range r.find(true, uuid.Nil) {
+		if err := each(discrepancy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *InMemoryReconciliationRepository) FindByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.Discrepancy, error) {
+	return r.find(false, userID), nil
+}
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemorySessionsRepository struct {
+	Sessions []models.Session
+}
+
+var ErrSessionNotFound = errors.New("session not found")
+
+func (r *InMemorySessionsRepository) Create(_ context.Context, session models.Session) error {
+	for _, s := range r.Sessions {
+		if s.ID == session.ID {
+			return ErrInsertionOnUnique
+		}
+	}
+
+	session.LastUsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	session.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	session.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Sessions = append(r.Sessions, session)
+	return nil
+}
+
+func (r *InMemorySessionsRepository) FindByID(
+	_ context.Context,
+	id uuid.UUID,
+) (models.Session, error) {
+	for _, session := range r.Sessions {
+		if session.ID == id {
+			return session, nil
+		}
+	}
+
+	return models.Session{}, ErrSessionNotFound
+}
+
+func (r *InMemorySessionsRepository) FindActiveByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range r.Sessions {
+		if session.UserID == userID &&
+			!session.RevokedAt.Valid &&
+			session.ExpiresAt.Time.After(time.Now()) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *InMemorySessionsRepository) Rotate(
+	_ context.Context,
+	session models.Session,
+	previousHash string,
+) (bool, error) {
+	for i, s := range r.Sessions {
+		if s.ID == session.ID && s.RefreshTokenHash == previousHash && !s.RevokedAt.Valid {
+			r.Sessions[i].RefreshTokenHash = session.RefreshTokenHash
+			r.Sessions[i].UserAgent = session.UserAgent
+			r.Sessions[i].IP = session.IP
+			r.Sessions[i].ExpiresAt = session.ExpiresAt
+			r.Sessions[i].LastUsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Sessions[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemorySessionsRepository) Revoke(_ context.Context, id uuid.UUID) (bool, error) {
+	for i, session := range r.Sessions {
+		if session.ID == id && !session.RevokedAt.Valid {
+			r.Sessions[i].RevokedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Sessions[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemorySessionsRepository) RevokeByUser(
+	_ context.Context,
+	userID, keep uuid.UUID,
+) (int, error) {
+	revoked := 0
+	for i, session := range r.Sessions {
+		if session.UserID == userID && session.ID != keep && !session.RevokedAt.Valid {
+			r.Sessions[i].RevokedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Sessions[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			revoked++
+		}
+	}
+
+	return revoked, nil
+}
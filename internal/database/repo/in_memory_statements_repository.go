@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+// InMemoryStatementsRepository keeps the entries of every user in the
+// ledger, they are not derived from the other repositories.
+type InMemoryStatementsRepository struct {
+	Ledger []InMemoryLedgerEntry
+}
+
+type InMemoryLedgerEntry struct {
+	UserID   uuid.UUID
+	Currency string
+	models.StatementEntry
+}
+
+func (r *InMemoryStatementsRepository) sorted(
+	userID uuid.UUID,
+	currency string,
+) []models.StatementEntry {
+	var entries []models.StatementEntry
+	for _, entry := range r.Ledger {
+		if entry.UserID == userID && entry.Currency == currency {
+			entries = append(entries, entry.StatementEntry)
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b models.StatementEntry) int {
+		if c := a.CreatedAt.Time.Compare(b.CreatedAt.Time); c != 0 {
+			return c
+		}
+
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	return entries
+}
+
+func (r *InMemoryStatementsRepository) BalanceAt(
+	_ context.Context,
+	userID uuid.UUID,
+	currency string,
+	at time.Time,
+) (float64, error) {
+	balance := 0.0
+	for _, entry := range r.sorted(userID, currency) {
+		if entry.CreatedAt.Time.Before(at) {
+			balance += entry.Amount
+		}
+	}
+
+	return balance, nil
+}
+
+func (r *InMemoryStatementsRepository) Entries(
+	_ context.Context,
+	userID uuid.UUID,
+	currency string,
+	from, to time.Time,
+	each func(models.StatementEntry) error,
+) error {
+	for _, entry := range r.sorted(userID, currency) {
+		if entry.CreatedAt.Time.Before(from) || !entry.CreatedAt.Time.Before(to) {
+			continue
+		}
+
+		if err := each(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryTOTPFactorsRepository struct {
+	Factors       []models.TOTPFactor
+	RecoveryCodes []models.RecoveryCode
+}
+
+var ErrTOTPFactorNotFound = errors.New("totp factor not found")
+
+func (r *InMemoryTOTPFactorsRepository) Save(_ context.Context, factor models.TOTPFactor) (bool, error) {
+	for i, f := range r.Factors {
+		if f.UserID == factor.UserID {
+			if f.ConfirmedAt.Valid {
+				return false, nil
+			}
+
+			r.Factors[i].Secret = factor.Secret
+			r.Factors[i].LastUsedStep = 0
+			r.Factors[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	factor.ConfirmedAt = pgtype.Timestamp{}
+	factor.LastUsedStep = 0
+	factor.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	factor.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Factors = append(r.Factors, factor)
+	return true, nil
+}
+
+func (r *InMemoryTOTPFactorsRepository) FindByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) (models.TOTPFactor, error) {
+	for _, factor := range r.Factors {
+		if factor.UserID == userID {
+			return factor, nil
+		}
+	}
+
+	return models.TOTPFactor{}, ErrTOTPFactorNotFound
+}
+
+func (r *InMemoryTOTPFactorsRepository) UseStep(_ context.Context, userID uuid.UUID, step int64) (bool, error) {
+	for i, factor := range r.Factors {
+		if factor.UserID == userID && factor.LastUsedStep < step {
+			r.Factors[i].LastUsedStep = step
+			if !factor.ConfirmedAt.Valid {
+				r.Factors[i].ConfirmedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			}
+			r.Factors[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryTOTPFactorsRepository) RecordFailure(
+	_ context.Context,
+	userID uuid.UUID,
+	maxAttempts int,
+	lockout time.Duration,
+) (bool, error) {
+	for i, factor := range r.Factors {
+		if factor.UserID != userID {
+			continue
+		}
+
+		r.Factors[i].FailedAttempts++
+		if r.Factors[i].FailedAttempts < maxAttempts {
+			return false, nil
+		}
+
+		r.Factors[i].FailedAttempts = 0
+		r.Factors[i].LockedUntil = pgtype.Timestamp{Time: time.Now().Add(lockout), Valid: true}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryTOTPFactorsRepository) ResetFailures(_ context.Context, userID uuid.UUID) error {
+	for i, factor := range r.Factors {
+		if factor.UserID == userID {
+			r.Factors[i].FailedAttempts = 0
+		}
+	}
+
+	return nil
+}
+
+func (r *InMemoryTOTPFactorsRepository) Delete(_ context.Context, userID uuid.UUID) error {
+	r.deleteRecoveryCodes(userID)
+
+	for i, factor := range r.Factors {
+		if factor.UserID == userID {
+			r.Factors = append(r.Factors[:i], r.Factors[i+1:]...)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (r *InMemoryTOTPFactorsRepository) deleteRecoveryCodes(userID uuid.UUID) {
+	codes := r.RecoveryCodes[:0]
+	for _, code := range r.RecoveryCodes {
+		if code.UserID != userID {
+			codes = append(codes, code)
+		}
+	}
+
+	r.RecoveryCodes = codes
+}
+
+func (r *InMemoryTOTPFactorsRepository) ReplaceRecoveryCodes(
+	_ context.Context,
+	userID uuid.UUID,
+	hashes []string,
+) error {
+	r.deleteRecoveryCodes(userID)
+
+	for _, hash := range hashes {
+		r.RecoveryCodes = append(r.RecoveryCodes, models.RecoveryCode{
+			ID:        uuid.New(),
+			UserID:    userID,
+			CodeHash:  hash,
+			CreatedAt: pgtype.Timestamp{Time: time.Now()},
+		})
+	}
+
+	return nil
+}
+
+func (r *InMemoryTOTPFactorsRepository) UseRecoveryCode(
+	_ context.Context,
+	userID uuid.UUID,
+	hash string,
+) (bool, error) {
+	for i, code := range r.RecoveryCodes {
+		if code.UserID == userID && code.CodeHash == hash && !code.UsedAt.Valid {
+			r.RecoveryCodes[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -1,7 +1,9 @@
 package repo
 
 import (
+	"bytes"
 	"context"
+	"slices"
 	"time"
 
 	"github.com/edulustosa/go-pay/internal/database/models"
@@ -24,3 +26,78 @@ func (r *InMemoryTransactionsRepository) Create(
 	r.Transaction = append(r.Transaction, transaction)
 	return transaction.ID, nil
 }
+
+func (r *InMemoryTransactionsRepository) CountByPayerSince(
+	_ context.Context,
+	payer uuid.UUID,
+	since time.Time,
+) (int, error) {
+	count := 0
+	for _, transaction := range r.Transaction {
+		if transaction.Payer == payer && !transaction.CreatedAt.Time.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *InMemoryTransactionsRepository) CountBetween(
+	_ context.Context,
+	payer, payee uuid.UUID,
+	since time.Time,
+) (int, error) {
+	count := 0
+	for _, transaction := range r.Transaction {
+		if transaction.Payer == payer &&
+			transaction.Payee == payee &&
+			!transaction.CreatedAt.Time.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *InMemoryTransactionsRepository) FindByUser(
+	_ context.Context,
+	userID uuid.UUID,
+	cursor *models.Cursor,
+	limit int,
+) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	for _, transaction := range r.Transaction {
+		if transaction.Payer == userID || transaction.Payee == userID {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	position := func(t models.Transaction) models.Cursor {
+		return models.Cursor{CreatedAt: t.CreatedAt.Time, ID: t.ID}
+	}
+
+	slices.SortStableFunc(transactions, func(a, b models.Transaction) int {
+		pa, pb := position(a), position(b)
+		if c := pb.CreatedAt.Compare(pa.CreatedAt); c != 0 {
+			return c
+		}
+
+		return bytes.Compare(pb.ID[:], pa.ID[:])
+	})
+
+	return inMemoryKeyset(transactions, cursor, limit, true, position), nil
+}
+
+func (r *InMemoryTransactionsRepository) CountByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) (int, error) {
+	count := 0
+	for _, transaction := range r.Transaction {
+		if transaction.Payer == userID || transaction.Payee == userID {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -1,10 +1,15 @@
 package repo
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/Rhymond/go-money"
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -24,7 +29,7 @@ func (r *InMemoryUserRepository) FindByDocument(
 	document string,
 ) (models.User, error) {
 	for _, user := range r.Users {
-		if user.Document == document {
+		if user.Document == document && !user.DeletedAt.Valid {
 			return user, nil
 		}
 	}
@@ -37,7 +42,7 @@ func (r *InMemoryUserRepository) FindByID(
 	id uuid.UUID,
 ) (models.User, error) {
 	for _, user := range r.Users {
-		if user.ID == id {
+		if user.ID == id && !user.DeletedAt.Valid {
 			return user, nil
 		}
 	}
@@ -50,7 +55,7 @@ func (r *InMemoryUserRepository) FindByEmail(
 	email string,
 ) (models.User, error) {
 	for _, user := range r.Users {
-		if user.Email == email {
+		if strings.EqualFold(user.Email, email) && !user.DeletedAt.Valid {
 			return user, nil
 		}
 	}
@@ -76,38 +81,247 @@ func (r *InMemoryUserRepository) Create(
 	if user.Role == "" {
 		user.Role = models.RoleCommon
 	}
-	user.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	if user.DocumentType == "" {
+		user.DocumentType = models.DocumentCPF
+	}
+	if user.Status == "" {
+		user.Status = models.StatusActive
+	}
+	if user.KYCLevel == "" {
+		user.KYCLevel = models.KYCBasic
+	}
+	if !user.CreatedAt.Valid {
+		user.CreatedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+	}
 	user.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
 
 	r.Users = append(r.Users, user)
 	return user.ID, nil
 }
 
+func (r *InMemoryUserRepository) filter(filter models.UserFilter) []models.User {
+	prefix := strings.ToLower(filter.NamePrefix)
+
+	var users []models.User
+	for _, user := range r.Users {
+		if filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+
+		fullName := strings.ToLower(user.FirstName + " " + user.LastName)
+		if prefix != "" && !strings.HasPrefix(fullName, prefix) &&
+			!strings.HasPrefix(strings.ToLower(user.LastName), prefix) {
+			continue
+		}
+
+		if filter.Email != "" && !strings.EqualFold(user.Email, filter.Email) {
+			continue
+		}
+
+		if filter.Status == "" && user.DeletedAt.Valid ||
+			filter.Status != "" && user.Status != filter.Status {
+			continue
+		}
+
+		if filter.CreatedFrom.Valid && user.CreatedAt.Time.Before(filter.CreatedFrom.Time) {
+			continue
+		}
+
+		if filter.CreatedTo.Valid && !user.CreatedAt.Time.Before(filter.CreatedTo.Time) {
+			continue
+		}
+
+		users = append(users, user)
+	}
+
+	return users
+}
+
 func (r *InMemoryUserRepository) FindMany(
 	_ context.Context,
+	filter models.UserFilter,
 	page int,
+	pageSize int,
 ) ([]models.User, error) {
-	start := (page - 1) * 20
-	if start >= len(r.Users) {
+	users := r.filter(filter)
+	sortUsers(users, filter.Sort, filter.Descending)
+
+	start := (page - 1) * pageSize
+	if start >= len(users) {
 		return []models.User{}, nil
 	}
 
-	end := page * 20
-	if end > len(r.Users) {
-		end = len(r.Users)
+	end := min(page*pageSize, len(users))
+	return users[start:end], nil
+}
+
+func (r *InMemoryUserRepository) FindAfter(
+	_ context.Context,
+	filter models.UserFilter,
+	cursor *models.Cursor,
+	limit int,
+) ([]models.User, error) {
+	users := r.filter(filter)
+	sortUsers(users, models.UserSortCreatedAt, filter.Descending)
+
+	return inMemoryKeyset(users, cursor, limit, filter.Descending, func(u models.User) models.Cursor {
+		return models.Cursor{CreatedAt: u.CreatedAt.Time, ID: u.ID}
+	}), nil
+}
+
+func sortUsers(users []models.User, sort models.UserSort, descending bool) {
+	slices.SortStableFunc(users, func(a, b models.User) int {
+		var c int
+		switch sort {
+		case models.UserSortName:
+			c = cmp.Compare(a.FirstName+" "+a.LastName, b.FirstName+" "+b.LastName)
+		case models.UserSortEmail:
+			c = cmp.Compare(a.Email, b.Email)
+		default:
+			c = a.CreatedAt.Time.Compare(b.CreatedAt.Time)
+		}
+
+		if c == 0 {
+			c = bytes.Compare(a.ID[:], b.ID[:])
+		}
+
+		if descending {
+			return -c
+		}
+
+		return c
+	})
+}
+
+func (r *InMemoryUserRepository) Count(
+	_ context.Context,
+	filter models.UserFilter,
+) (int, error) {
+	return len(r.filter(filter)), nil
+}
+
+// The balances of the users are in BRL
+func (r *InMemoryUserRepository) AddBalance(
+	_ context.Context,
+	id uuid.UUID,
+	amount float64,
+) (float64, bool, error) {
+	for i, user := range r.Users {
+		if user.ID == id {
+			balance, ok := addMajorUnits(user.Balance, amount, money.BRL)
+			if !ok {
+				return 0, false, nil
+			}
+
+			r.Users[i].Balance = balance
+			return balance, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func (r *InMemoryUserRepository) UpdatePassword(
+	_ context.Context,
+	id uuid.UUID,
+	passwordHash string,
+) error {
+	for i, user := range r.Users {
+		if user.ID == id {
+			r.Users[i].PasswordHash = passwordHash
+			r.Users[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return nil
+		}
 	}
 
-	return r.Users[start:end], nil
+	return ErrUserNotFound
 }
 
-func (r *InMemoryUserRepository) UpdateBalance(
+func (r *InMemoryUserRepository) MarkEmailVerified(_ context.Context, id uuid.UUID, email string) error {
+	for i, user := range r.Users {
+		if user.ID == id {
+			if !user.EmailVerifiedAt.Valid && strings.EqualFold(user.Email, email) {
+				r.Users[i].EmailVerifiedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			}
+			return nil
+		}
+	}
+
+	return ErrUserNotFound
+}
+
+func (r *InMemoryUserRepository) UpdateProfile(_ context.Context, user models.User) error {
+	for i := range r.Users {
+		if r.Users[i].ID == user.ID && !r.Users[i].DeletedAt.Valid {
+			r.Users[i].FirstName = user.FirstName
+			r.Users[i].LastName = user.LastName
+			r.Users[i].Email = user.Email
+			r.Users[i].EmailVerifiedAt = user.EmailVerifiedAt
+			r.Users[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return nil
+		}
+	}
+
+	return ErrUserNotFound
+}
+
+func (r *InMemoryUserRepository) Close(_ context.Context, id uuid.UUID) (bool, error) {
+	for i, user := range r.Users {
+		if user.ID == id && user.Balance == 0 && user.Status == models.StatusActive {
+			r.Users[i].Status = models.StatusClosed
+			r.Users[i].DeletedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Users[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryUserRepository) Freeze(
+	_ context.Context,
+	id uuid.UUID,
+	reason string,
+	frozenBy uuid.UUID,
+) (bool, error) {
+	for i, user := range r.Users {
+		if user.ID == id && user.Status == models.StatusActive {
+			r.Users[i].Status = models.StatusFrozen
+			r.Users[i].FrozenReason = reason
+			r.Users[i].FrozenBy = pgtype.UUID{Bytes: frozenBy, Valid: frozenBy != uuid.Nil}
+			r.Users[i].FrozenAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			r.Users[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryUserRepository) Unfreeze(_ context.Context, id uuid.UUID) (bool, error) {
+	for i, user := range r.Users {
+		if user.ID == id && user.Status == models.StatusFrozen {
+			r.Users[i].Status = models.StatusActive
+			r.Users[i].FrozenReason = ""
+			r.Users[i].FrozenBy = pgtype.UUID{}
+			r.Users[i].FrozenAt = pgtype.Timestamp{}
+			r.Users[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryUserRepository) UpdateKYCLevel(
 	_ context.Context,
 	id uuid.UUID,
-	balance float64,
+	level models.KYCLevel,
 ) error {
 	for i, user := range r.Users {
 		if user.ID == id {
-			r.Users[i].Balance = balance
+			r.Users[i].KYCLevel = level
+			r.Users[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
 			return nil
 		}
 	}
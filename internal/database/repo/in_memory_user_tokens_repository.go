@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryUserTokensRepository struct {
+	Tokens []models.UserToken
+}
+
+var ErrUserTokenNotFound = errors.New("user token not found")
+
+func (r *InMemoryUserTokensRepository) Create(
+	_ context.Context,
+	token models.UserToken,
+) (uuid.UUID, error) {
+	for _, t := range r.Tokens {
+		if t.TokenHash == token.TokenHash {
+			return uuid.Nil, ErrInsertionOnUnique
+		}
+	}
+
+	token.ID = uuid.New()
+	token.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Tokens = append(r.Tokens, token)
+	return token.ID, nil
+}
+
+func (r *InMemoryUserTokensRepository) FindByHash(
+	_ context.Context,
+	hash string,
+) (models.UserToken, error) {
+	for _, token := range r.Tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+
+	return models.UserToken{}, ErrUserTokenNotFound
+}
+
+func (r *InMemoryUserTokensRepository) Use(_ context.Context, id uuid.UUID) (bool, error) {
+	for i, token := range r.Tokens {
+		if token.ID == id && !token.UsedAt.Valid {
+			r.Tokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *InMemoryUserTokensRepository) Invalidate(
+	_ context.Context,
+	userID uuid.UUID,
+	purpose models.TokenPurpose,
+) error {
+	for i, token := range r.Tokens {
+		if token.UserID == userID && token.Purpose == purpose && !token.UsedAt.Valid {
+			r.Tokens[i].UsedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		}
+	}
+
+	return nil
+}
+
+func (r *InMemoryUserTokensRepository) CountSince(
+	_ context.Context,
+	userID uuid.UUID,
+	purpose models.TokenPurpose,
+	since time.Time,
+) (int, error) {
+	count := 0
+	for _, token := range r.Tokens {
+		if token.UserID == userID &&
+			token.Purpose == purpose &&
+			!token.CreatedAt.Time.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
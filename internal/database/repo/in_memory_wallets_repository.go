@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryWalletsRepository struct {
+	Wallets []models.Wallet
+}
+
+var ErrWalletNotFound = errors.New("wallet not found")
+
+func (r *InMemoryWalletsRepository) Create(
+	ctx context.Context,
+	wallet models.Wallet,
+) (uuid.UUID, error) {
+	_, err := r.FindByUserAndCurrency(ctx, wallet.UserID, wallet.Currency)
+	if err == nil {
+		return uuid.Nil, ErrInsertionOnUnique
+	}
+
+	wallet.ID = uuid.New()
+	wallet.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	wallet.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Wallets = append(r.Wallets, wallet)
+	return wallet.ID, nil
+}
+
+func (r *InMemoryWalletsRepository) FindByUserAndCurrency(
+	_ context.Context,
+	userID uuid.UUID,
+	currency string,
+) (models.Wallet, error) {
+	for _, wallet := range r.Wallets {
+		if wallet.UserID == userID && wallet.Currency == currency {
+			return wallet, nil
+		}
+	}
+
+	return models.Wallet{}, ErrWalletNotFound
+}
+
+func (r *InMemoryWalletsRepository) FindByUser(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	for _, wallet := range r.Wallets {
+		if wallet.UserID == userID {
+			wallets = append(wallets, wallet)
+		}
+	}
+
+	return wallets, nil
+}
+
+func (r *InMemoryWalletsRepository) AddBalance(
+	_ context.Context,
+	id uuid.UUID,
+	amount float64,
+) (float64, bool, error) {
+	for i, wallet := range r.Wallets {
+		if wallet.ID == id {
+			balance, ok := addMajorUnits(wallet.Balance, amount, wallet.Currency)
+			if !ok {
+				return 0, false, nil
+			}
+
+			r.Wallets[i].Balance = balance
+			r.Wallets[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			return balance, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// addMajorUnits adds the amounts in minor units, as the database adds
+// decimals, and returns false if the result would be negative.
+func addMajorUnits(balance, amount float64, currency string) (float64, bool) {
+	sum, err := money.NewFromFloat(balance, currency).Add(money.NewFromFloat(amount, currency))
+	if err != nil || sum.IsNegative() {
+		return balance, false
+	}
+
+	return sum.AsMajorUnits(), true
+}
@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type InMemoryWithdrawalsRepository struct {
+	Withdrawals []models.Withdrawal
+}
+
+var ErrWithdrawalNotFound = errors.New("withdrawal not found")
+
+func (r *InMemoryWithdrawalsRepository) Create(
+	_ context.Context,
+	withdrawal models.Withdrawal,
+) (uuid.UUID, error) {
+	withdrawal.ID = uuid.New()
+	withdrawal.Status = models.WithdrawalPending
+	withdrawal.CreatedAt = pgtype.Timestamp{Time: time.Now()}
+	withdrawal.UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+
+	r.Withdrawals = append(r.Withdrawals, withdrawal)
+	return withdrawal.ID, nil
+}
+
+func (r *InMemoryWithdrawalsRepository) FindByID(
+	_ context.Context,
+	id uuid.UUID,
+) (models.Withdrawal, error) {
+	for _, withdrawal := range r.Withdrawals {
+		if withdrawal.ID == id {
+			return withdrawal, nil
+		}
+	}
+
+	return models.Withdrawal{}, ErrWithdrawalNotFound
+}
+
+func (r *InMemoryWithdrawalsRepository) FindByStatus(
+	_ context.Context,
+	status models.WithdrawalStatus,
+	limit int,
+) ([]models.Withdrawal, error) {
+	withdrawals := make([]models.Withdrawal, 0, limit)
+	for _, withdrawal := range r.Withdrawals {
+		if len(withdrawals) == limit {
+			break
+		}
+
+		if withdrawal.Status == status {
+			withdrawals = append(withdrawals, withdrawal)
+		}
+	}
+
+	return withdrawals, nil
+}
+
+func (r *InMemoryWithdrawalsRepository) UpdateStatus(
+	_ context.Context,
+	withdrawal models.Withdrawal,
+	from models.WithdrawalStatus,
+) (bool, error) {
+	for i := range r.Withdrawals {
+		if r.Withdrawals[i].ID != withdrawal.ID {
+			continue
+		}
+
+		if r.Withdrawals[i].Status != from {
+			return false, nil
+		}
+
+		r.Withdrawals[i].Status = withdrawal.Status
+		r.Withdrawals[i].Reference = withdrawal.Reference
+		r.Withdrawals[i].FailureReason = withdrawal.FailureReason
+		r.Withdrawals[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+		switch withdrawal.Status {
+		case models.WithdrawalProcessing:
+			r.Withdrawals[i].ClaimedAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		case models.WithdrawalPending:
+			r.Withdrawals[i].ClaimedAt = pgtype.Timestamp{}
+		}
+		if withdrawal.Status == models.WithdrawalCompleted ||
+			withdrawal.Status == models.WithdrawalFailed {
+			r.Withdrawals[i].SettledAt = pgtype.Timestamp{Time: time.Now(), Valid: true}
+		}
+		return true, nil
+	}
+
+	return false, ErrWithdrawalNotFound
+}
+
+func (r *InMemoryWithdrawalsRepository) ReleaseStale(_ context.Context, before time.Time) (int, error) {
+	released := 0
+	for i, withdrawal := range r.Withdrawals {
+		if withdrawal.Status == models.WithdrawalProcessing &&
+			withdrawal.ClaimedAt.Time.Before(before) {
+			r.Withdrawals[i].Status = models.WithdrawalPending
+			r.Withdrawals[i].ClaimedAt = pgtype.Timestamp{}
+			r.Withdrawals[i].UpdatedAt = pgtype.Timestamp{Time: time.Now()}
+			released++
+		}
+	}
+
+	return released, nil
+}
+
+func (r *InMemoryWithdrawalsRepository) HasPending(_ context.Context, userID uuid.UUID) (bool, error) {
+	for _, withdrawal := range r.Withdrawals {
+		if withdrawal.UserID == userID &&
+			(withdrawal.Status == models.WithdrawalPending ||
+				withdrawal.Status == models.WithdrawalProcessing) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
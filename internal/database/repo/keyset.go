@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// keyset returns how the (created_at, id) of the rows fetched are compared
+// to the cursor and their order. The pages before the cursor are fetched in
+// reverse and must be reversed after, see reverseIfBackward.
+func keyset(cursor *models.Cursor, descending bool) (op, order string) {
+	backward := cursor != nil && cursor.Backward
+	if descending != backward {
+		return "<", "DESC"
+	}
+
+	return ">", "ASC"
+}
+
+// keysetArgs are null without a cursor, which fetches the first page
+func keysetArgs(cursor *models.Cursor) (pgtype.Timestamp, pgtype.UUID) {
+	if cursor == nil {
+		return pgtype.Timestamp{}, pgtype.UUID{}
+	}
+
+	return pgtype.Timestamp{Time: cursor.CreatedAt, Valid: true},
+		pgtype.UUID{Bytes: cursor.ID, Valid: true}
+}
+
+func reverseIfBackward[T any](cursor *models.Cursor, rows []T) {
+	if cursor != nil && cursor.Backward {
+		slices.Reverse(rows)
+	}
+}
+
+// inMemoryKeyset pages the items, sorted by (created_at, id) in the
+// order given, the same way the keyset queries do.
+func inMemoryKeyset[T any](
+	items []T,
+	cursor *models.Cursor,
+	limit int,
+	descending bool,
+	position func(T) models.Cursor,
+) []T {
+	if cursor == nil {
+		return items[:min(limit, len(items))]
+	}
+
+	// Positive if the item comes after the cursor in the listing
+	compare := func(item T) int {
+		p := position(item)
+
+		c := p.CreatedAt.Compare(cursor.CreatedAt)
+		if c == 0 {
+			c = bytes.Compare(p.ID[:], cursor.ID[:])
+		}
+
+		if descending {
+			return -c
+		}
+
+		return c
+	}
+
+	var page []T
+	for _, item := range items {
+		if cursor.Backward && compare(item) < 0 || !cursor.Backward && compare(item) > 0 {
+			page = append(page, item)
+		}
+	}
+
+	if cursor.Backward {
+		return page[max(len(page)-limit, 0):]
+	}
+
+	return page[:min(limit, len(page))]
+}
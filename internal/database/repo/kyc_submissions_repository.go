@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type KYCSubmissionsRepository struct {
+	db       *pgxpool.Pool
+	pageSize int
+}
+
+func NewKYCSubmissionsRepository(db *pgxpool.Pool, pageSize int) *KYCSubmissionsRepository {
+	return &KYCSubmissionsRepository{
+		db,
+		pageSize,
+	}
+}
+
+func scanKYCSubmission(row pgx.Row) (models.KYCSubmission, error) {
+	var submission models.KYCSubmission
+	err := row.Scan(
+		&submission.ID,
+		&submission.UserID,
+		&submission.Level,
+		&submission.BirthDate,
+		&submission.AddressLine,
+		&submission.City,
+		&submission.State,
+		&submission.PostalCode,
+		&submission.Documents,
+		&submission.Status,
+		&submission.ReviewedBy,
+		&submission.ReviewNote,
+		&submission.ReviewedAt,
+		&submission.CreatedAt,
+		&submission.UpdatedAt,
+	)
+
+	return submission, err
+}
+
+const createKYCSubmission = `
+	INSERT INTO kyc_submissions (
+		"user_id",
+		"level",
+		"birth_date",
+		"address_line",
+		"city",
+		"state",
+		"postal_code",
+		"documents"
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING "id";
+`
+
+func (r *KYCSubmissionsRepository) Create(
+	ctx context.Context,
+	submission models.KYCSubmission,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := r.db.QueryRow(
+		ctx,
+		createKYCSubmission,
+		submission.UserID,
+		submission.Level,
+		submission.BirthDate,
+		submission.AddressLine,
+		submission.City,
+		submission.State,
+		submission.PostalCode,
+		submission.Documents,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findKYCSubmissionByID = "SELECT * FROM kyc_submissions WHERE id = $1"
+
+func (r *KYCSubmissionsRepository) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.KYCSubmission, error) {
+	row := r.db.QueryRow(ctx, findKYCSubmissionByID, id)
+	return scanKYCSubmission(row)
+}
+
+const findLatestKYCSubmission = `
+	SELECT * FROM kyc_submissions WHERE user_id = $1
+	ORDER BY created_at DESC LIMIT 1;
+`
+
+func (r *KYCSubmissionsRepository) FindLatestByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) (models.KYCSubmission, error) {
+	row := r.db.QueryRow(ctx, findLatestKYCSubmission, userID)
+	return scanKYCSubmission(row)
+}
+
+const findKYCSubmissionsByStatus = `
+	SELECT * FROM kyc_submissions
+	WHERE status = $1
+	ORDER BY created_at
+	LIMIT $2 OFFSET $3;
+`
+
+func (r *KYCSubmissionsRepository) FindByStatus(
+	ctx context.Context,
+	status models.ReviewStatus,
+	page int,
+) ([]models.KYCSubmission, error) {
+	rows, err := r.db.Query(
+		ctx,
+		findKYCSubmissionsByStatus,
+		status,
+		r.pageSize,
+		(page-1)*r.pageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	submissions := make([]models.KYCSubmission, 0, r.pageSize)
+	for rows.Next() {
+		submission, err := scanKYCSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		submissions = append(submissions, submission)
+	}
+
+	return submissions, rows.Err()
+}
+
+const hasPendingKYCSubmission = `
+	SELECT EXISTS (
+		SELECT 1 FROM kyc_submissions WHERE user_id = $1 AND status = 'PENDING'
+	);
+`
+
+func (r *KYCSubmissionsRepository) HasPending(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var pending bool
+	err := r.db.QueryRow(ctx, hasPendingKYCSubmission, userID).Scan(&pending)
+	return pending, err
+}
+
+const resolveKYCSubmission = `
+	UPDATE kyc_submissions SET
+		status = $2,
+		reviewed_by = $3,
+		review_note = $4,
+		reviewed_at = NOW(),
+		updated_at = NOW()
+	WHERE id = $1 AND status = 'PENDING';
+`
+
+// Resolve returns false if the submission was already resolved.
+func (r *KYCSubmissionsRepository) Resolve(
+	ctx context.Context,
+	submission models.KYCSubmission,
+) (bool, error) {
+	tag, err := r.db.Exec(
+		ctx,
+		resolveKYCSubmission,
+		submission.ID,
+		submission.Status,
+		submission.ReviewedBy,
+		submission.ReviewNote,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReconciliationRepository compares the balances of the users with the
+// ones rebuilt from the same entries of the StatementsRepository.
+type ReconciliationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReconciliationRepository(db *pgxpool.Pool) *ReconciliationRepository {
+	return &ReconciliationRepository{
+		db,
+	}
+}
+
+// The BRL balance is in users.balance and the others in the wallets, a
+// balance missing on either side is zero. The comparison is on the
+// DECIMAL values, so it is exact. The query runs on a single snapshot.
+const findDiscrepancies = `
+	WITH ledger AS (
+		SELECT payer AS user_id, currency, -amount AS amount FROM transactions
+		UNION ALL
+		SELECT payee, payee_currency, payee_amount FROM transactions
+		UNION ALL
+		SELECT user_id, currency, amount FROM deposits WHERE status = 'CONFIRMED'
+		UNION ALL
+		SELECT user_id, 'BRL', -amount FROM withdrawals
+		UNION ALL
+		SELECT user_id, 'BRL', amount FROM withdrawals WHERE status = 'FAILED'
+	), expected AS (
+		SELECT user_id, currency, SUM(amount) AS balance
+		FROM ledger
+		WHERE $1::uuid IS NULL OR user_id = $1
+		GROUP BY user_id, currency
+	), actual AS (
+		SELECT id AS user_id, 'BRL'::char(3) AS currency, balance
+		FROM users WHERE $1::uuid IS NULL OR id = $1
+		UNION ALL
+		SELECT user_id, currency, balance
+		FROM wallets WHERE $1::uuid IS NULL OR user_id = $1
+	)
+	SELECT
+		COALESCE(a.user_id, e.user_id),
+		COALESCE(a.currency, e.currency),
+		COALESCE(e.balance, 0)::float8,
+		COALESCE(a.balance, 0)::float8
+	FROM actual a
+	FULL JOIN expected e ON e.user_id = a.user_id AND e.currency = a.currency
+	WHERE COALESCE(a.balance, 0) <> COALESCE(e.balance, 0)
+	ORDER BY 1, 2
+`
+
+func (r *ReconciliationRepository) find(
+	ctx context.Context,
+	userID pgtype.UUID,
+	each func(models.Discrepancy) error,
+) error {
+	rows, err := r.db.Query(ctx, findDiscrepancies, userID)
+	if err != nil {
+		return err
+	}
+
+	var discrepancy models.Discrepancy
+	_, err = pgx.ForEachRow(rows, []any{
+		&discrepancy.UserID,
+		&discrepancy.Currency,
+		&discrepancy.Expected,
+		&discrepancy.Actual,
+	}, func() error {
+		return each(discrepancy)
+	})
+
+	return err
+}
+
+// Discrepancies calls each with the balances of every user that differ
+// from the expected ones, ordered by user and currency, as they are read.
+func (r *ReconciliationRepository) Discrepancies(
+	ctx context.Context,
+	each func(models.Discrepancy) error,
+) error {
+	return r.find(ctx, pgtype.UUID{}, each)
+}
+
+func (r *ReconciliationRepository) FindByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.Discrepancy, error) {
+	var discrepancies []models.Discrepancy
+	err := r.find(ctx, pgtype.UUID{Bytes: userID, Valid: true}, func(d models.Discrepancy) error {
+		discrepancies = append(discrepancies, d)
+		return nil
+	})
+
+	return discrepancies, err
+}
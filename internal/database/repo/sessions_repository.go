@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionsRepository(db *pgxpool.Pool) *SessionsRepository {
+	return &SessionsRepository{
+		db,
+	}
+}
+
+func scanSession(row pgx.Row) (models.Session, error) {
+	var session models.Session
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&session.UserAgent,
+		&session.IP,
+		&session.ExpiresAt,
+		&session.LastUsedAt,
+		&session.RevokedAt,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+
+	return session, err
+}
+
+const createSession = `
+	INSERT INTO sessions (
+		"id",
+		"user_id",
+		"refresh_token_hash",
+		"user_agent",
+		"ip",
+		"expires_at"
+	) VALUES ($1, $2, $3, $4, $5, $6);
+`
+
+// Create stores the session with its given id, which is
+// part of the refresh token generated before the insertion.
+func (r *SessionsRepository) Create(ctx context.Context, session models.Session) error {
+	_, err := r.db.Exec(
+		ctx,
+		createSession,
+		session.ID,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.IP,
+		session.ExpiresAt,
+	)
+
+	return err
+}
+
+const findSessionByID = "SELECT * FROM sessions WHERE id = $1"
+
+func (r *SessionsRepository) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.Session, error) {
+	row := r.db.QueryRow(ctx, findSessionByID, id)
+	return scanSession(row)
+}
+
+const findActiveSessionsByUser = `
+	SELECT * FROM sessions
+	WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	ORDER BY last_used_at DESC
+`
+
+func (r *SessionsRepository) FindActiveByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.Session, error) {
+	rows, err := r.db.Query(ctx, findActiveSessionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+const rotateSession = `
+	UPDATE sessions
+	SET refresh_token_hash = $3,
+		user_agent = $4,
+		ip = $5,
+		expires_at = $6,
+		last_used_at = NOW(),
+		updated_at = NOW()
+	WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL;
+`
+
+// Rotate replaces the refresh token of the session only if it is still the
+// given one, returns false if it was already rotated or the session revoked.
+func (r *SessionsRepository) Rotate(
+	ctx context.Context,
+	session models.Session,
+	previousHash string,
+) (bool, error) {
+	tag, err := r.db.Exec(
+		ctx,
+		rotateSession,
+		session.ID,
+		previousHash,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.IP,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const revokeSession = `
+	UPDATE sessions
+	SET revoked_at = NOW(), updated_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL;
+`
+
+// Revoke returns false if the session was already revoked.
+func (r *SessionsRepository) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, revokeSession, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const revokeSessionsByUser = `
+	UPDATE sessions
+	SET revoked_at = NOW(), updated_at = NOW()
+	WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL;
+`
+
+// RevokeByUser revokes every active session of the user but the kept one,
+// which may be uuid.Nil to revoke all, and returns how many were revoked.
+func (r *SessionsRepository) RevokeByUser(
+	ctx context.Context,
+	userID, keep uuid.UUID,
+) (int, error) {
+	tag, err := r.db.Exec(ctx, revokeSessionsByUser, userID, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
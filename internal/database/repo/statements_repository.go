@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StatementsRepository reads the balance changes of the users from the
+// transfers, deposits and withdrawals, every change of a balance is one
+// of them, so the balances can be rebuilt at any time.
+type StatementsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStatementsRepository(db *pgxpool.Pool) *StatementsRepository {
+	return &StatementsRepository{
+		db,
+	}
+}
+
+// The withdrawals are always in BRL, their hold is a debit when they are
+// requested and the failed ones are credited back when they are settled.
+const statementEntries = `
+	WITH entries AS (
+		SELECT id, 'TRANSFER_OUT' AS kind, -amount AS amount, created_at, payee AS counterparty
+		FROM transactions WHERE payer = $1 AND currency = $2
+		UNION ALL
+		SELECT id, 'TRANSFER_IN', payee_amount, created_at, payer
+		FROM transactions WHERE payee = $1 AND payee_currency = $2
+		UNION ALL
+		SELECT id, 'DEPOSIT', amount, confirmed_at, NULL
+		FROM deposits WHERE user_id = $1 AND currency = $2 AND status = 'CONFIRMED'
+		UNION ALL
+		SELECT id, 'WITHDRAWAL', -amount, created_at, NULL
+		FROM withdrawals WHERE user_id = $1 AND $2 = 'BRL'
+		UNION ALL
+		SELECT id, 'WITHDRAWAL_REVERSAL', amount, settled_at, NULL
+		FROM withdrawals WHERE user_id = $1 AND $2 = 'BRL' AND status = 'FAILED'
+	)
+`
+
+const balanceBefore = statementEntries + `
+	SELECT COALESCE(SUM(amount), 0)::float8 FROM entries WHERE created_at < $3
+`
+
+// BalanceAt returns the balance of the user in the currency right before the time.
+func (r *StatementsRepository) BalanceAt(
+	ctx context.Context,
+	userID uuid.UUID,
+	currency string,
+	at time.Time,
+) (float64, error) {
+	var balance float64
+	err := r.db.QueryRow(ctx, balanceBefore, userID, currency, at).Scan(&balance)
+	return balance, err
+}
+
+const findEntries = statementEntries + `
+	SELECT id, kind, amount::float8, created_at, counterparty FROM entries
+	WHERE created_at >= $3 AND created_at < $4
+	ORDER BY created_at, id
+`
+
+// Entries calls each with the balance changes in the [from, to) range
+// in chronological order, as they are read, so they are never all in
+// memory. It stops at the first error of each.
+func (r *StatementsRepository) Entries(
+	ctx context.Context,
+	userID uuid.UUID,
+	currency string,
+	from, to time.Time,
+	each func(models.StatementEntry) error,
+) error {
+	rows, err := r.db.Query(ctx, findEntries, userID, currency, from, to)
+	if err != nil {
+		return err
+	}
+
+	var entry models.StatementEntry
+	_, err = pgx.ForEachRow(rows, []any{
+		&entry.ID,
+		&entry.Kind,
+		&entry.Amount,
+		&entry.CreatedAt,
+		&entry.Counterparty,
+	}, func() error {
+		return each(entry)
+	})
+
+	return err
+}
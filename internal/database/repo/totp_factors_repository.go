@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TOTPFactorsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTOTPFactorsRepository(db *pgxpool.Pool) *TOTPFactorsRepository {
+	return &TOTPFactorsRepository{
+		db,
+	}
+}
+
+const upsertTOTPFactor = `
+	INSERT INTO totp_factors ("user_id", "secret")
+	VALUES ($1, $2)
+	ON CONFLICT ("user_id") DO UPDATE
+	SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = NOW()
+	WHERE totp_factors.confirmed_at IS NULL;
+`
+
+// Save stores the factor of the user, replacing a previous one only if it
+// was not confirmed. Returns false if the user already has a confirmed one.
+func (r *TOTPFactorsRepository) Save(ctx context.Context, factor models.TOTPFactor) (bool, error) {
+	tag, err := r.db.Exec(ctx, upsertTOTPFactor, factor.UserID, factor.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const findTOTPFactorByUser = "SELECT * FROM totp_factors WHERE user_id = $1"
+
+func (r *TOTPFactorsRepository) FindByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) (models.TOTPFactor, error) {
+	var factor models.TOTPFactor
+	err := r.db.QueryRow(ctx, findTOTPFactorByUser, userID).Scan(
+		&factor.UserID,
+		&factor.Secret,
+		&factor.ConfirmedAt,
+		&factor.LastUsedStep,
+		&factor.CreatedAt,
+		&factor.UpdatedAt,
+		&factor.FailedAttempts,
+		&factor.LockedUntil,
+	)
+
+	return factor, err
+}
+
+// The attempts are counted in place, so concurrent guesses are all
+// counted, and start over once the factor is locked.
+const recordTOTPFailure = `
+	UPDATE totp_factors SET
+		failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		locked_until = CASE
+			WHEN failed_attempts + 1 >= $2 THEN NOW() + make_interval(secs => $3)
+			ELSE locked_until
+		END,
+		updated_at = NOW()
+	WHERE user_id = $1
+	RETURNING failed_attempts = 0;
+`
+
+// RecordFailure counts an invalid code, locking the factor for the lockout
+// after maxAttempts in a row. Returns true if this failure locked it.
+func (r *TOTPFactorsRepository) RecordFailure(
+	ctx context.Context,
+	userID uuid.UUID,
+	maxAttempts int,
+	lockout time.Duration,
+) (bool, error) {
+	var locked bool
+	err := r.db.QueryRow(ctx, recordTOTPFailure, userID, maxAttempts, lockout.Seconds()).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return locked, err
+}
+
+const resetTOTPFailures = `
+	UPDATE totp_factors SET failed_attempts = 0, updated_at = NOW()
+	WHERE user_id = $1 AND failed_attempts > 0;
+`
+
+func (r *TOTPFactorsRepository) ResetFailures(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, resetTOTPFailures, userID)
+	return err
+}
+
+const useTOTPStep = `
+	UPDATE totp_factors
+	SET last_used_step = $2,
+		confirmed_at = COALESCE(confirmed_at, NOW()),
+		updated_at = NOW()
+	WHERE user_id = $1 AND last_used_step < $2;
+`
+
+// UseStep records the step of an accepted code, confirming the factor on
+// its first use. Returns false if a code of the same or a later step was
+// already used.
+func (r *TOTPFactorsRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, useTOTPStep, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const deleteTOTPFactor = "DELETE FROM totp_factors WHERE user_id = $1"
+
+const deleteRecoveryCodes = "DELETE FROM recovery_codes WHERE user_id = $1"
+
+// Delete removes the factor along with the recovery codes of the user.
+func (r *TOTPFactorsRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, deleteRecoveryCodes, userID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, deleteTOTPFactor, userID)
+		return err
+	})
+}
+
+const createRecoveryCode = `
+	INSERT INTO recovery_codes ("user_id", "code_hash") VALUES ($1, $2);
+`
+
+// ReplaceRecoveryCodes invalidates the previous recovery codes of the user.
+func (r *TOTPFactorsRepository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userID uuid.UUID,
+	hashes []string,
+) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, deleteRecoveryCodes, userID); err != nil {
+			return err
+		}
+
+		for _, hash := range hashes {
+			if _, err := tx.Exec(ctx, createRecoveryCode, userID, hash); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+const useRecoveryCode = `
+	UPDATE recovery_codes
+	SET used_at = NOW()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+`
+
+// UseRecoveryCode returns false if the code does not exist or was used.
+func (r *TOTPFactorsRepository) UseRecoveryCode(
+	ctx context.Context,
+	userID uuid.UUID,
+	hash string,
+) (bool, error) {
+	tag, err := r.db.Exec(ctx, useRecoveryCode, userID, hash)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -2,9 +2,12 @@ package repo
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,12 +21,36 @@ func NewTransactionsRepository(db *pgxpool.Pool) *TransactionsRepository {
 	}
 }
 
+func scanTransaction(row pgx.Row) (models.Transaction, error) {
+	var transaction models.Transaction
+	err := row.Scan(
+		&transaction.ID,
+		&transaction.Amount,
+		&transaction.Payer,
+		&transaction.Payee,
+		&transaction.CreatedAt,
+		&transaction.UpdatedAt,
+		&transaction.Currency,
+		&transaction.PayeeAmount,
+		&transaction.PayeeCurrency,
+		&transaction.FXRate,
+		&transaction.FXSpread,
+	)
+
+	return transaction, err
+}
+
 const create = `
 	INSERT INTO transactions (
 		payer,
 		payee,
-		amount
-	) VALUES ($1, $2, $3)
+		amount,
+		currency,
+		payee_amount,
+		payee_currency,
+		fx_rate,
+		fx_spread
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	RETURNING id;
 `
 
@@ -33,13 +60,103 @@ func (r *TransactionsRepository) Create(
 ) (uuid.UUID, error) {
 	var id uuid.UUID
 
-	err := r.db.QueryRow(
+	err := conn(ctx, r.db).QueryRow(
 		ctx,
 		create,
 		transaction.Payer,
 		transaction.Payee,
 		transaction.Amount,
+		transaction.Currency,
+		transaction.PayeeAmount,
+		transaction.PayeeCurrency,
+		transaction.FXRate,
+		transaction.FXSpread,
 	).Scan(&id)
 
 	return id, err
 }
+
+const countByPayerSince = `
+	SELECT COUNT(*) FROM transactions
+	WHERE payer = $1 AND created_at >= $2;
+`
+
+func (r *TransactionsRepository) CountByPayerSince(
+	ctx context.Context,
+	payer uuid.UUID,
+	since time.Time,
+) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRow(ctx, countByPayerSince, payer, since).Scan(&count)
+	return count, err
+}
+
+const countBetween = `
+	SELECT COUNT(*) FROM transactions
+	WHERE payer = $1 AND payee = $2 AND created_at >= $3;
+`
+
+// CountBetween counts the transfers from payer to payee made since the given time.
+func (r *TransactionsRepository) CountBetween(
+	ctx context.Context,
+	payer, payee uuid.UUID,
+	since time.Time,
+) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRow(ctx, countBetween, payer, payee, since).Scan(&count)
+	return count, err
+}
+
+const findTransactionsByUser = `
+	SELECT * FROM transactions
+	WHERE (payer = $1 OR payee = $1)
+		AND ($2::timestamp IS NULL OR (created_at, id) %s ($2, $3::uuid))
+	ORDER BY created_at %s, id %s
+	LIMIT $4;
+`
+
+// FindByUser returns up to limit transactions sent or received by
+// the user next to the cursor, most recent first.
+func (r *TransactionsRepository) FindByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+	cursor *models.Cursor,
+	limit int,
+) ([]models.Transaction, error) {
+	op, order := keyset(cursor, true)
+	createdAt, id := keysetArgs(cursor)
+
+	rows, err := conn(ctx, r.db).Query(
+		ctx,
+		fmt.Sprintf(findTransactionsByUser, op, order, order),
+		userID,
+		createdAt,
+		id,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0, limit)
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, transaction)
+	}
+
+	reverseIfBackward(cursor, transactions)
+	return transactions, rows.Err()
+}
+
+const countByUser = "SELECT COUNT(*) FROM transactions WHERE payer = $1 OR payee = $1"
+
+func (r *TransactionsRepository) CountByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRow(ctx, countByUser, userID).Scan(&count)
+	return count, err
+}
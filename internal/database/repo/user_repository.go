@@ -2,25 +2,45 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// documentCipher encrypts the documents of the users, which are
+// looked up by their blind index.
+type documentCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+	CurrentPrefix() string
+	BlindIndex(value string) string
+}
+
+// UserRepository stores the documents encrypted, the users
+// returned by it have the decrypted documents.
 type UserRepository struct {
-	db *pgxpool.Pool
+	db        *pgxpool.Pool
+	documents documentCipher
 }
 
-func NewUserRepository(db *pgxpool.Pool) *UserRepository {
+func NewUserRepository(db *pgxpool.Pool, documents documentCipher) *UserRepository {
 	return &UserRepository{
 		db,
+		documents,
 	}
 }
 
-func scanUser(row pgx.Row) (models.User, error) {
-	var user models.User
+func (r *UserRepository) scanUser(row pgx.Row) (models.User, error) {
+	var (
+		user  models.User
+		index pgtype.Text
+	)
 	err := row.Scan(
 		&user.ID,
 		&user.FirstName,
@@ -32,29 +52,42 @@ func scanUser(row pgx.Row) (models.User, error) {
 		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
+		&user.DocumentType,
+		&user.DeletedAt,
+		&user.Status,
+		&user.FrozenReason,
+		&user.FrozenBy,
+		&user.FrozenAt,
+		&user.KYCLevel,
+		&index,
 	)
+	if err != nil {
+		return models.User{}, err
+	}
 
+	user.Document, err = r.documents.Decrypt(user.Document)
 	return user, err
 }
 
-const findByDocument = "SELECT * FROM users WHERE document = $1"
+const findByDocument = "SELECT * FROM users WHERE document_index = $1 AND deleted_at IS NULL"
 
 func (r *UserRepository) FindByDocument(
 	ctx context.Context,
 	document string,
 ) (models.User, error) {
-	row := r.db.QueryRow(ctx, findByDocument, document)
-	return scanUser(row)
+	row := conn(ctx, r.db).QueryRow(ctx, findByDocument, r.documents.BlindIndex(document))
+	return r.scanUser(row)
 }
 
-const findByID = "SELECT * FROM users WHERE id = $1"
+const findByID = "SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL"
 
 func (r *UserRepository) FindByID(
 	ctx context.Context,
 	id uuid.UUID,
 ) (models.User, error) {
-	row := r.db.QueryRow(ctx, findByID, id)
-	return scanUser(row)
+	row := conn(ctx, r.db).QueryRow(ctx, findByID, id)
+	return r.scanUser(row)
 }
 
 const createUser = `
@@ -65,8 +98,10 @@ const createUser = `
 		"email",
 		"password_hash",
 		"balance",
-		"role"
-	) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		"role",
+		"document_type",
+		"document_index"
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	RETURNING "id";
 `
 
@@ -76,38 +111,145 @@ func (r *UserRepository) Create(
 ) (uuid.UUID, error) {
 	var id uuid.UUID
 
-	err := r.db.QueryRow(
+	document, err := r.documents.Encrypt(user.Document)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = conn(ctx, r.db).QueryRow(
 		ctx,
 		createUser,
 		user.FirstName,
 		user.LastName,
-		user.Document,
+		document,
 		user.Email,
 		user.PasswordHash,
 		user.Balance,
 		user.Role,
+		user.DocumentType,
+		r.documents.BlindIndex(user.Document),
 	).Scan(&id)
 
 	return id, err
 }
 
-const findMany = "SELECT * FROM users LIMIT $1 OFFSET $2;"
+// Matches the users of a models.UserFilter, the status
+// filter is the only one that reaches the closed users.
+const userFilter = `
+	WHERE ($1::text = '' OR role::text = $1)
+		AND ($2::text = '' OR first_name ILIKE $2 OR last_name ILIKE $2
+			OR first_name || ' ' || last_name ILIKE $2)
+		AND ($3::text = '' OR lower(email) = lower($3))
+		AND ($4::text = '' AND deleted_at IS NULL OR status::text = $4)
+		AND ($5::timestamp IS NULL OR created_at >= $5)
+		AND ($6::timestamp IS NULL OR created_at < $6)
+`
+
+// The columns of each sort, the id breaks the ties so the pages are stable
+var userSortColumns = map[models.UserSort][]string{
+	models.UserSortCreatedAt: {"created_at"},
+	models.UserSortName:      {"first_name", "last_name"},
+	models.UserSortEmail:     {"email"},
+}
+
+func userOrderBy(sort models.UserSort, descending bool) string {
+	columns, ok := userSortColumns[sort]
+	if !ok {
+		columns = userSortColumns[models.UserSortCreatedAt]
+	}
+
+	direction := " ASC"
+	if descending {
+		direction = " DESC"
+	}
+
+	order := make([]string, 0, len(columns)+1)
+	for _, column := range append(columns, "id") {
+		order = append(order, column+direction)
+	}
+
+	return strings.Join(order, ", ")
+}
+
+// likePrefix escapes the LIKE wildcards of the prefix
+func likePrefix(prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	return escaped + "%"
+}
 
-const itemsPerPage = 20
+func userFilterArgs(filter models.UserFilter) []any {
+	return []any{
+		string(filter.Role),
+		likePrefix(filter.NamePrefix),
+		filter.Email,
+		string(filter.Status),
+		filter.CreatedFrom,
+		filter.CreatedTo,
+	}
+}
 
 func (r *UserRepository) FindMany(
 	ctx context.Context,
+	filter models.UserFilter,
 	page int,
+	pageSize int,
+) ([]models.User, error) {
+	query := fmt.Sprintf(
+		"SELECT * FROM users %s ORDER BY %s LIMIT $7 OFFSET $8",
+		userFilter,
+		userOrderBy(filter.Sort, filter.Descending),
+	)
+
+	args := append(userFilterArgs(filter), pageSize, (page-1)*pageSize)
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0, pageSize)
+	for rows.Next() {
+		user, err := r.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// FindAfter returns up to limit users next to the cursor, or the first
+// ones without it, sorted by creation regardless of the filter sort.
+func (r *UserRepository) FindAfter(
+	ctx context.Context,
+	filter models.UserFilter,
+	cursor *models.Cursor,
+	limit int,
 ) ([]models.User, error) {
-	rows, err := r.db.Query(ctx, findMany, itemsPerPage, (page-1)*itemsPerPage)
+	op, order := keyset(cursor, filter.Descending)
+	query := fmt.Sprintf(`
+		SELECT * FROM users %s
+			AND ($7::timestamp IS NULL OR (created_at, id) %s ($7, $8::uuid))
+		ORDER BY created_at %s, id %s LIMIT $9
+	`, userFilter, op, order, order)
+
+	createdAt, id := keysetArgs(cursor)
+	args := append(userFilterArgs(filter), createdAt, id, limit)
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	users := make([]models.User, 0, itemsPerPage)
+	users := make([]models.User, 0, limit)
 	for rows.Next() {
-		user, err := scanUser(rows)
+		user, err := r.scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -115,26 +257,258 @@ func (r *UserRepository) FindMany(
 		users = append(users, user)
 	}
 
-	return users, nil
+	reverseIfBackward(cursor, users)
+	return users, rows.Err()
+}
+
+const countUsers = "SELECT COUNT(*) FROM users" + userFilter
+
+func (r *UserRepository) Count(
+	ctx context.Context,
+	filter models.UserFilter,
+) (int, error) {
+	var total int
+	err := conn(ctx, r.db).QueryRow(ctx, countUsers, userFilterArgs(filter)...).Scan(&total)
+	return total, err
 }
 
-const findByEmail = "SELECT * FROM users WHERE email = $1"
+// Matches the emails stored before they were lower cased too
+const findByEmail = "SELECT * FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL"
 
 func (r *UserRepository) FindByEmail(
 	ctx context.Context,
 	email string,
 ) (models.User, error) {
-	row := r.db.QueryRow(ctx, findByEmail, email)
-	return scanUser(row)
+	row := conn(ctx, r.db).QueryRow(ctx, findByEmail, email)
+	return r.scanUser(row)
 }
 
-const updateBalance = "UPDATE users SET balance = $2 WHERE id = $1"
+// The balance is changed in place, so concurrent operations on the
+// same user neither overwrite each other nor overdraw the balance.
+const addBalance = `
+	UPDATE users SET balance = balance + $2::numeric
+	WHERE id = $1 AND balance + $2::numeric >= 0
+	RETURNING balance
+`
 
-func (r *UserRepository) UpdateBalance(
+// AddBalance adds the amount, which may be negative, to the balance of
+// the user and returns the new balance. Returns false if the user is not
+// found or the balance would become negative.
+func (r *UserRepository) AddBalance(
 	ctx context.Context,
 	id uuid.UUID,
 	amount float64,
+) (float64, bool, error) {
+	var balance float64
+	err := conn(ctx, r.db).QueryRow(ctx, addBalance, id, amount).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return balance, true, nil
+}
+
+const updatePassword = `
+	UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1
+`
+
+func (r *UserRepository) UpdatePassword(
+	ctx context.Context,
+	id uuid.UUID,
+	passwordHash string,
 ) error {
-	_, err := r.db.Exec(ctx, updateBalance, id, amount)
+	_, err := conn(ctx, r.db).Exec(ctx, updatePassword, id, passwordHash)
 	return err
 }
+
+const markEmailVerified = `
+	UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+	WHERE id = $1 AND lower(email) = lower($2) AND email_verified_at IS NULL
+`
+
+// MarkEmailVerified verifies the email of the user, unless it was
+// changed meanwhile.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) error {
+	_, err := conn(ctx, r.db).Exec(ctx, markEmailVerified, id, email)
+	return err
+}
+
+const updateProfile = `
+	UPDATE users SET
+		first_name = $2,
+		last_name = $3,
+		email = $4,
+		email_verified_at = $5,
+		updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (r *UserRepository) UpdateProfile(ctx context.Context, user models.User) error {
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		updateProfile,
+		user.ID,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.EmailVerifiedAt,
+	)
+	return err
+}
+
+const closeUser = `
+	UPDATE users SET status = 'CLOSED', deleted_at = NOW(), updated_at = NOW()
+	WHERE id = $1 AND balance = 0 AND status = 'ACTIVE'
+`
+
+// Close soft deletes the user, keeping its financial history. Returns
+// false if the user is not active or its balance is not zero.
+func (r *UserRepository) Close(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := conn(ctx, r.db).Exec(ctx, closeUser, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const freezeUser = `
+	UPDATE users SET
+		status = 'FROZEN',
+		frozen_reason = $2,
+		frozen_by = $3,
+		frozen_at = NOW(),
+		updated_at = NOW()
+	WHERE id = $1 AND status = 'ACTIVE'
+`
+
+// Freeze returns false if the user is not active. A nil frozenBy
+// means it was frozen by the system.
+func (r *UserRepository) Freeze(
+	ctx context.Context,
+	id uuid.UUID,
+	reason string,
+	frozenBy uuid.UUID,
+) (bool, error) {
+	by := pgtype.UUID{Bytes: frozenBy, Valid: frozenBy != uuid.Nil}
+	tag, err := conn(ctx, r.db).Exec(ctx, freezeUser, id, reason, by)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const unfreezeUser = `
+	UPDATE users SET
+		status = 'ACTIVE',
+		frozen_reason = '',
+		frozen_by = NULL,
+		frozen_at = NULL,
+		updated_at = NOW()
+	WHERE id = $1 AND status = 'FROZEN'
+`
+
+// Unfreeze returns false if the user is not frozen.
+func (r *UserRepository) Unfreeze(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := conn(ctx, r.db).Exec(ctx, unfreezeUser, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const updateKYCLevel = "UPDATE users SET kyc_level = $2, updated_at = NOW() WHERE id = $1"
+
+func (r *UserRepository) UpdateKYCLevel(
+	ctx context.Context,
+	id uuid.UUID,
+	level models.KYCLevel,
+) error {
+	_, err := conn(ctx, r.db).Exec(ctx, updateKYCLevel, id, level)
+	return err
+}
+
+// Number of documents encrypted at a time by EncryptDocuments
+const encryptionBatchSize = 100
+
+const findStaleDocuments = `
+	SELECT id, document, document_index FROM users
+	WHERE document_index IS NULL OR NOT starts_with(document, $1)
+	LIMIT $2
+`
+
+const updateDocument = `
+	UPDATE users SET document = $3, document_index = $4
+	WHERE id = $1 AND document = $2
+`
+
+// EncryptDocuments encrypts the documents stored in plaintext, the ones
+// without a blind index, and encrypts again the ones whose key is not the
+// current one. It includes closed accounts and returns how many were changed.
+func (r *UserRepository) EncryptDocuments(ctx context.Context) (int, error) {
+	encrypted := 0
+	for {
+		rows, err := conn(ctx, r.db).Query(
+			ctx,
+			findStaleDocuments,
+			r.documents.CurrentPrefix(),
+			encryptionBatchSize,
+		)
+		if err != nil {
+			return encrypted, err
+		}
+
+		type staleDocument struct {
+			id       uuid.UUID
+			document string
+			index    pgtype.Text
+		}
+
+		stale, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (staleDocument, error) {
+			var d staleDocument
+			err := row.Scan(&d.id, &d.document, &d.index)
+			return d, err
+		})
+		if err != nil {
+			return encrypted, err
+		}
+
+		if len(stale) == 0 {
+			return encrypted, nil
+		}
+
+		for _, d := range stale {
+			plaintext := d.document
+			if d.index.Valid {
+				plaintext, err = r.documents.Decrypt(d.document)
+				if err != nil {
+					return encrypted, fmt.Errorf("user %s: %w", d.id, err)
+				}
+			}
+
+			ciphertext, err := r.documents.Encrypt(plaintext)
+			if err != nil {
+				return encrypted, err
+			}
+
+			_, err = conn(ctx, r.db).Exec(
+				ctx,
+				updateDocument,
+				d.id,
+				d.document,
+				ciphertext,
+				r.documents.BlindIndex(plaintext),
+			)
+			if err != nil {
+				return encrypted, err
+			}
+
+			encrypted++
+		}
+	}
+}
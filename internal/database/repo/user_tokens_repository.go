@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserTokensRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTokensRepository(db *pgxpool.Pool) *UserTokensRepository {
+	return &UserTokensRepository{
+		db,
+	}
+}
+
+const createUserToken = `
+	INSERT INTO user_tokens (
+		"user_id",
+		"purpose",
+		"token_hash",
+		"expires_at",
+		"email"
+	) VALUES ($1, $2, $3, $4, $5)
+	RETURNING "id";
+`
+
+func (r *UserTokensRepository) Create(
+	ctx context.Context,
+	token models.UserToken,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := r.db.QueryRow(
+		ctx,
+		createUserToken,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.Email,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findUserTokenByHash = "SELECT * FROM user_tokens WHERE token_hash = $1"
+
+func (r *UserTokensRepository) FindByHash(
+	ctx context.Context,
+	hash string,
+) (models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.QueryRow(ctx, findUserTokenByHash, hash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+		&token.Email,
+	)
+
+	return token, err
+}
+
+const useUserToken = `
+	UPDATE user_tokens SET used_at = NOW()
+	WHERE id = $1 AND used_at IS NULL;
+`
+
+// Use returns false if the token was already used.
+func (r *UserTokensRepository) Use(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, useUserToken, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const invalidateUserTokens = `
+	UPDATE user_tokens SET used_at = NOW()
+	WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+`
+
+// Invalidate marks every unused token of the user for the purpose as used,
+// so only the last one issued works.
+func (r *UserTokensRepository) Invalidate(
+	ctx context.Context,
+	userID uuid.UUID,
+	purpose models.TokenPurpose,
+) error {
+	_, err := r.db.Exec(ctx, invalidateUserTokens, userID, purpose)
+	return err
+}
+
+const countUserTokensSince = `
+	SELECT COUNT(*) FROM user_tokens
+	WHERE user_id = $1 AND purpose = $2 AND created_at >= $3;
+`
+
+func (r *UserTokensRepository) CountSince(
+	ctx context.Context,
+	userID uuid.UUID,
+	purpose models.TokenPurpose,
+	since time.Time,
+) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, countUserTokensSince, userID, purpose, since).Scan(&count)
+	return count, err
+}
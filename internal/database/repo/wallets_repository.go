@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WalletsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWalletsRepository(db *pgxpool.Pool) *WalletsRepository {
+	return &WalletsRepository{
+		db,
+	}
+}
+
+func scanWallet(row pgx.Row) (models.Wallet, error) {
+	var wallet models.Wallet
+	err := row.Scan(
+		&wallet.ID,
+		&wallet.UserID,
+		&wallet.Currency,
+		&wallet.Balance,
+		&wallet.CreatedAt,
+		&wallet.UpdatedAt,
+	)
+
+	return wallet, err
+}
+
+const createWallet = `
+	INSERT INTO wallets (
+		"user_id",
+		"currency"
+	) VALUES ($1, $2)
+	RETURNING "id";
+`
+
+func (r *WalletsRepository) Create(
+	ctx context.Context,
+	wallet models.Wallet,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		createWallet,
+		wallet.UserID,
+		wallet.Currency,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findWallet = "SELECT * FROM wallets WHERE user_id = $1 AND currency = $2"
+
+func (r *WalletsRepository) FindByUserAndCurrency(
+	ctx context.Context,
+	userID uuid.UUID,
+	currency string,
+) (models.Wallet, error) {
+	row := conn(ctx, r.db).QueryRow(ctx, findWallet, userID, currency)
+	return scanWallet(row)
+}
+
+const findWalletsByUser = "SELECT * FROM wallets WHERE user_id = $1 ORDER BY currency"
+
+func (r *WalletsRepository) FindByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.Wallet, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, findWalletsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wallets []models.Wallet
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		wallets = append(wallets, wallet)
+	}
+
+	return wallets, rows.Err()
+}
+
+const addWalletBalance = `
+	UPDATE wallets SET balance = balance + $2::numeric, updated_at = NOW()
+	WHERE id = $1 AND balance + $2::numeric >= 0
+	RETURNING balance
+`
+
+// AddBalance adds the amount, which may be negative, to the balance of the
+// wallet in place and returns the new balance. Returns false if the wallet
+// is not found or the balance would become negative.
+func (r *WalletsRepository) AddBalance(
+	ctx context.Context,
+	id uuid.UUID,
+	amount float64,
+) (float64, bool, error) {
+	var balance float64
+	err := conn(ctx, r.db).QueryRow(ctx, addWalletBalance, id, amount).Scan(&balance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return balance, true, nil
+}
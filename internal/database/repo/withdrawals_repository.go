@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WithdrawalsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWithdrawalsRepository(db *pgxpool.Pool) *WithdrawalsRepository {
+	return &WithdrawalsRepository{
+		db,
+	}
+}
+
+func scanWithdrawal(row pgx.Row) (models.Withdrawal, error) {
+	var withdrawal models.Withdrawal
+	err := row.Scan(
+		&withdrawal.ID,
+		&withdrawal.UserID,
+		&withdrawal.BankAccountID,
+		&withdrawal.Amount,
+		&withdrawal.Status,
+		&withdrawal.Reference,
+		&withdrawal.FailureReason,
+		&withdrawal.SettledAt,
+		&withdrawal.CreatedAt,
+		&withdrawal.UpdatedAt,
+		&withdrawal.ClaimedAt,
+	)
+
+	return withdrawal, err
+}
+
+const createWithdrawal = `
+	INSERT INTO withdrawals (
+		"user_id",
+		"bank_account_id",
+		"amount"
+	) VALUES ($1, $2, $3)
+	RETURNING "id";
+`
+
+func (r *WithdrawalsRepository) Create(
+	ctx context.Context,
+	withdrawal models.Withdrawal,
+) (uuid.UUID, error) {
+	var id uuid.UUID
+
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		createWithdrawal,
+		withdrawal.UserID,
+		withdrawal.BankAccountID,
+		withdrawal.Amount,
+	).Scan(&id)
+
+	return id, err
+}
+
+const findWithdrawalByID = "SELECT * FROM withdrawals WHERE id = $1"
+
+func (r *WithdrawalsRepository) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.Withdrawal, error) {
+	row := conn(ctx, r.db).QueryRow(ctx, findWithdrawalByID, id)
+	return scanWithdrawal(row)
+}
+
+const findWithdrawalsByStatus = `
+	SELECT * FROM withdrawals
+	WHERE status = $1
+	ORDER BY created_at
+	LIMIT $2;
+`
+
+func (r *WithdrawalsRepository) FindByStatus(
+	ctx context.Context,
+	status models.WithdrawalStatus,
+	limit int,
+) ([]models.Withdrawal, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, findWithdrawalsByStatus, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	withdrawals := make([]models.Withdrawal, 0, limit)
+	for rows.Next() {
+		withdrawal, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		withdrawals = append(withdrawals, withdrawal)
+	}
+
+	return withdrawals, rows.Err()
+}
+
+const updateWithdrawalStatus = `
+	UPDATE withdrawals SET
+		status = $3,
+		reference = $4,
+		failure_reason = $5,
+		settled_at = CASE
+			WHEN $3 IN ('COMPLETED'::"WithdrawalStatus", 'FAILED'::"WithdrawalStatus") THEN NOW()
+		END,
+		claimed_at = CASE
+			WHEN $3 = 'PROCESSING'::"WithdrawalStatus" THEN NOW()
+			WHEN $3 = 'PENDING'::"WithdrawalStatus" THEN NULL
+			ELSE claimed_at
+		END,
+		updated_at = NOW()
+	WHERE id = $1 AND status = $2;
+`
+
+// UpdateStatus moves the withdrawal to its current status only if it is
+// still in the given one, returns false if another process changed it first.
+func (r *WithdrawalsRepository) UpdateStatus(
+	ctx context.Context,
+	withdrawal models.Withdrawal,
+	from models.WithdrawalStatus,
+) (bool, error) {
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		updateWithdrawalStatus,
+		withdrawal.ID,
+		from,
+		withdrawal.Status,
+		withdrawal.Reference,
+		withdrawal.FailureReason,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const releaseStaleWithdrawals = `
+	UPDATE withdrawals SET status = 'PENDING', claimed_at = NULL, updated_at = NOW()
+	WHERE status = 'PROCESSING' AND claimed_at < $1;
+`
+
+// ReleaseStale gives the withdrawals claimed before the given time back
+// to the queue, returning how many there were.
+func (r *WithdrawalsRepository) ReleaseStale(ctx context.Context, before time.Time) (int, error) {
+	tag, err := conn(ctx, r.db).Exec(ctx, releaseStaleWithdrawals, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+const hasPendingWithdrawals = `
+	SELECT EXISTS (
+		SELECT 1 FROM withdrawals
+		WHERE user_id = $1 AND status IN ('PENDING', 'PROCESSING')
+	);
+`
+
+// HasPending reports if the user has withdrawals not settled yet.
+func (r *WithdrawalsRepository) HasPending(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var pending bool
+	err := conn(ctx, r.db).QueryRow(ctx, hasPendingWithdrawals, userID).Scan(&pending)
+	return pending, err
+}
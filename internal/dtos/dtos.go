@@ -1,9 +1,14 @@
 package dtos
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/mail"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/Rhymond/go-money"
 	"github.com/edulustosa/go-pay/helpers"
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/google/uuid"
@@ -13,10 +18,36 @@ func validLength(field string, min, max int) bool {
 	return len(field) >= min && len(field) <= max
 }
 
+func validPassword(password string) bool {
+	return validLength(password, 6, 255)
+}
+
+func validName(name string) bool {
+	return validLength(name, 3, 255)
+}
+
+func validEmail(email string) bool {
+	_, err := mail.ParseAddress(email)
+	return err == nil
+}
+
+func validCurrency(code string) bool {
+	return len(code) == 3 &&
+		strings.ToUpper(code) == code &&
+		money.GetCurrency(code) != nil
+}
+
+// TransactionDTO currencies are optional, the payer currency defaults
+// to BRL and the payee currency defaults to the payer one. The payer
+// defaults to the authenticated user, the only one allowed to pay. The
+// two-factor code is only required for high-value transfers.
 type TransactionDTO struct {
-	Value float64   `json:"value"`
-	Payer uuid.UUID `json:"payer"`
-	Payee uuid.UUID `json:"payee"`
+	Value         float64   `json:"value"`
+	Payer         uuid.UUID `json:"payer"`
+	Payee         uuid.UUID `json:"payee"`
+	Currency      string    `json:"currency,omitempty"`
+	PayeeCurrency string    `json:"payeeCurrency,omitempty"`
+	OTP           string    `json:"otp,omitempty"`
 }
 
 func (t TransactionDTO) Valid() (problems map[string]string) {
@@ -26,17 +57,441 @@ func (t TransactionDTO) Valid() (problems map[string]string) {
 		problems["amount"] = "must be greater than 0"
 	}
 
-	if t.Payer == uuid.Nil {
-		problems["payer"] = "must be a valid UUID"
-	}
-
 	if t.Payee == uuid.Nil {
 		problems["payee"] = "must be a valid UUID"
 	}
 
+	if t.Currency != "" && !validCurrency(t.Currency) {
+		problems["currency"] = "must be a valid ISO 4217 currency code"
+	}
+
+	if t.PayeeCurrency != "" && !validCurrency(t.PayeeCurrency) {
+		problems["payeeCurrency"] = "must be a valid ISO 4217 currency code"
+	}
+
+	return problems
+}
+
+type ReviewResolutionDTO struct {
+	Note string `json:"note"`
+}
+
+func (r ReviewResolutionDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if !validLength(r.Note, 0, 500) {
+		problems["note"] = "must have at most 500 characters"
+	}
+
+	return problems
+}
+
+type FraudReviewResponseDTO struct {
+	ID        uuid.UUID           `json:"id"`
+	Payer     uuid.UUID           `json:"payer"`
+	Payee     uuid.UUID           `json:"payee"`
+	Amount    float64             `json:"amount"`
+	Score     int                 `json:"score"`
+	Reasons   []string            `json:"reasons"`
+	Status    models.ReviewStatus `json:"status"`
+	CreatedAt time.Time           `json:"createdAt"`
+}
+
+type WalletDTO struct {
+	Currency string `json:"currency"`
+}
+
+func (w WalletDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if !validCurrency(w.Currency) {
+		problems["currency"] = "must be a valid ISO 4217 currency code"
+	}
+
+	return problems
+}
+
+type WalletResponseDTO struct {
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+}
+
+// CardDTO is only forwarded to the card acquirer and never stored.
+type CardDTO struct {
+	Number     string `json:"number"`
+	HolderName string `json:"holderName"`
+	ExpMonth   int    `json:"expMonth"`
+	ExpYear    int    `json:"expYear"`
+	CVV        string `json:"cvv"`
+}
+
+func (c CardDTO) problems() map[string]string {
+	problems := make(map[string]string)
+
+	if !validLength(c.Number, 12, 19) || strings.Trim(c.Number, "0123456789") != "" {
+		problems["card.number"] = "must have between 12 and 19 digits"
+	}
+
+	if !validLength(c.HolderName, 3, 255) {
+		problems["card.holderName"] = "must be between 3 and 255 characters"
+	}
+
+	if c.ExpMonth < 1 || c.ExpMonth > 12 {
+		problems["card.expMonth"] = "must be between 1 and 12"
+	}
+
+	if c.ExpYear < 2000 {
+		problems["card.expYear"] = "must be a four digit year"
+	}
+
+	if !validLength(c.CVV, 3, 4) || strings.Trim(c.CVV, "0123456789") != "" {
+		problems["card.cvv"] = "must have 3 or 4 digits"
+	}
+
+	return problems
+}
+
+// DepositDTO user defaults to the authenticated one.
+type DepositDTO struct {
+	User     uuid.UUID            `json:"user"`
+	Amount   float64              `json:"amount"`
+	Currency string               `json:"currency,omitempty"`
+	Source   models.FundingSource `json:"source"`
+	Card     *CardDTO             `json:"card,omitempty"`
+}
+
+func (d DepositDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if d.Amount <= 0 {
+		problems["amount"] = "must be greater than 0"
+	}
+
+	if d.Currency != "" && !validCurrency(d.Currency) {
+		problems["currency"] = "must be a valid ISO 4217 currency code"
+	}
+
+	switch d.Source {
+	case models.SourceBankTransfer:
+	case models.SourceCard:
+		if d.Card == nil {
+			problems["card"] = "is required for card deposits"
+			break
+		}
+
+		for field, problem := range d.Card.problems() {
+			problems[field] = problem
+		}
+	default:
+		problems["source"] = fmt.Sprintf(
+			"must be %s or %s",
+			models.SourceBankTransfer,
+			models.SourceCard,
+		)
+	}
+
+	return problems
+}
+
+type DepositResponseDTO struct {
+	ID           uuid.UUID            `json:"id"`
+	User         uuid.UUID            `json:"user"`
+	Amount       float64              `json:"amount"`
+	Currency     string               `json:"currency"`
+	Source       models.FundingSource `json:"source"`
+	Status       models.DepositStatus `json:"status"`
+	Reference    string               `json:"reference"`
+	Instructions string               `json:"instructions,omitempty"`
+	CreatedAt    time.Time            `json:"createdAt"`
+}
+
+// DepositFundingDTO is the notice of a funding source
+// that the money of a deposit was received.
+type DepositFundingDTO struct {
+	Reference string `json:"reference"`
+}
+
+func (d DepositFundingDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if d.Reference == "" {
+		problems["reference"] = "is required"
+	}
+
+	return problems
+}
+
+type BankAccountDTO struct {
+	BankCode      string             `json:"bankCode"`
+	Branch        string             `json:"branch"`
+	AccountNumber string             `json:"account"`
+	AccountType   models.AccountType `json:"accountType,omitempty"`
+}
+
+func (b BankAccountDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if err := helpers.ParseBankCode(b.BankCode); err != nil {
+		problems["bankCode"] = err.Error()
+	}
+
+	if err := helpers.ParseBranch(b.Branch); err != nil {
+		problems["branch"] = err.Error()
+	}
+
+	if err := helpers.ParseAccountNumber(b.AccountNumber); err != nil {
+		problems["account"] = err.Error()
+	}
+
+	switch b.AccountType {
+	case "", models.AccountChecking, models.AccountSavings, models.AccountPayment:
+	default:
+		problems["accountType"] = fmt.Sprintf(
+			"must be %s, %s or %s",
+			models.AccountChecking,
+			models.AccountSavings,
+			models.AccountPayment,
+		)
+	}
+
+	return problems
+}
+
+type BankAccountResponseDTO struct {
+	ID            uuid.UUID          `json:"id"`
+	BankCode      string             `json:"bankCode"`
+	Branch        string             `json:"branch"`
+	AccountNumber string             `json:"account"`
+	AccountType   models.AccountType `json:"accountType"`
+}
+
+// WithdrawalDTO user defaults to the authenticated one.
+type WithdrawalDTO struct {
+	User        uuid.UUID `json:"user"`
+	BankAccount uuid.UUID `json:"bankAccount"`
+	Amount      float64   `json:"amount"`
+}
+
+func (w WithdrawalDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if w.BankAccount == uuid.Nil {
+		problems["bankAccount"] = "must be a valid UUID"
+	}
+
+	if w.Amount <= 0 {
+		problems["amount"] = "must be greater than 0"
+	}
+
+	return problems
+}
+
+type WithdrawalResponseDTO struct {
+	ID            uuid.UUID               `json:"id"`
+	User          uuid.UUID               `json:"user"`
+	BankAccount   uuid.UUID               `json:"bankAccount"`
+	Amount        float64                 `json:"amount"`
+	Status        models.WithdrawalStatus `json:"status"`
+	Reference     string                  `json:"reference,omitempty"`
+	FailureReason string                  `json:"failureReason,omitempty"`
+	CreatedAt     time.Time               `json:"createdAt"`
+}
+
+// LoginDTO identifies the user either by the email or the document.
+type LoginDTO struct {
+	Email    string `json:"email,omitempty"`
+	Document string `json:"document,omitempty"`
+	Password string `json:"password"`
+}
+
+func (l LoginDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if (l.Email == "") == (l.Document == "") {
+		problems["email"] = "either email or document must be given"
+	}
+
+	if l.Password == "" {
+		problems["password"] = "must not be empty"
+	}
+
+	return problems
+}
+
+type OTPDTO struct {
+	Code string `json:"code"`
+}
+
+func (o OTPDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if o.Code == "" {
+		problems["code"] = "must not be empty"
+	}
+
+	return problems
+}
+
+type TOTPEnrollmentResponseDTO struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesResponseDTO struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email"`
+}
+
+func (f ForgotPasswordDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if !validEmail(f.Email) {
+		problems["email"] = fmt.Sprintf("%s is not a valid email", f.Email)
+	}
+
+	return problems
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (r ResetPasswordDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if r.Token == "" {
+		problems["token"] = "must not be empty"
+	}
+
+	if !validPassword(r.Password) {
+		problems["password"] = "must be between 6 and 255 characters"
+	}
+
+	return problems
+}
+
+type VerifyEmailDTO struct {
+	Token string `json:"token"`
+}
+
+func (v VerifyEmailDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if v.Token == "" {
+		problems["token"] = "must not be empty"
+	}
+
+	return problems
+}
+
+// ChangePasswordDTO otp is only required with two-factor authentication enabled.
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+	OTP             string `json:"otp,omitempty"`
+}
+
+func (c ChangePasswordDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if c.CurrentPassword == "" {
+		problems["currentPassword"] = "must not be empty"
+	}
+
+	if !validPassword(c.NewPassword) {
+		problems["newPassword"] = "must be between 6 and 255 characters"
+	}
+
+	return problems
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (r RefreshTokenDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if r.RefreshToken == "" {
+		problems["refreshToken"] = "must not be empty"
+	}
+
+	return problems
+}
+
+type AccessTokenResponseDTO struct {
+	AccessToken      string `json:"accessToken"`
+	TokenType        string `json:"tokenType"`
+	ExpiresIn        int    `json:"expiresIn"`
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresIn int    `json:"refreshExpiresIn"`
+}
+
+type SessionResponseDTO struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// APIKeyDTO otp is a fresh two-factor code, required to create keys.
+type APIKeyDTO struct {
+	Name   string         `json:"name"`
+	Scopes []models.Scope `json:"scopes"`
+	OTP    string         `json:"otp"`
+}
+
+func (a APIKeyDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if !validLength(a.Name, 1, 100) {
+		problems["name"] = "must have between 1 and 100 characters"
+	}
+
+	if len(a.Scopes) == 0 {
+		problems["scopes"] = "must have at least one scope"
+	}
+
+	for _, scope := range a.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			problems["scopes"] = fmt.Sprintf("unknown scope %q", scope)
+			break
+		}
+	}
+
 	return problems
 }
 
+// APIKeyResponseDTO key is only present when the key is created or rotated.
+type APIKeyResponseDTO struct {
+	ID         uuid.UUID      `json:"id"`
+	Name       string         `json:"name"`
+	Prefix     string         `json:"prefix"`
+	Key        string         `json:"key,omitempty"`
+	Scopes     []models.Scope `json:"scopes"`
+	LastUsedAt *time.Time     `json:"lastUsedAt"`
+	RevokedAt  *time.Time     `json:"revokedAt"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+type TransactionResponseDTO struct {
+	ID            uuid.UUID `json:"id"`
+	Payer         uuid.UUID `json:"payer"`
+	Payee         uuid.UUID `json:"payee"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	PayeeAmount   float64   `json:"payeeAmount"`
+	PayeeCurrency string    `json:"payeeCurrency"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
 type NotificationDTO struct {
 	Email   string `json:"email"`
 	Message string `json:"message"`
@@ -48,47 +503,256 @@ type UserDTO struct {
 	Document  string      `json:"document"`
 	Email     string      `json:"email"`
 	Password  string      `json:"password"`
-	Balance   float64     `json:"balance"`
 	Role      models.Role `json:"role,omitempty"`
 }
 
 func (u UserDTO) Valid() (problems map[string]string) {
 	problems = make(map[string]string)
 
-	if !validLength(u.FirstName, 3, 255) {
+	if !validName(u.FirstName) {
 		problems["firstName"] = "must be between 3 and 255 characters"
 	}
 
-	if !validLength(u.LastName, 3, 255) {
+	if !validName(u.LastName) {
 		problems["lastName"] = "must be between 3 and 255 characters"
 	}
 
-	if err := helpers.ParseDocument(u.Document); err != nil {
-		problems["document"] = err.Error()
+	// Individuals are identified by a CPF and companies by a CNPJ
+	switch u.Role {
+	case "", models.RoleCommon:
+		if err := helpers.ParseCPF(u.Document); err != nil {
+			problems["document"] = fmt.Sprintf("common users must have a valid CPF: %v", err)
+		}
+	case models.RoleMerchant:
+		if err := helpers.ParseCNPJ(u.Document); err != nil {
+			problems["document"] = fmt.Sprintf("merchants must have a valid CNPJ: %v", err)
+		}
+	default:
+		problems["role"] = fmt.Sprintf("must be %s or %s", models.RoleCommon, models.RoleMerchant)
 	}
 
-	_, err := mail.ParseAddress(u.Email)
-	if err != nil {
+	if !validEmail(u.Email) {
 		problems["email"] = fmt.Sprintf("%s is not a valid email", u.Email)
 	}
 
-	if !validLength(u.Password, 6, 255) {
+	if !validPassword(u.Password) {
 		problems["password"] = "must be between 6 and 255 characters"
 	}
 
-	if u.Balance < 0 {
-		problems["balance"] = "must be greater than or equal to 0"
+	return problems
+}
+
+// UpdateUserDTO only the fields present are updated, with the same rules
+// of the user creation. Changing the email requires verifying it again.
+type UpdateUserDTO struct {
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Email     *string `json:"email,omitempty"`
+}
+
+func (u UpdateUserDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if u.FirstName == nil && u.LastName == nil && u.Email == nil {
+		problems["body"] = "must have at least one of firstName, lastName or email"
+	}
+
+	if u.FirstName != nil && !validName(*u.FirstName) {
+		problems["firstName"] = "must be between 3 and 255 characters"
+	}
+
+	if u.LastName != nil && !validName(*u.LastName) {
+		problems["lastName"] = "must be between 3 and 255 characters"
+	}
+
+	if u.Email != nil && !validEmail(*u.Email) {
+		problems["email"] = fmt.Sprintf("%s is not a valid email", *u.Email)
 	}
 
 	return problems
 }
 
 type UserResponseDTO struct {
-	ID        uuid.UUID   `json:"id"`
-	FirstName string      `json:"firstName"`
-	LastName  string      `json:"lastName"`
-	Document  string      `json:"document"`
-	Email     string      `json:"email"`
-	Balance   float64     `json:"balance"`
-	Role      models.Role `json:"role"`
+	ID            uuid.UUID            `json:"id"`
+	FirstName     string               `json:"firstName"`
+	LastName      string               `json:"lastName"`
+	Document      string               `json:"document"`
+	DocumentType  models.DocumentType  `json:"documentType"`
+	Email         string               `json:"email"`
+	Balance       float64              `json:"balance"`
+	Role          models.Role          `json:"role"`
+	EmailVerified bool                 `json:"emailVerified"`
+	Status        models.AccountStatus `json:"status"`
+}
+
+// PaginationDTO describes either a page of an offset listing, with
+// its number and the count of pages, or a page of a cursor listing,
+// with the cursors of the pages after and before it.
+type PaginationDTO struct {
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"pageSize"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"totalPages,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+}
+
+type UserPageResponseDTO struct {
+	Users      []UserResponseDTO `json:"users"`
+	Pagination PaginationDTO     `json:"pagination"`
+}
+
+type TransactionPageResponseDTO struct {
+	Transactions []TransactionResponseDTO `json:"transactions"`
+	Pagination   PaginationDTO            `json:"pagination"`
+}
+
+type FreezeUserDTO struct {
+	Reason string `json:"reason"`
+}
+
+func (f FreezeUserDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	if !validLength(f.Reason, 3, 255) {
+		problems["reason"] = "must be between 3 and 255 characters"
+	}
+
+	return problems
+}
+
+type KYCAddressDTO struct {
+	Line       string `json:"line"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postalCode"`
+}
+
+// Largest document image accepted, in bytes
+const maxKYCDocumentSize = 10 << 20
+
+var kycContentTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+// KYCSubmissionDTO the intermediate level requires the birth date, in
+// the YYYY-MM-DD format, and the address. The full level requires the
+// front and back of an ID and a selfie, already uploaded to the storage.
+type KYCSubmissionDTO struct {
+	Level     models.KYCLevel      `json:"level"`
+	BirthDate string               `json:"birthDate,omitempty"`
+	Address   *KYCAddressDTO       `json:"address,omitempty"`
+	Documents []models.KYCDocument `json:"documents,omitempty"`
+}
+
+func (k KYCSubmissionDTO) Valid() (problems map[string]string) {
+	problems = make(map[string]string)
+
+	switch k.Level {
+	case models.KYCIntermediate:
+		if _, err := time.Parse(time.DateOnly, k.BirthDate); err != nil {
+			problems["birthDate"] = "must be a date in the YYYY-MM-DD format"
+		}
+
+		if k.Address == nil {
+			problems["address"] = "must not be empty"
+			break
+		}
+
+		if !validLength(k.Address.Line, 3, 255) {
+			problems["address.line"] = "must be between 3 and 255 characters"
+		}
+
+		if !validLength(k.Address.City, 2, 255) {
+			problems["address.city"] = "must be between 2 and 255 characters"
+		}
+
+		if len(k.Address.State) != 2 || strings.ToUpper(k.Address.State) != k.Address.State {
+			problems["address.state"] = "must be the 2 upper case letters of the state"
+		}
+
+		postalCode := strings.ReplaceAll(k.Address.PostalCode, "-", "")
+		if len(postalCode) != 8 || strings.Trim(postalCode, "0123456789") != "" {
+			problems["address.postalCode"] = "must have 8 digits"
+		}
+	case models.KYCFull:
+		kinds := make([]models.KYCDocumentKind, 0, len(k.Documents))
+		for i, document := range k.Documents {
+			field := fmt.Sprintf("documents[%d]", i)
+
+			if document.StorageKey == "" {
+				problems[field+".storageKey"] = "must not be empty"
+			}
+
+			if !slices.Contains(kycContentTypes, document.ContentType) {
+				problems[field+".contentType"] = fmt.Sprintf("must be one of %v", kycContentTypes)
+			}
+
+			if document.Size <= 0 || document.Size > maxKYCDocumentSize {
+				problems[field+".size"] = "must be between 1 byte and 10 MB"
+			}
+
+			if len(document.SHA256) != 64 || strings.Trim(document.SHA256, "0123456789abcdef") != "" {
+				problems[field+".sha256"] = "must be the hex encoded SHA-256 of the image"
+			}
+
+			kinds = append(kinds, document.Kind)
+		}
+
+		for _, kind := range []models.KYCDocumentKind{
+			models.DocumentIDFront,
+			models.DocumentIDBack,
+			models.DocumentSelfie,
+		} {
+			if !slices.Contains(kinds, kind) {
+				problems["documents"] = fmt.Sprintf("must have the %s document", kind)
+			}
+		}
+	default:
+		problems["level"] = fmt.Sprintf(
+			"must be %s or %s",
+			models.KYCIntermediate,
+			models.KYCFull,
+		)
+	}
+
+	return problems
+}
+
+// KYCLimitsResponseDTO max transfer is in BRL, zero means no limit.
+type KYCLimitsResponseDTO struct {
+	MaxTransfer float64 `json:"maxTransfer"`
+	Withdrawals bool    `json:"withdrawals"`
+}
+
+// KYCSubmissionResponseDTO the submitted data is only
+// present for the staff reviewing the submission.
+type KYCSubmissionResponseDTO struct {
+	ID         uuid.UUID            `json:"id"`
+	User       uuid.UUID            `json:"user"`
+	Level      models.KYCLevel      `json:"level"`
+	Status     models.ReviewStatus  `json:"status"`
+	ReviewNote string               `json:"reviewNote"`
+	BirthDate  string               `json:"birthDate,omitempty"`
+	Address    *KYCAddressDTO       `json:"address,omitempty"`
+	Documents  []models.KYCDocument `json:"documents,omitempty"`
+	ReviewedAt *time.Time           `json:"reviewedAt"`
+	CreatedAt  time.Time            `json:"createdAt"`
+}
+
+type KYCStatusResponseDTO struct {
+	Level      models.KYCLevel           `json:"level"`
+	Limits     KYCLimitsResponseDTO      `json:"limits"`
+	Submission *KYCSubmissionResponseDTO `json:"submission"`
+}
+
+// AuditEventResponseDTO the actor is null for anonymous requests
+type AuditEventResponseDTO struct {
+	ID        uuid.UUID          `json:"id"`
+	Actor     *uuid.UUID         `json:"actor"`
+	Action    models.AuditAction `json:"action"`
+	Target    uuid.UUID          `json:"target"`
+	Before    json.RawMessage    `json:"before"`
+	After     json.RawMessage    `json:"after"`
+	RequestID string             `json:"requestId"`
+	SourceIP  string             `json:"sourceIp"`
+	CreatedAt time.Time          `json:"createdAt"`
 }
@@ -0,0 +1,117 @@
+package dtos
+
+import (
+	"log/slog"
+
+	"github.com/edulustosa/go-pay/helpers"
+)
+
+// The requests below implement slog.LogValuer so they can be logged
+// as a whole without leaking passwords, tokens, documents or emails.
+
+func (u UserDTO) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("firstName", u.FirstName),
+		slog.String("lastName", u.LastName),
+		slog.String("document", helpers.MaskDocument(u.Document)),
+		slog.String("email", helpers.MaskEmail(u.Email)),
+		slog.String("password", helpers.Redacted),
+		slog.String("role", string(u.Role)),
+	)
+}
+
+func (u UpdateUserDTO) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 3)
+	if u.FirstName != nil {
+		attrs = append(attrs, slog.String("firstName", *u.FirstName))
+	}
+
+	if u.LastName != nil {
+		attrs = append(attrs, slog.String("lastName", *u.LastName))
+	}
+
+	if u.Email != nil {
+		attrs = append(attrs, slog.String("email", helpers.MaskEmail(*u.Email)))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+func (t TransactionDTO) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Float64("value", t.Value),
+		slog.String("payer", t.Payer.String()),
+		slog.String("payee", t.Payee.String()),
+		slog.String("currency", t.Currency),
+		slog.String("payeeCurrency", t.PayeeCurrency),
+		slog.String("otp", helpers.Redacted),
+	)
+}
+
+func (c CardDTO) LogValue() slog.Value {
+	last4 := helpers.Redacted
+	if len(c.Number) > 4 {
+		last4 = c.Number[len(c.Number)-4:]
+	}
+
+	return slog.GroupValue(
+		slog.String("last4", last4),
+		slog.String("cvv", helpers.Redacted),
+	)
+}
+
+func (d DepositDTO) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("user", d.User.String()),
+		slog.Float64("amount", d.Amount),
+		slog.String("currency", d.Currency),
+		slog.String("source", string(d.Source)),
+	}
+
+	if d.Card != nil {
+		attrs = append(attrs, slog.Any("card", *d.Card))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+func (b BankAccountDTO) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("bankCode", b.BankCode),
+		slog.String("branch", b.Branch),
+		slog.String("account", helpers.MaskDocument(b.AccountNumber)),
+		slog.String("accountType", string(b.AccountType)),
+	)
+}
+
+func (l LoginDTO) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("email", helpers.MaskEmail(l.Email)),
+		slog.String("document", helpers.MaskDocument(l.Document)),
+		slog.String("password", helpers.Redacted),
+	)
+}
+
+func (f ForgotPasswordDTO) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("email", helpers.MaskEmail(f.Email)))
+}
+
+func (r ResetPasswordDTO) LogValue() slog.Value {
+	return slog.StringValue(helpers.Redacted)
+}
+
+func (c ChangePasswordDTO) LogValue() slog.Value {
+	return slog.StringValue(helpers.Redacted)
+}
+
+func (v VerifyEmailDTO) LogValue() slog.Value {
+	return slog.StringValue(helpers.Redacted)
+}
+
+func (r RefreshTokenDTO) LogValue() slog.Value {
+	return slog.StringValue(helpers.Redacted)
+}
+
+func (o OTPDTO) LogValue() slog.Value {
+	return slog.StringValue(helpers.Redacted)
+}
@@ -0,0 +1,42 @@
+package dtos_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/edulustosa/go-pay/internal/dtos"
+)
+
+func TestLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	logger.Error(
+		"failed",
+		"user", dtos.UserDTO{
+			FirstName: "John",
+			LastName:  "Doe",
+			Document:  "529.982.247-25",
+			Email:     "johndoe@email.com",
+			Password:  "secret123",
+		},
+		"deposit", dtos.DepositDTO{
+			Amount: 100,
+			Card:   &dtos.CardDTO{Number: "4111111111111111", CVV: "123"},
+		},
+		"transfer", dtos.TransactionDTO{Value: 10, OTP: "654321"},
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"secret123", "52998224725", "johndoe@", "4111111111111111", `"123"`, "654321"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %q to be redacted, got %s", secret, out)
+		}
+	}
+
+	if !strings.Contains(out, "*******4725") || !strings.Contains(out, `"last4":"1111"`) {
+		t.Errorf("expected masked values in the log, got %s", out)
+	}
+}
@@ -1,20 +1,299 @@
 package factories
 
 import (
+	"github.com/edulustosa/go-pay/internal/config"
+	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/account"
+	"github.com/edulustosa/go-pay/internal/services/apikey"
+	"github.com/edulustosa/go-pay/internal/services/audit"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/edulustosa/go-pay/internal/services/cursor"
+	"github.com/edulustosa/go-pay/internal/services/deposit"
+	"github.com/edulustosa/go-pay/internal/services/fraud"
+	"github.com/edulustosa/go-pay/internal/services/fx"
+	"github.com/edulustosa/go-pay/internal/services/keyring"
+	"github.com/edulustosa/go-pay/internal/services/kyc"
+	"github.com/edulustosa/go-pay/internal/services/mfa"
+	"github.com/edulustosa/go-pay/internal/services/notification"
+	"github.com/edulustosa/go-pay/internal/services/reconciliation"
+	"github.com/edulustosa/go-pay/internal/services/statement"
 	"github.com/edulustosa/go-pay/internal/services/transfer"
 	"github.com/edulustosa/go-pay/internal/services/user"
+	"github.com/edulustosa/go-pay/internal/services/usertoken"
+	"github.com/edulustosa/go-pay/internal/services/verification"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/edulustosa/go-pay/internal/services/withdrawal"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func MakeTransferService(pool *pgxpool.Pool) *transfer.Service {
-	transactionRepository := repo.NewTransactionsRepository(pool)
-	usersRepository := repo.NewUserRepository(pool)
-	userService := user.NewService(usersRepository)
+// Factory makes the services with the dependencies loaded at startup,
+// the handlers and the commands make theirs from the same one.
+type Factory struct {
+	pool     *pgxpool.Pool
+	settings *config.Config
+
+	// Encrypts the documents of the users
+	documents *keyring.Keyring
+
+	// Signs the pagination cursors
+	cursors *cursor.Signer
+}
+
+func New(
+	pool *pgxpool.Pool,
+	settings *config.Config,
+	documents *keyring.Keyring,
+	cursors *cursor.Signer,
+) *Factory {
+	return &Factory{
+		pool,
+		settings,
+		documents,
+		cursors,
+	}
+}
+
+func (f *Factory) Config() *config.Config {
+	return f.settings
+}
+
+func (f *Factory) MakeCursorSigner() *cursor.Signer {
+	return f.cursors
+}
+
+func (f *Factory) MakeTransactor() *repo.Transactor {
+	return repo.NewTransactor(f.pool)
+}
+
+func (f *Factory) MakeUserRepository() *repo.UserRepository {
+	return repo.NewUserRepository(f.pool, f.documents)
+}
+
+func (f *Factory) MakeUserService() *user.Service {
+	userService := user.NewService(f.MakeUserRepository(), user.Config{
+		DefaultPageSize: f.settings.Pagination.PageSize,
+		MaxPageSize:     f.settings.Pagination.MaxPageSize,
+	})
+
+	return userService
+}
+
+func (f *Factory) MakeNotifier() *notification.Client {
+	return notification.NewClient(f.settings.External.NotificationURL, f.settings.External.Timeout)
+}
+
+// FraudConfig maps the thresholds of the configuration to the rules
+func (f *Factory) FraudConfig() fraud.Config {
+	rules := f.settings.Fraud
+
+	return fraud.Config{
+		ReviewScore: rules.ReviewScore,
+		DenyScore:   rules.DenyScore,
+		Velocity: fraud.VelocityRule{
+			MaxTransfers: rules.VelocityMaxTransfers,
+			Window:       rules.VelocityWindow,
+			Score:        rules.VelocityScore,
+		},
+		NewPayee: fraud.NewPayeeRule{
+			MinAmount: rules.NewPayeeMinAmount,
+			Score:     rules.NewPayeeScore,
+		},
+		NewAccount: fraud.NewAccountRule{
+			MaxAge:    rules.NewAccountMaxAge,
+			MinAmount: rules.NewAccountMinAmount,
+			Score:     rules.NewAccountScore,
+		},
+		RoundTrip: fraud.RoundTripRule{
+			Window: rules.RoundTripWindow,
+			Score:  rules.RoundTripScore,
+		},
+	}
+}
+
+func (f *Factory) MakeFraudService() *fraud.Service {
+	reviewsRepository := repo.NewFraudReviewsRepository(f.pool, f.settings.Pagination.PageSize)
+	transactionRepository := repo.NewTransactionsRepository(f.pool)
+	fraudService := fraud.NewService(
+		reviewsRepository,
+		transactionRepository,
+		f.FraudConfig(),
+	)
+
+	return fraudService
+}
+
+func (f *Factory) MakeWalletService() *wallet.Service {
+	walletsRepository := repo.NewWalletsRepository(f.pool)
+	usersRepository := f.MakeUserRepository()
+	walletService := wallet.NewService(walletsRepository, usersRepository)
+
+	return walletService
+}
+
+func (f *Factory) MakeExchangeService() *fx.Service {
+	provider := fx.NewFileProvider(f.settings.External.FXRatesFile)
+	return fx.NewService(provider, f.settings.Transfers.ExchangeSpread)
+}
+
+func (f *Factory) MakeTransferService() *transfer.Service {
+	transactionRepository := repo.NewTransactionsRepository(f.pool)
+	userService := f.MakeUserService()
 	transferService := transfer.NewService(
 		transactionRepository,
+		f.MakeTransactor(),
 		userService,
+		f.MakeWalletService(),
+		f.MakeExchangeService(),
+		transfer.NewExternalAuthorizer(f.settings.External.AuthorizerURL, f.settings.External.Timeout),
+		f.MakeFraudService(),
+		f.MakeMFAService(),
+		f.MakeNotifier(),
 	)
 
 	return transferService
 }
+
+func (f *Factory) MakeDepositService() *deposit.Service {
+	depositsRepository := repo.NewDepositsRepository(f.pool)
+	userService := f.MakeUserService()
+	depositService := deposit.NewService(
+		depositsRepository,
+		f.MakeTransactor(),
+		userService,
+		f.MakeWalletService(),
+		map[models.FundingSource]deposit.FundingProvider{
+			models.SourceBankTransfer: deposit.SimulatedBankTransfer{},
+			models.SourceCard:         deposit.SimulatedCard{},
+		},
+	)
+
+	return depositService
+}
+
+func (f *Factory) MakeWithdrawalService() *withdrawal.Service {
+	withdrawalsRepository := repo.NewWithdrawalsRepository(f.pool)
+	bankAccountsRepository := repo.NewBankAccountsRepository(f.pool)
+	userService := f.MakeUserService()
+	withdrawalService := withdrawal.NewService(
+		withdrawalsRepository,
+		f.MakeTransactor(),
+		bankAccountsRepository,
+		userService,
+		f.MakeWalletService(),
+		withdrawal.FakePayout{},
+		f.MakeAuditService(),
+		f.settings.Jobs.WithdrawalClaimTimeout,
+	)
+
+	return withdrawalService
+}
+
+func (f *Factory) MakeAuthService(tokens *auth.TokenManager) *auth.Service {
+	usersRepository := f.MakeUserRepository()
+	sessionsRepository := repo.NewSessionsRepository(f.pool)
+	authService := auth.NewService(
+		usersRepository,
+		sessionsRepository,
+		f.MakeUserTokenService(),
+		tokens,
+		f.MakeNotifier(),
+		f.MakeMFAService(),
+	)
+
+	return authService
+}
+
+func (f *Factory) MakeAPIKeyService() *apikey.Service {
+	apiKeysRepository := repo.NewAPIKeysRepository(f.pool)
+	userService := f.MakeUserService()
+	apiKeyService := apikey.NewService(
+		apiKeysRepository,
+		userService,
+		f.MakeMFAService(),
+	)
+
+	return apiKeyService
+}
+
+func (f *Factory) MakeMFAService() *mfa.Service {
+	factorsRepository := repo.NewTOTPFactorsRepository(f.pool)
+	userService := f.MakeUserService()
+	mfaService := mfa.NewService(factorsRepository, userService, mfa.Config{
+		TransferThreshold: f.settings.Transfers.StepUpAmount,
+		MaxAttempts:       f.settings.Auth.MFAMaxAttempts,
+		Lockout:           f.settings.Auth.MFALockout,
+	})
+
+	return mfaService
+}
+
+func (f *Factory) MakeUserTokenService() *usertoken.Service {
+	userTokensRepository := repo.NewUserTokensRepository(f.pool)
+	return usertoken.NewService(userTokensRepository)
+}
+
+func (f *Factory) MakeVerificationService() *verification.Service {
+	usersRepository := f.MakeUserRepository()
+	verificationService := verification.NewService(
+		usersRepository,
+		f.MakeUserTokenService(),
+		f.MakeNotifier(),
+	)
+
+	return verificationService
+}
+
+func (f *Factory) MakeAccountService() *account.Service {
+	accountService := account.NewService(
+		f.MakeUserRepository(),
+		repo.NewWalletsRepository(f.pool),
+		repo.NewSessionsRepository(f.pool),
+		repo.NewAPIKeysRepository(f.pool),
+		repo.NewDepositsRepository(f.pool),
+		repo.NewWithdrawalsRepository(f.pool),
+		repo.NewFraudReviewsRepository(f.pool, f.settings.Pagination.PageSize),
+	)
+
+	return accountService
+}
+
+func (f *Factory) MakeKYCService() *kyc.Service {
+	submissionsRepository := repo.NewKYCSubmissionsRepository(f.pool, f.settings.Pagination.PageSize)
+	usersRepository := f.MakeUserRepository()
+	kycService := kyc.NewService(submissionsRepository, usersRepository)
+
+	return kycService
+}
+
+func (f *Factory) MakeAuditService() *audit.Service {
+	eventsRepository := repo.NewAuditEventsRepository(f.pool, f.settings.Pagination.PageSize)
+	return audit.NewService(eventsRepository)
+}
+
+func (f *Factory) MakeStatementService() *statement.Service {
+	statementsRepository := repo.NewStatementsRepository(f.pool)
+	userService := f.MakeUserService()
+
+	return statement.NewService(statementsRepository, userService)
+}
+
+// ReconciliationConfig of the background job and of the reconcile command
+func (f *Factory) ReconciliationConfig() reconciliation.Config {
+	return reconciliation.Config{
+		Freeze:  f.settings.Jobs.ReconciliationFreeze,
+		Recheck: f.settings.Jobs.ReconciliationRecheck,
+	}
+}
+
+func (f *Factory) MakeReconciliationService(config reconciliation.Config) *reconciliation.Service {
+	reconciliationRepository := repo.NewReconciliationRepository(f.pool)
+	reconciliationService := reconciliation.NewService(
+		reconciliationRepository,
+		f.MakeAccountService(),
+		f.MakeAuditService(),
+		config,
+	)
+
+	return reconciliationService
+}
@@ -0,0 +1,167 @@
+package account
+
+import (
+	"context"
+	"errors"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+type userRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+	Close(ctx context.Context, id uuid.UUID) (bool, error)
+	Freeze(ctx context.Context, id uuid.UUID, reason string, frozenBy uuid.UUID) (bool, error)
+	Unfreeze(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type walletsRepository interface {
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]models.Wallet, error)
+}
+
+// holdsRepository is implemented by the repositories of the operations
+// that move funds of an user after they are requested.
+type holdsRepository interface {
+	HasPending(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+type sessionsRepository interface {
+	RevokeByUser(ctx context.Context, userID, keep uuid.UUID) (int, error)
+}
+
+type apiKeysRepository interface {
+	RevokeByUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// Service manages the status of the accounts of users. Closed accounts
+// are soft deleted so their transactions are kept for the other parties
+// and for audits.
+type Service struct {
+	users    userRepository
+	wallets  walletsRepository
+	sessions sessionsRepository
+	apiKeys  apiKeysRepository
+	holds    []holdsRepository
+}
+
+func NewService(
+	users userRepository,
+	wallets walletsRepository,
+	sessions sessionsRepository,
+	apiKeys apiKeysRepository,
+	holds ...holdsRepository,
+) *Service {
+	return &Service{
+		users,
+		wallets,
+		sessions,
+		apiKeys,
+		holds,
+	}
+}
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrBalanceNotZero = errors.New("account balance is not zero")
+	ErrPendingHolds   = errors.New("account has pending operations")
+	ErrAccountFrozen  = errors.New("account frozen")
+	ErrNotFrozen      = errors.New("account not frozen")
+)
+
+// Close requires the balance of the user and of its wallets to be zero
+// and no deposit, withdrawal or held transfer to be pending. The
+// sessions and API keys of the user are revoked.
+func (s *Service) Close(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if user.Status == models.StatusFrozen {
+		return ErrAccountFrozen
+	}
+
+	if user.Balance != 0 {
+		return ErrBalanceNotZero
+	}
+
+	wallets, err := s.wallets.FindByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, wallet := range wallets {
+		if wallet.Balance != 0 {
+			return ErrBalanceNotZero
+		}
+	}
+
+	for _, holds := range s.holds {
+		pending, err := holds.HasPending(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if pending {
+			return ErrPendingHolds
+		}
+	}
+
+	// The balance is checked again as it may be credited meanwhile
+	closed, err := s.users.Close(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !closed {
+		return ErrBalanceNotZero
+	}
+
+	if _, err := s.sessions.RevokeByUser(ctx, userID, uuid.Nil); err != nil {
+		return err
+	}
+
+	return s.apiKeys.RevokeByUser(ctx, userID)
+}
+
+// Freeze stops the account from sending or receiving money until it is
+// unfrozen, its sessions are revoked to log out whoever is using it.
+func (s *Service) Freeze(
+	ctx context.Context,
+	userID uuid.UUID,
+	reason string,
+	frozenBy uuid.UUID,
+) error {
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	frozen, err := s.users.Freeze(ctx, userID, reason, frozenBy)
+	if err != nil {
+		return err
+	}
+
+	if !frozen {
+		return ErrAccountFrozen
+	}
+
+	_, err = s.sessions.RevokeByUser(ctx, userID, uuid.Nil)
+	return err
+}
+
+func (s *Service) Unfreeze(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		return ErrUserNotFound
+	}
+
+	unfrozen, err := s.users.Unfreeze(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !unfrozen {
+		return ErrNotFrozen
+	}
+
+	return nil
+}
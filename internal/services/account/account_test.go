@@ -0,0 +1,151 @@
+package account_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/account"
+	"github.com/google/uuid"
+)
+
+func TestAccountService_Close(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	walletsRepository := &repo.InMemoryWalletsRepository{}
+	sessionsRepository := &repo.InMemorySessionsRepository{}
+	apiKeysRepository := &repo.InMemoryAPIKeysRepository{}
+	withdrawalsRepository := &repo.InMemoryWithdrawalsRepository{}
+	sut := account.NewService(
+		userRepository,
+		walletsRepository,
+		sessionsRepository,
+		apiKeysRepository,
+		&repo.InMemoryDepositsRepository{},
+		withdrawalsRepository,
+		&repo.InMemoryFraudReviewsRepository{},
+	)
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+		Balance:   100,
+	})
+	walletsRepository.Create(ctx, models.Wallet{UserID: userID, Currency: "USD", Balance: 10})
+	withdrawalsRepository.Create(ctx, models.Withdrawal{
+		UserID: userID,
+		Amount: 50,
+		Status: models.WithdrawalProcessing,
+	})
+	sessionsRepository.Create(ctx, models.Session{ID: uuid.New(), UserID: userID})
+	apiKeysRepository.Create(ctx, models.APIKey{UserID: userID, Name: "shop"})
+
+	t.Run("should not close accounts with funds", func(t *testing.T) {
+		if err := sut.Close(ctx, userID); err != account.ErrBalanceNotZero {
+			t.Errorf("expected %v, got %v", account.ErrBalanceNotZero, err)
+		}
+
+		userRepository.Users[0].Balance = 0
+		if err := sut.Close(ctx, userID); err != account.ErrBalanceNotZero {
+			t.Errorf("expected wallet balance to count, got %v", err)
+		}
+		walletsRepository.Wallets[0].Balance = 0
+	})
+
+	t.Run("should not close accounts with pending withdrawals", func(t *testing.T) {
+		if err := sut.Close(ctx, userID); err != account.ErrPendingHolds {
+			t.Errorf("expected %v, got %v", account.ErrPendingHolds, err)
+		}
+		withdrawalsRepository.Withdrawals[0].Status = models.WithdrawalCompleted
+	})
+
+	t.Run("should soft delete the user and revoke its credentials", func(t *testing.T) {
+		if err := sut.Close(ctx, userID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := userRepository.FindByID(ctx, userID); err == nil {
+			t.Errorf("expected closed user to not be found")
+		}
+
+		if len(userRepository.Users) != 1 || !userRepository.Users[0].DeletedAt.Valid {
+			t.Errorf("expected user to be kept as deleted, got %+v", userRepository.Users)
+		}
+
+		if sessions, _ := sessionsRepository.FindActiveByUser(ctx, userID); len(sessions) != 0 {
+			t.Errorf("expected sessions to be revoked, got %d", len(sessions))
+		}
+
+		if !apiKeysRepository.Keys[0].RevokedAt.Valid {
+			t.Errorf("expected api keys to be revoked")
+		}
+
+		if err := sut.Close(ctx, userID); err != account.ErrUserNotFound {
+			t.Errorf("expected %v, got %v", account.ErrUserNotFound, err)
+		}
+	})
+}
+
+func TestAccountService_Freeze(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	sessionsRepository := &repo.InMemorySessionsRepository{}
+	sut := account.NewService(
+		userRepository,
+		&repo.InMemoryWalletsRepository{},
+		sessionsRepository,
+		&repo.InMemoryAPIKeysRepository{},
+	)
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+	})
+	sessionsRepository.Create(ctx, models.Session{ID: uuid.New(), UserID: userID})
+	adminID := uuid.New()
+
+	t.Run("should freeze the account and log it out", func(t *testing.T) {
+		if err := sut.Freeze(ctx, userID, "compromised", adminID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		frozen, _ := userRepository.FindByID(ctx, userID)
+		if frozen.Status != models.StatusFrozen ||
+			frozen.FrozenReason != "compromised" ||
+			frozen.FrozenBy.Bytes != adminID {
+			t.Errorf("expected account frozen by %v, got %+v", adminID, frozen)
+		}
+
+		if sessions, _ := sessionsRepository.FindActiveByUser(ctx, userID); len(sessions) != 0 {
+			t.Errorf("expected sessions to be revoked, got %d", len(sessions))
+		}
+
+		if err := sut.Freeze(ctx, userID, "compromised", adminID); err != account.ErrAccountFrozen {
+			t.Errorf("expected %v, got %v", account.ErrAccountFrozen, err)
+		}
+
+		if err := sut.Close(ctx, userID); err != account.ErrAccountFrozen {
+			t.Errorf("expected %v, got %v", account.ErrAccountFrozen, err)
+		}
+	})
+
+	t.Run("should unfreeze the account", func(t *testing.T) {
+		if err := sut.Unfreeze(ctx, userID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		unfrozen, _ := userRepository.FindByID(ctx, userID)
+		if unfrozen.Status != models.StatusActive || unfrozen.FrozenReason != "" {
+			t.Errorf("expected active account, got %+v", unfrozen)
+		}
+
+		if err := sut.Unfreeze(ctx, userID); err != account.ErrNotFrozen {
+			t.Errorf("expected %v, got %v", account.ErrNotFrozen, err)
+		}
+	})
+}
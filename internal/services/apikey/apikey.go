@@ -0,0 +1,240 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/google/uuid"
+)
+
+type apiKeysRepository interface {
+	Create(ctx context.Context, key models.APIKey) (uuid.UUID, error)
+	FindByID(ctx context.Context, id uuid.UUID) (models.APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) (bool, error)
+	Touch(ctx context.Context, id uuid.UUID) error
+}
+
+type userService interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+}
+
+type stepUpService interface {
+	StepUp(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+type Service struct {
+	repo   apiKeysRepository
+	user   userService
+	stepUp stepUpService
+}
+
+func NewService(repo apiKeysRepository, user userService, stepUp stepUpService) *Service {
+	return &Service{
+		repo,
+		user,
+		stepUp,
+	}
+}
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrNotMerchant    = errors.New("only merchants can manage api keys")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyRevoked  = errors.New("api key already revoked")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+)
+
+// Every key starts with KeyPrefix, so it can be told apart from access
+// tokens and found by secret scanners, followed by the key identifier
+// and the secret: gpk_<id>_<secret>.
+const KeyPrefix = "gpk_"
+
+const (
+	idBytes     = 4
+	secretBytes = 32
+)
+
+// Key is a created API key along with its secret, which is only
+// known at creation since just its hash is stored.
+type Key struct {
+	models.APIKey
+	Secret string
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, KeyPrefix)
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Service) Create(
+	ctx context.Context,
+	userID uuid.UUID,
+	keyDTO dtos.APIKeyDTO,
+) (Key, error) {
+	user, err := s.user.FindByID(ctx, userID)
+	if err != nil {
+		return Key{}, ErrUserNotFound
+	}
+
+	if user.Role != models.RoleMerchant {
+		return Key{}, ErrNotMerchant
+	}
+
+	if err := s.stepUp.StepUp(ctx, userID, keyDTO.OTP); err != nil {
+		return Key{}, err
+	}
+
+	return s.create(ctx, models.APIKey{
+		UserID: userID,
+		Name:   keyDTO.Name,
+		Scopes: keyDTO.Scopes,
+	})
+}
+
+func (s *Service) create(ctx context.Context, key models.APIKey) (Key, error) {
+	id, err := randomHex(idBytes)
+	if err != nil {
+		return Key{}, err
+	}
+
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return Key{}, err
+	}
+
+	key.Prefix = KeyPrefix + id
+	key.KeyHash = hash(secret)
+
+	key.ID, err = s.repo.Create(ctx, key)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{
+		APIKey: key,
+		Secret: key.Prefix + "_" + secret,
+	}, nil
+}
+
+func (s *Service) FindByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	if _, err := s.user.FindByID(ctx, userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.repo.FindByUser(ctx, userID)
+}
+
+// Keys of other users are reported as not found.
+func (s *Service) findOwned(
+	ctx context.Context,
+	userID, keyID uuid.UUID,
+) (models.APIKey, error) {
+	key, err := s.repo.FindByID(ctx, keyID)
+	if err != nil || key.UserID != userID {
+		return models.APIKey{}, ErrAPIKeyNotFound
+	}
+
+	if key.RevokedAt.Valid {
+		return models.APIKey{}, ErrAPIKeyRevoked
+	}
+
+	return key, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, userID, keyID uuid.UUID) error {
+	if _, err := s.findOwned(ctx, userID, keyID); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.Revoke(ctx, keyID)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return ErrAPIKeyRevoked
+	}
+
+	return nil
+}
+
+// Rotate replaces the key with a new one with the same name and scopes,
+// the old key stops working immediately. As it creates a key, it also
+// requires a fresh two-factor code.
+func (s *Service) Rotate(ctx context.Context, userID, keyID uuid.UUID, code string) (Key, error) {
+	old, err := s.findOwned(ctx, userID, keyID)
+	if err != nil {
+		return Key{}, err
+	}
+
+	if err := s.stepUp.StepUp(ctx, userID, code); err != nil {
+		return Key{}, err
+	}
+
+	key, err := s.create(ctx, models.APIKey{
+		UserID: old.UserID,
+		Name:   old.Name,
+		Scopes: old.Scopes,
+	})
+	if err != nil {
+		return Key{}, err
+	}
+
+	if _, err := s.repo.Revoke(ctx, old.ID); err != nil {
+		return Key{}, err
+	}
+
+	return key, nil
+}
+
+// Authenticate returns the active key matching the secret and
+// records its use.
+func (s *Service) Authenticate(ctx context.Context, secret string) (models.APIKey, error) {
+	rest, ok := strings.CutPrefix(secret, KeyPrefix)
+	if !ok {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	id, keySecret, ok := strings.Cut(rest, "_")
+	if !ok {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindByPrefix(ctx, KeyPrefix+id)
+	if err != nil || key.RevokedAt.Valid {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash(keySecret)), []byte(key.KeyHash)) != 1 {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	if err := s.repo.Touch(ctx, key.ID); err != nil {
+		slog.Error("failed to record api key usage", "error", err, "key", key.ID)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,148 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/apikey"
+	"github.com/edulustosa/go-pay/internal/services/mfa"
+	"github.com/edulustosa/go-pay/internal/services/user"
+	"github.com/google/uuid"
+)
+
+type stepUpStub struct {
+	err error
+}
+
+func (s *stepUpStub) StepUp(_ context.Context, _ uuid.UUID, _ string) error {
+	return s.err
+}
+
+func TestAPIKeyService(t *testing.T) {
+	keysRepository := &repo.InMemoryAPIKeysRepository{}
+	userRepository := &repo.InMemoryUserRepository{}
+	stepUp := &stepUpStub{}
+	sut := apikey.NewService(keysRepository, user.NewService(userRepository, user.DefaultConfig()), stepUp)
+
+	ctx := context.Background()
+	merchantID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+		Role:      models.RoleMerchant,
+	})
+
+	commonID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "janedoe@email.com",
+		Document:  "12345678901",
+		Role:      models.RoleCommon,
+	})
+
+	keyDTO := dtos.APIKeyDTO{
+		Name:   "backend",
+		Scopes: []models.Scope{models.ScopeTransactionsRead},
+	}
+
+	t.Run("should create a key storing only its hash", func(t *testing.T) {
+		key, err := sut.Create(ctx, merchantID, keyDTO)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !strings.HasPrefix(key.Secret, key.Prefix+"_") {
+			t.Errorf("expected secret to start with %q, got %q", key.Prefix, key.Secret)
+		}
+
+		stored, _ := keysRepository.FindByID(ctx, key.ID)
+		if strings.Contains(key.Secret, stored.KeyHash) {
+			t.Error("expected the secret not to be stored")
+		}
+
+		authenticated, err := sut.Authenticate(ctx, key.Secret)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !authenticated.HasScope(models.ScopeTransactionsRead) ||
+			authenticated.HasScope(models.ScopeRefundsWrite) {
+			t.Errorf("expected only the granted scopes, got %v", authenticated.Scopes)
+		}
+
+		stored, _ = keysRepository.FindByID(ctx, key.ID)
+		if !stored.LastUsedAt.Valid {
+			t.Error("expected the key usage to be recorded")
+		}
+	})
+
+	t.Run("should not authenticate with a wrong secret", func(t *testing.T) {
+		key, _ := sut.Create(ctx, merchantID, keyDTO)
+
+		_, err := sut.Authenticate(ctx, key.Prefix+"_"+strings.Repeat("0", 64))
+		if !errors.Is(err, apikey.ErrInvalidAPIKey) {
+			t.Errorf("expected error %v, got %v", apikey.ErrInvalidAPIKey, err)
+		}
+	})
+
+	t.Run("should only let merchants create keys", func(t *testing.T) {
+		_, err := sut.Create(ctx, commonID, keyDTO)
+		if !errors.Is(err, apikey.ErrNotMerchant) {
+			t.Errorf("expected error %v, got %v", apikey.ErrNotMerchant, err)
+		}
+	})
+
+	t.Run("should require a fresh two-factor code", func(t *testing.T) {
+		stepUp.err = mfa.ErrCodeRequired
+		defer func() { stepUp.err = nil }()
+
+		_, err := sut.Create(ctx, merchantID, keyDTO)
+		if !errors.Is(err, mfa.ErrCodeRequired) {
+			t.Errorf("expected error %v, got %v", mfa.ErrCodeRequired, err)
+		}
+	})
+
+	t.Run("should stop accepting a rotated key", func(t *testing.T) {
+		old, _ := sut.Create(ctx, merchantID, keyDTO)
+
+		rotated, err := sut.Rotate(ctx, merchantID, old.ID, "123456")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := sut.Authenticate(ctx, old.Secret); !errors.Is(err, apikey.ErrInvalidAPIKey) {
+			t.Errorf("expected error %v, got %v", apikey.ErrInvalidAPIKey, err)
+		}
+
+		if _, err := sut.Authenticate(ctx, rotated.Secret); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if rotated.Name != old.Name || len(rotated.Scopes) != len(old.Scopes) {
+			t.Errorf("expected rotated key to keep name and scopes, got %+v", rotated.APIKey)
+		}
+	})
+
+	t.Run("should not revoke keys of other users", func(t *testing.T) {
+		key, _ := sut.Create(ctx, merchantID, keyDTO)
+
+		err := sut.Revoke(ctx, commonID, key.ID)
+		if !errors.Is(err, apikey.ErrAPIKeyNotFound) {
+			t.Errorf("expected error %v, got %v", apikey.ErrAPIKeyNotFound, err)
+		}
+
+		if err := sut.Revoke(ctx, merchantID, key.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := sut.Authenticate(ctx, key.Secret); !errors.Is(err, apikey.ErrInvalidAPIKey) {
+			t.Errorf("expected error %v, got %v", apikey.ErrInvalidAPIKey, err)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type eventsRepository interface {
+	Create(ctx context.Context, event models.AuditEvent) (uuid.UUID, error)
+	Find(ctx context.Context, filter models.AuditFilter, page int) ([]models.AuditEvent, error)
+}
+
+// Service keeps the trail of the operations that changed the state
+// of the system, so every balance change can be traced to its cause.
+type Service struct {
+	repo eventsRepository
+}
+
+func NewService(repo eventsRepository) *Service {
+	return &Service{
+		repo,
+	}
+}
+
+var ErrInvalidRange = errors.New("the start of the range must be before its end")
+
+// Entry is an operation to record. Before and After are marshaled to
+// JSON and must not hold secrets, nil means the snapshot is unknown or
+// that the target did not exist.
+type Entry struct {
+	Actor     uuid.UUID
+	Action    models.AuditAction
+	Target    uuid.UUID
+	Before    any
+	After     any
+	RequestID string
+	SourceIP  string
+}
+
+func snapshot(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(v)
+}
+
+func (s *Service) Record(ctx context.Context, entry Entry) error {
+	before, err := snapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := snapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	event := models.AuditEvent{
+		Action:    entry.Action,
+		TargetID:  entry.Target,
+		Before:    before,
+		After:     after,
+		RequestID: entry.RequestID,
+		SourceIP:  entry.SourceIP,
+	}
+
+	if entry.Actor != uuid.Nil {
+		event.ActorID = pgtype.UUID{Bytes: entry.Actor, Valid: true}
+	}
+
+	_, err = s.repo.Create(ctx, event)
+	return err
+}
+
+// Find returns the newest events first, the zero values of
+// actor, target, from and to match any event.
+func (s *Service) Find(
+	ctx context.Context,
+	actor, target uuid.UUID,
+	from, to time.Time,
+	page int,
+) ([]models.AuditEvent, error) {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	var filter models.AuditFilter
+	if actor != uuid.Nil {
+		filter.Actor = pgtype.UUID{Bytes: actor, Valid: true}
+	}
+
+	if target != uuid.Nil {
+		filter.Target = pgtype.UUID{Bytes: target, Valid: true}
+	}
+
+	if !from.IsZero() {
+		filter.From = pgtype.Timestamp{Time: from.UTC(), Valid: true}
+	}
+
+	if !to.IsZero() {
+		filter.To = pgtype.Timestamp{Time: to.UTC(), Valid: true}
+	}
+
+	return s.repo.Find(ctx, filter, page)
+}
@@ -0,0 +1,89 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/audit"
+	"github.com/google/uuid"
+)
+
+func TestAuditService(t *testing.T) {
+	eventsRepository := &repo.InMemoryAuditEventsRepository{}
+	sut := audit.NewService(eventsRepository)
+
+	ctx := context.Background()
+	adminID := uuid.New()
+	userID := uuid.New()
+
+	t.Run("should record the snapshots as json", func(t *testing.T) {
+		err := sut.Record(ctx, audit.Entry{
+			Action:    models.AuditUserCreate,
+			Target:    userID,
+			After:     map[string]string{"email": "johndoe@email.com"},
+			RequestID: "req-1",
+			SourceIP:  "10.0.0.1",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		err = sut.Record(ctx, audit.Entry{
+			Actor:  adminID,
+			Action: models.AuditUserFreeze,
+			Target: userID,
+			Before: map[string]string{"status": "ACTIVE"},
+			After:  map[string]string{"status": "FROZEN"},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		created := eventsRepository.Events[0]
+		if created.ActorID.Valid || created.Before != nil ||
+			string(created.After) != `{"email":"johndoe@email.com"}` {
+			t.Errorf("expected anonymous event with only the after snapshot, got %+v", created)
+		}
+
+		frozen := eventsRepository.Events[1]
+		if !frozen.ActorID.Valid || frozen.ActorID.Bytes != adminID {
+			t.Errorf("expected actor %v, got %+v", adminID, frozen.ActorID)
+		}
+	})
+
+	t.Run("should filter by actor, target and time range", func(t *testing.T) {
+		events, err := sut.Find(ctx, adminID, uuid.Nil, time.Time{}, time.Time{}, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(events) != 1 || events[0].Action != models.AuditUserFreeze {
+			t.Errorf("expected the freeze event, got %+v", events)
+		}
+
+		events, _ = sut.Find(ctx, uuid.Nil, userID, time.Time{}, time.Time{}, 1)
+		if len(events) != 2 || events[0].Action != models.AuditUserFreeze {
+			t.Errorf("expected both events newest first, got %+v", events)
+		}
+
+		events, _ = sut.Find(ctx, uuid.Nil, uuid.Nil, time.Now().Add(time.Minute), time.Time{}, 1)
+		if len(events) != 0 {
+			t.Errorf("expected no events after now, got %d", len(events))
+		}
+
+		events, _ = sut.Find(ctx, uuid.Nil, uuid.Nil, time.Now().Add(-time.Minute), time.Now().Add(time.Minute), 1)
+		if len(events) != 2 {
+			t.Errorf("expected 2 events in range, got %d", len(events))
+		}
+	})
+
+	t.Run("should reject inverted time ranges", func(t *testing.T) {
+		now := time.Now()
+		if _, err := sut.Find(ctx, uuid.Nil, uuid.Nil, now, now.Add(-time.Hour), 1); err != audit.ErrInvalidRange {
+			t.Errorf("expected %v, got %v", audit.ErrInvalidRange, err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/helpers"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/notification"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type userRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	FindByDocument(ctx context.Context, document string) (models.User, error)
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+}
+
+type sessionsRepository interface {
+	Create(ctx context.Context, session models.Session) error
+	FindByID(ctx context.Context, id uuid.UUID) (models.Session, error)
+	FindActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	Rotate(ctx context.Context, session models.Session, previousHash string) (bool, error)
+	Revoke(ctx context.Context, id uuid.UUID) (bool, error)
+	RevokeByUser(ctx context.Context, userID, keep uuid.UUID) (int, error)
+}
+
+type userTokenService interface {
+	Issue(ctx context.Context, userID uuid.UUID, email string, purpose models.TokenPurpose, ttl time.Duration) (string, error)
+	Use(ctx context.Context, token string, purpose models.TokenPurpose) (models.UserToken, error)
+	CountSince(ctx context.Context, userID uuid.UUID, purpose models.TokenPurpose, since time.Time) (int, error)
+}
+
+type stepUpService interface {
+	StepUpIfEnabled(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+type Service struct {
+	repo       userRepository
+	sessions   sessionsRepository
+	userTokens userTokenService
+	tokens     *TokenManager
+	notifier   notification.Notifier
+	stepUp     stepUpService
+}
+
+func NewService(
+	repo userRepository,
+	sessions sessionsRepository,
+	userTokens userTokenService,
+	tokens *TokenManager,
+	notifier notification.Notifier,
+	stepUp stepUpService,
+) *Service {
+	return &Service{
+		repo,
+		sessions,
+		userTokens,
+		tokens,
+		notifier,
+		stepUp,
+	}
+}
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Compared when the user does not exist so the response time
+// does not reveal which emails and documents are registered.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// Login starts a new session for the user on the client.
+func (s *Service) Login(
+	ctx context.Context,
+	loginDTO dtos.LoginDTO,
+	client Client,
+) (Tokens, error) {
+	var (
+		user models.User
+		err  error
+	)
+	if loginDTO.Email != "" {
+		user, err = s.repo.FindByEmail(ctx, helpers.NormalizeEmail(loginDTO.Email))
+	} else {
+		document := helpers.NormalizeDocument(loginDTO.Document)
+		user, err = s.repo.FindByDocument(ctx, document)
+	}
+
+	if err != nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(loginDTO.Password))
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginDTO.Password))
+	if err != nil {
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	return s.startSession(ctx, user, client)
+}
@@ -0,0 +1,487 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/auth"
+	"github.com/edulustosa/go-pay/internal/services/user"
+	"github.com/edulustosa/go-pay/internal/services/usertoken"
+	"github.com/google/uuid"
+)
+
+var secret = []byte("a-secret-with-at-least-32-characters")
+
+type notifierStub struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (n *notifierStub) Notify(_ *models.User, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func (n *notifierStub) reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.messages = nil
+}
+
+// sent waits for the messages sent in the background, giving up
+// after the timeout with the ones sent until then.
+func (n *notifierStub) sent(count int, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	for {
+		n.mu.Lock()
+		messages := append([]string(nil), n.messages...)
+		n.mu.Unlock()
+
+		if len(messages) >= count || time.Now().After(deadline) {
+			return messages
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blockingNotifier holds every message until released.
+type blockingNotifier struct {
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Notify(*models.User, string) error {
+	<-n.release
+	return nil
+}
+
+type stepUpStub struct {
+	err error
+}
+
+func (s stepUpStub) StepUpIfEnabled(_ context.Context, _ uuid.UUID, _ string) error {
+	return s.err
+}
+
+func TestAuthService_Login(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	tokens := auth.NewTokenManager(secret, time.Minute)
+	sut := auth.NewService(
+		userRepository,
+		&repo.InMemorySessionsRepository{},
+		usertoken.NewService(&repo.InMemoryUserTokensRepository{}),
+		tokens,
+		&notifierStub{},
+		stepUpStub{},
+	)
+
+	ctx := context.Background()
+	userID, err := user.NewService(userRepository, user.DefaultConfig()).Create(ctx, dtos.UserDTO{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "529.982.247-25",
+		Password:  "123456",
+		Role:      models.RoleMerchant,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	client := auth.Client{UserAgent: "go-test", IP: "127.0.0.1"}
+
+	t.Run("should issue a token with the user id and role", func(t *testing.T) {
+		token, err := sut.Login(ctx, dtos.LoginDTO{
+			Email:    "johndoe@email.com",
+			Password: "123456",
+		}, client)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		principal, err := tokens.Parse(token.Access.Token)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if principal.UserID != userID ||
+			principal.Role != models.RoleMerchant ||
+			principal.SessionID != token.SessionID {
+			t.Errorf("expected principal of %v, got %+v", userID, principal)
+		}
+	})
+
+	t.Run("should login with the document", func(t *testing.T) {
+		_, err := sut.Login(ctx, dtos.LoginDTO{
+			Document: "52998224725",
+			Password: "123456",
+		}, client)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("should not login with a wrong password", func(t *testing.T) {
+		_, err := sut.Login(ctx, dtos.LoginDTO{
+			Email:    "johndoe@email.com",
+			Password: "654321",
+		}, client)
+		if err != auth.ErrInvalidCredentials {
+			t.Errorf("expected %v, got %v", auth.ErrInvalidCredentials, err)
+		}
+	})
+
+	t.Run("should not login unknown users", func(t *testing.T) {
+		_, err := sut.Login(ctx, dtos.LoginDTO{
+			Email:    "janedoe@email.com",
+			Password: "123456",
+		}, client)
+		if err != auth.ErrInvalidCredentials {
+			t.Errorf("expected %v, got %v", auth.ErrInvalidCredentials, err)
+		}
+	})
+}
+
+func TestTokenManager_Parse(t *testing.T) {
+	u := models.User{Role: models.RoleCommon}
+
+	expired, _ := auth.NewTokenManager(secret, -time.Minute).Issue(u, uuid.New())
+	forged, _ := auth.NewTokenManager([]byte("another-secret-with-32-characters"), time.Minute).Issue(u, uuid.New())
+
+	sut := auth.NewTokenManager(secret, time.Minute)
+	for _, token := range []string{expired.Token, forged.Token, "not a token"} {
+		if _, err := sut.Parse(token); err != auth.ErrInvalidToken {
+			t.Errorf("Parse(%s) got %v, want %v", token, err, auth.ErrInvalidToken)
+		}
+	}
+}
+
+func TestAuthService_Refresh(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	sessionsRepository := &repo.InMemorySessionsRepository{}
+	tokens := auth.NewTokenManager(secret, time.Minute)
+	sut := auth.NewService(
+		userRepository,
+		sessionsRepository,
+		usertoken.NewService(&repo.InMemoryUserTokensRepository{}),
+		tokens,
+		&notifierStub{},
+		stepUpStub{},
+	)
+
+	ctx := context.Background()
+	userID, err := user.NewService(userRepository, user.DefaultConfig()).Create(ctx, dtos.UserDTO{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "529.982.247-25",
+		Password:  "123456",
+		Role:      models.RoleCommon,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	client := auth.Client{UserAgent: "go-test", IP: "127.0.0.1"}
+	login := func(t *testing.T) auth.Tokens {
+		t.Helper()
+
+		issued, err := sut.Login(ctx, dtos.LoginDTO{
+			Email:    "johndoe@email.com",
+			Password: "123456",
+		}, client)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		return issued
+	}
+
+	t.Run("should rotate the refresh token", func(t *testing.T) {
+		issued := login(t)
+
+		refreshed, err := sut.Refresh(ctx, issued.RefreshToken, client)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if refreshed.RefreshToken == issued.RefreshToken || refreshed.SessionID != issued.SessionID {
+			t.Errorf("expected a new refresh token for the same session, got %+v", refreshed)
+		}
+
+		if _, err := sut.Refresh(ctx, refreshed.RefreshToken, client); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("should revoke the session when a refresh token is reused", func(t *testing.T) {
+		issued := login(t)
+
+		refreshed, err := sut.Refresh(ctx, issued.RefreshToken, client)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		_, err = sut.Refresh(ctx, issued.RefreshToken, client)
+		if !errors.Is(err, auth.ErrRefreshTokenReused) {
+			t.Errorf("expected %v, got %v", auth.ErrRefreshTokenReused, err)
+		}
+
+		_, err = sut.Refresh(ctx, refreshed.RefreshToken, client)
+		if !errors.Is(err, auth.ErrInvalidRefreshToken) {
+			t.Errorf("expected %v, got %v", auth.ErrInvalidRefreshToken, err)
+		}
+	})
+
+	t.Run("should revoke every session of the user", func(t *testing.T) {
+		issued := login(t)
+
+		if err := sut.RevokeSession(ctx, uuid.New(), issued.SessionID); !errors.Is(err, auth.ErrSessionNotFound) {
+			t.Errorf("expected %v, got %v", auth.ErrSessionNotFound, err)
+		}
+
+		if _, err := sut.RevokeSessions(ctx, userID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		sessions, _ := sut.FindSessions(ctx, userID)
+		if len(sessions) != 0 {
+			t.Errorf("expected no active sessions, got %d", len(sessions))
+		}
+
+		_, err := sut.Refresh(ctx, issued.RefreshToken, client)
+		if !errors.Is(err, auth.ErrInvalidRefreshToken) {
+			t.Errorf("expected %v, got %v", auth.ErrInvalidRefreshToken, err)
+		}
+	})
+}
+
+func TestAuthService_Password(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	sessionsRepository := &repo.InMemorySessionsRepository{}
+	notifier := &notifierStub{}
+	stepUp := &stepUpStub{}
+	tokens := auth.NewTokenManager(secret, time.Minute)
+	sut := auth.NewService(
+		userRepository,
+		sessionsRepository,
+		usertoken.NewService(&repo.InMemoryUserTokensRepository{}),
+		tokens,
+		notifier,
+		stepUp,
+	)
+
+	ctx := context.Background()
+	_, err := user.NewService(userRepository, user.DefaultConfig()).Create(ctx, dtos.UserDTO{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "529.982.247-25",
+		Password:  "123456",
+		Role:      models.RoleCommon,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	client := auth.Client{UserAgent: "go-test", IP: "127.0.0.1"}
+	login := func(password string) (auth.Tokens, error) {
+		return sut.Login(ctx, dtos.LoginDTO{
+			Email:    "johndoe@email.com",
+			Password: password,
+		}, client)
+	}
+
+	t.Run("should reset the password with the token sent to the user", func(t *testing.T) {
+		other, _ := login("123456")
+		notifier.reset()
+
+		if err := sut.ForgotPassword(ctx, "johndoe@email.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		messages := notifier.sent(1, time.Second)
+		if len(messages) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(messages))
+		}
+
+		// Use the code <token> to reset your password...
+		token := strings.Fields(messages[0])[3]
+
+		resetDTO := dtos.ResetPasswordDTO{Token: token, Password: "abcdef"}
+		userID, err := sut.ResetPassword(ctx, resetDTO)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if userID != other.UserID {
+			t.Errorf("expected the password of %v to be reset, got %v", other.UserID, userID)
+		}
+
+		if _, err := sut.ResetPassword(ctx, resetDTO); !errors.Is(err, auth.ErrInvalidResetToken) {
+			t.Errorf("expected %v, got %v", auth.ErrInvalidResetToken, err)
+		}
+
+		if _, err := login("abcdef"); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if _, err := sut.Refresh(ctx, other.RefreshToken, client); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+			t.Errorf("expected sessions to be revoked, got %v", err)
+		}
+	})
+
+	t.Run("should not reveal unknown emails", func(t *testing.T) {
+		notifier.reset()
+
+		if err := sut.ForgotPassword(ctx, "janedoe@email.com"); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if messages := notifier.sent(1, 50*time.Millisecond); len(messages) != 0 {
+			t.Errorf("expected no message, got %v", messages)
+		}
+	})
+
+	t.Run("should not wait for the reset token to be sent", func(t *testing.T) {
+		blocking := &blockingNotifier{release: make(chan struct{})}
+		defer close(blocking.release)
+
+		sut := auth.NewService(
+			userRepository,
+			sessionsRepository,
+			usertoken.NewService(&repo.InMemoryUserTokensRepository{}),
+			tokens,
+			blocking,
+			stepUp,
+		)
+
+		done := make(chan error, 1)
+		go func() { done <- sut.ForgotPassword(ctx, "johndoe@email.com") }()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("expected to return before the reset token is sent")
+		}
+	})
+
+	t.Run("should limit how often the reset token is sent", func(t *testing.T) {
+		userTokensRepository := &repo.InMemoryUserTokensRepository{}
+		notifier := &notifierStub{}
+		sut := auth.NewService(
+			userRepository,
+			sessionsRepository,
+			usertoken.NewService(userTokensRepository),
+			tokens,
+			notifier,
+			stepUp,
+		)
+
+		forgot := func() []string {
+			issued := len(userTokensRepository.Tokens)
+			if err := sut.ForgotPassword(ctx, "johndoe@email.com"); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			return notifier.sent(issued+1, 50*time.Millisecond)
+		}
+
+		if messages := forgot(); len(messages) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(messages))
+		}
+
+		if messages := forgot(); len(messages) != 1 {
+			t.Errorf("expected the second request to be ignored, got %d messages", len(messages))
+		}
+
+		// Tokens issued more than a minute ago still count for the hourly limit
+		for i := range 4 {
+			userTokensRepository.Tokens[i].CreatedAt.Time = time.Now().Add(-2 * time.Minute)
+			if messages := forgot(); len(messages) != i+2 {
+				t.Fatalf("expected %d messages, got %d", i+2, len(messages))
+			}
+		}
+
+		userTokensRepository.Tokens[4].CreatedAt.Time = time.Now().Add(-2 * time.Minute)
+		if messages := forgot(); len(messages) != 5 {
+			t.Errorf("expected the requests over the hourly limit to be ignored, got %d messages", len(messages))
+		}
+	})
+
+	t.Run("should change the password keeping only the current session", func(t *testing.T) {
+		current, _ := login("abcdef")
+		other, _ := login("abcdef")
+		principal, _ := tokens.Parse(current.Access.Token)
+
+		err := sut.ChangePassword(ctx, principal, dtos.ChangePasswordDTO{
+			CurrentPassword: "wrong",
+			NewPassword:     "654321",
+		})
+		if !errors.Is(err, auth.ErrWrongPassword) {
+			t.Errorf("expected %v, got %v", auth.ErrWrongPassword, err)
+		}
+
+		stepUp.err = errors.New("code required")
+		err = sut.ChangePassword(ctx, principal, dtos.ChangePasswordDTO{
+			CurrentPassword: "abcdef",
+			NewPassword:     "654321",
+		})
+		if err != stepUp.err {
+			t.Errorf("expected %v, got %v", stepUp.err, err)
+		}
+		stepUp.err = nil
+
+		err = sut.ChangePassword(ctx, principal, dtos.ChangePasswordDTO{
+			CurrentPassword: "abcdef",
+			NewPassword:     "654321",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := sut.Refresh(ctx, current.RefreshToken, client); err != nil {
+			t.Errorf("expected current session to be kept, got %v", err)
+		}
+
+		if _, err := sut.Refresh(ctx, other.RefreshToken, client); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+			t.Errorf("expected other sessions to be revoked, got %v", err)
+		}
+	})
+}
+
+func TestPrincipal_Can(t *testing.T) {
+	testCases := []struct {
+		principal  auth.Principal
+		permission auth.Permission
+		want       bool
+	}{
+		{auth.Principal{Role: models.RoleAdmin}, auth.PermissionUsersRead, true},
+		{auth.Principal{Role: models.RoleSupport}, auth.PermissionUsersFreeze, true},
+		{auth.Principal{Role: models.RoleSupport}, auth.PermissionFraudResolve, false},
+		{auth.Principal{Role: models.RoleFinance}, auth.PermissionDepositsConfirm, true},
+		{auth.Principal{Role: models.RoleFinance}, auth.PermissionUsersRead, false},
+		{auth.Principal{Role: models.RoleSupport}, auth.PermissionAuditRead, false},
+		{auth.Principal{Role: models.RoleAdmin}, auth.PermissionAuditRead, true},
+		{auth.Principal{Role: models.RoleCommon}, auth.PermissionFraudRead, false},
+		{auth.Principal{Role: models.RoleAdmin, APIKeyID: uuid.New()}, auth.PermissionUsersRead, false},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.principal.Can(tc.permission); got != tc.want {
+			t.Errorf("%s.Can(%s) got %v, want %v", tc.principal.Role, tc.permission, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/edulustosa/go-pay/helpers"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/usertoken"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	resetTokenTTL   = 30 * time.Minute
+	passwordChanged = "Your password was changed. If it was not you, reset it and contact our support."
+)
+
+// Limits of password reset emails sent to an user, the requests over
+// them are ignored like the ones of unknown emails.
+const (
+	resetResendInterval = time.Minute
+	maxResetsPerHour    = 5
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrWrongPassword     = errors.New("wrong current password")
+)
+
+// ForgotPassword sends a reset token to the user with the email. Unknown
+// emails and requests over the limits are silently ignored, and the token
+// is issued and sent in the background, so neither the response nor its
+// time reveal which emails are registered or throttled.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.repo.FindByEmail(ctx, helpers.NormalizeEmail(email))
+	if err != nil {
+		return nil
+	}
+
+	// The request is over before the token is sent
+	go s.sendResetToken(context.WithoutCancel(ctx), user)
+
+	return nil
+}
+
+func (s *Service) sendResetToken(ctx context.Context, user models.User) {
+	throttled, err := s.resetThrottled(ctx, user.ID)
+	if err != nil {
+		slog.Error("failed to count password reset tokens", "error", err, "user", user.ID)
+		return
+	}
+
+	if throttled {
+		slog.Warn("password reset requested too often", "user", user.ID)
+		return
+	}
+
+	token, err := s.userTokens.Issue(ctx, user.ID, user.Email, models.PurposePasswordReset, resetTokenTTL)
+	if err != nil {
+		slog.Error("failed to issue password reset token", "error", err, "user", user.ID)
+		return
+	}
+
+	message := fmt.Sprintf(
+		"Use the code %s to reset your password, it expires in %d minutes.",
+		token,
+		int(resetTokenTTL.Minutes()),
+	)
+
+	if err := s.notifier.Notify(&user, message); err != nil {
+		slog.Error("failed to send password reset token", "error", err, "user", user.ID)
+	}
+}
+
+// Reports if a reset token was sent to the user in the last
+// resetResendInterval or maxResetsPerHour in the last hour.
+func (s *Service) resetThrottled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	now := time.Now()
+	recent, err := s.userTokens.CountSince(ctx, userID, models.PurposePasswordReset, now.Add(-resetResendInterval))
+	if err != nil {
+		return false, err
+	}
+
+	lastHour, err := s.userTokens.CountSince(ctx, userID, models.PurposePasswordReset, now.Add(-time.Hour))
+	if err != nil {
+		return false, err
+	}
+
+	return recent > 0 || lastHour >= maxResetsPerHour, nil
+}
+
+// ResetPassword sets the new password of the user owning the token and
+// ends every session, as whoever knew the old password may be logged in.
+func (s *Service) ResetPassword(ctx context.Context, resetDTO dtos.ResetPasswordDTO) (uuid.UUID, error) {
+	userToken, err := s.userTokens.Use(ctx, resetDTO.Token, models.PurposePasswordReset)
+	if errors.Is(err, usertoken.ErrInvalidToken) {
+		return uuid.Nil, ErrInvalidResetToken
+	}
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return userToken.UserID, s.setPassword(ctx, userToken.UserID, resetDTO.Password, uuid.Nil)
+}
+
+// ChangePassword requires the current password, and a fresh two-factor
+// code when enabled, ending every other session of the user.
+func (s *Service) ChangePassword(
+	ctx context.Context,
+	principal Principal,
+	changeDTO dtos.ChangePasswordDTO,
+) error {
+	user, err := s.repo.FindByID(ctx, principal.UserID)
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(changeDTO.CurrentPassword))
+	if err != nil {
+		return ErrWrongPassword
+	}
+
+	if err := s.stepUp.StepUpIfEnabled(ctx, user.ID, changeDTO.OTP); err != nil {
+		return err
+	}
+
+	return s.setPassword(ctx, user.ID, changeDTO.NewPassword, principal.SessionID)
+}
+
+func (s *Service) setPassword(
+	ctx context.Context,
+	userID uuid.UUID,
+	password string,
+	keepSession uuid.UUID,
+) error {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, userID, string(passwordHash)); err != nil {
+		return err
+	}
+
+	if _, err := s.sessions.RevokeByUser(ctx, userID, keepSession); err != nil {
+		return err
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.notifier.Notify(&user, passwordChanged); err != nil {
+		slog.Error("failed to notify password change", "error", err, "user", userID)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"slices"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+// Permission is an operation reserved to the staff, users can always
+// act on their own resources.
+type Permission string
+
+const (
+	PermissionUsersRead       Permission = "users:read"
+	PermissionUsersFreeze     Permission = "users:freeze"
+	PermissionFraudRead       Permission = "fraud:read"
+	PermissionFraudResolve    Permission = "fraud:resolve"
+	PermissionDepositsConfirm Permission = "deposits:confirm"
+	PermissionKYCReview       Permission = "kyc:review"
+	PermissionAuditRead       Permission = "audit:read"
+)
+
+// permissions is the matrix of what each staff role can do,
+// the roles missing from it have no permissions.
+var permissions = map[models.Role][]Permission{
+	models.RoleAdmin: {
+		PermissionUsersRead,
+		PermissionUsersFreeze,
+		PermissionFraudRead,
+		PermissionFraudResolve,
+		PermissionDepositsConfirm,
+		PermissionKYCReview,
+		PermissionAuditRead,
+	},
+	models.RoleSupport: {
+		PermissionUsersFreeze,
+		PermissionFraudRead,
+		PermissionKYCReview,
+	},
+	models.RoleFinance: {
+		PermissionFraudRead,
+		PermissionFraudResolve,
+		PermissionDepositsConfirm,
+	},
+}
+
+// Can reports if the principal was granted the permission by its role,
+// requests made with an API key are never granted staff permissions.
+func (p Principal) Can(permission Permission) bool {
+	if p.APIKeyID != uuid.Nil {
+		return false
+	}
+
+	return slices.Contains(permissions[p.Role], permission)
+}
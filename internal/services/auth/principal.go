@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated user of a request. Requests made with
+// an API key act on behalf of its owner, limited to the key scopes.
+type Principal struct {
+	UserID    uuid.UUID
+	Role      models.Role
+	SessionID uuid.UUID
+	APIKeyID  uuid.UUID
+	Scopes    []models.Scope
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
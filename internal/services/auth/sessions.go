@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Refresh tokens are rotated on every use, so a session stays alive
+// as long as it is used at least once in this period. Access tokens of
+// revoked sessions are rejected by CheckSession.
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+const (
+	refreshSecretBytes = 32
+	maxUserAgentLength = 255
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionInactive     = errors.New("session revoked or expired")
+	ErrAccountInactive     = errors.New("account frozen or closed")
+)
+
+// Client identifies the device a session is used from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Tokens are issued on login and on every refresh, the refresh token
+// can only be used once.
+type Tokens struct {
+	Access           AccessToken
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	SessionID        uuid.UUID
+	UserID           uuid.UUID
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Refresh tokens carry the session id so a reused token can be traced
+// back to its session: <session id>.<secret>
+func newRefreshToken(sessionID uuid.UUID) (token, hash string, err error) {
+	b := make([]byte, refreshSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return sessionID.String() + "." + secret, hashSecret(secret), nil
+}
+
+func parseRefreshToken(token string) (uuid.UUID, string, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return uuid.Nil, "", ErrInvalidRefreshToken
+	}
+
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidRefreshToken
+	}
+
+	return sessionID, secret, nil
+}
+
+func (c Client) userAgent() string {
+	if len(c.UserAgent) > maxUserAgentLength {
+		return c.UserAgent[:maxUserAgentLength]
+	}
+
+	return c.UserAgent
+}
+
+func (s *Service) issue(user models.User, session models.Session, refreshToken string) (Tokens, error) {
+	access, err := s.tokens.Issue(user, session.ID)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		Access:           access,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt.Time,
+		SessionID:        session.ID,
+		UserID:           user.ID,
+	}, nil
+}
+
+func (s *Service) startSession(ctx context.Context, user models.User, client Client) (Tokens, error) {
+	sessionID := uuid.New()
+	refreshToken, hash, err := newRefreshToken(sessionID)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	session := models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		RefreshTokenHash: hash,
+		UserAgent:        client.userAgent(),
+		IP:               client.IP,
+		ExpiresAt:        pgtype.Timestamp{Time: time.Now().Add(refreshTokenTTL), Valid: true},
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return Tokens{}, err
+	}
+
+	return s.issue(user, session, refreshToken)
+}
+
+// Refresh exchanges the refresh token for new tokens. Using a refresh
+// token that was already exchanged means it leaked, so the whole session
+// is revoked and both the attacker and the user must login again.
+func (s *Service) Refresh(ctx context.Context, refreshToken string, client Client) (Tokens, error) {
+	sessionID, secret, err := parseRefreshToken(refreshToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	session, err := s.sessions.FindByID(ctx, sessionID)
+	if err != nil ||
+		session.RevokedAt.Valid ||
+		!session.ExpiresAt.Time.After(time.Now()) {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+
+	hash := hashSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(session.RefreshTokenHash)) != 1 {
+		return Tokens{}, s.revokeReused(ctx, session)
+	}
+
+	user, err := s.repo.FindByID(ctx, session.UserID)
+	if err != nil {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+
+	newToken, newHash, err := newRefreshToken(session.ID)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	session.RefreshTokenHash = newHash
+	session.UserAgent = client.userAgent()
+	session.IP = client.IP
+	session.ExpiresAt = pgtype.Timestamp{Time: time.Now().Add(refreshTokenTTL), Valid: true}
+
+	rotated, err := s.sessions.Rotate(ctx, session, hash)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// Another request exchanged the same token first
+	if !rotated {
+		return Tokens{}, s.revokeReused(ctx, session)
+	}
+
+	return s.issue(user, session, newToken)
+}
+
+func (s *Service) revokeReused(ctx context.Context, session models.Session) error {
+	slog.Warn(
+		"refresh token reused, revoking session",
+		"session", session.ID,
+		"user", session.UserID,
+	)
+
+	if _, err := s.sessions.Revoke(ctx, session.ID); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// CheckSession confirms the session of an access token is still active
+// and its user was not frozen or closed since the token was issued.
+func (s *Service) CheckSession(ctx context.Context, principal Principal) error {
+	session, err := s.sessions.FindByID(ctx, principal.SessionID)
+	if err != nil ||
+		session.UserID != principal.UserID ||
+		session.RevokedAt.Valid ||
+		!session.ExpiresAt.Time.After(time.Now()) {
+		return ErrSessionInactive
+	}
+
+	user, err := s.repo.FindByID(ctx, principal.UserID)
+	if err != nil ||
+		user.Status == models.StatusFrozen ||
+		user.Status == models.StatusClosed {
+		return ErrAccountInactive
+	}
+
+	return nil
+}
+
+func (s *Service) FindSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	return s.sessions.FindActiveByUser(ctx, userID)
+}
+
+// RevokeSession ends a session of the user, sessions
+// of other users are reported as not found.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := s.sessions.FindByID(ctx, sessionID)
+	if err != nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	revoked, err := s.sessions.Revoke(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeSessions ends every session of the user, like
+// after the password is changed.
+func (s *Service) RevokeSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	return s.sessions.RevokeByUser(ctx, userID, uuid.Nil)
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const issuer = "go-pay"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type claims struct {
+	Role      models.Role `json:"role"`
+	SessionID string      `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenManager issues and verifies the HS256 signed access tokens.
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokenManager(secret []byte, ttl time.Duration) *TokenManager {
+	return &TokenManager{
+		secret,
+		ttl,
+	}
+}
+
+type AccessToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// Issue signs an access token for the user in the given session.
+func (m *TokenManager) Issue(user models.User, sessionID uuid.UUID) (AccessToken, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Role:      user.Role,
+		SessionID: sessionID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   user.ID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+
+	signed, err := token.SignedString(m.secret)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("sign token: %w", err)
+	}
+
+	return AccessToken{signed, expiresAt}, nil
+}
+
+// Parse verifies the token signature and expiration
+// and returns the principal it was issued to.
+func (m *TokenManager) Parse(token string) (Principal, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(
+		token,
+		&c,
+		func(*jwt.Token) (any, error) { return m.secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	sessionID, err := uuid.Parse(c.SessionID)
+	if err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+
+	return Principal{
+		UserID:    userID,
+		Role:      c.Role,
+		SessionID: sessionID,
+	}, nil
+}
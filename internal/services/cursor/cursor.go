@@ -0,0 +1,140 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+// Signer turns the cursors into opaque tokens. They are signed, so
+// clients can't forge positions that were never listed to them.
+type Signer struct {
+	key []byte
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{
+		key,
+	}
+}
+
+type payload struct {
+	CreatedAt int64     `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+	Scope     string    `json:"s"`
+}
+
+// Scope identifies the listing of a cursor, the resource and the values
+// of its filters. A cursor is only decoded in the scope it was encoded,
+// so positions of a listing are not used to page another one.
+func Scope(resource string, filter ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(filter, "\x00")))
+	return resource + ":" + base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// The signatures are prefixed with it, so the key can
+// be shared with other signatures without confusion.
+const domain = "cursor:"
+
+func (s *Signer) sign(data string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(domain + data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Encode returns the token of the cursor in the scope,
+// <payload>.<signature> in base64url
+func (s *Signer) Encode(c models.Cursor, scope string) string {
+	data, _ := json.Marshal(payload{
+		CreatedAt: c.CreatedAt.UnixNano(),
+		ID:        c.ID,
+		Backward:  c.Backward,
+		Scope:     scope,
+	})
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + s.sign(encoded)
+}
+
+// Decode rejects the tokens not signed by the signer or from another scope
+func (s *Signer) Decode(token, scope string) (models.Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil || p.Scope != scope {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+
+	return models.Cursor{
+		CreatedAt: time.Unix(0, p.CreatedAt).UTC(),
+		ID:        p.ID,
+		Backward:  p.Backward,
+	}, nil
+}
+
+// Paginate builds the page of the items fetched next to the cursor, which
+// must be up to limit+1 in the listing order, the extra one tells there are
+// more items in that direction.
+func Paginate[T any](
+	items []T,
+	cursor *models.Cursor,
+	limit int,
+	position func(T) models.Cursor,
+) models.Page[T] {
+	backward := cursor != nil && cursor.Backward
+
+	more := len(items) > limit
+	if more && backward {
+		items = items[len(items)-limit:]
+	} else if more {
+		items = items[:limit]
+	}
+
+	page := models.Page[T]{Items: items}
+
+	// Without items, the pages around are the ones around the cursor
+	if len(items) == 0 {
+		if cursor != nil {
+			around := *cursor
+			around.Backward = !backward
+			if backward {
+				page.Next = &around
+			} else {
+				page.Prev = &around
+			}
+		}
+
+		return page
+	}
+
+	if more || backward {
+		next := position(items[len(items)-1])
+		page.Next = &next
+	}
+
+	if cursor != nil && (more || !backward) {
+		prev := position(items[0])
+		prev.Backward = true
+		page.Prev = &prev
+	}
+
+	return page
+}
@@ -0,0 +1,128 @@
+package cursor_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/services/cursor"
+	"github.com/google/uuid"
+)
+
+func TestSigner(t *testing.T) {
+	sut := cursor.NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+
+	c := models.Cursor{
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC),
+		ID:        uuid.New(),
+		Backward:  true,
+	}
+	scope := cursor.Scope("users", "COMMON")
+
+	t.Run("should decode the cursors it encodes", func(t *testing.T) {
+		decoded, err := sut.Decode(sut.Encode(c, scope), scope)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !decoded.CreatedAt.Equal(c.CreatedAt) || decoded.ID != c.ID || !decoded.Backward {
+			t.Errorf("expected %+v, got %+v", c, decoded)
+		}
+	})
+
+	t.Run("should not decode tampered or foreign cursors", func(t *testing.T) {
+		token := sut.Encode(c, scope)
+		payload, signature, _ := strings.Cut(token, ".")
+		other := cursor.NewSigner([]byte("fedcba9876543210fedcba9876543210"))
+
+		forged := sut.Encode(models.Cursor{CreatedAt: c.CreatedAt, ID: uuid.New()}, scope)
+		forgedPayload, _, _ := strings.Cut(forged, ".")
+
+		for _, token := range []string{
+			"",
+			payload,
+			forgedPayload + "." + signature,
+			payload + "." + signature + "x",
+			other.Encode(c, scope),
+		} {
+			if _, err := sut.Decode(token, scope); err != cursor.ErrInvalidCursor {
+				t.Errorf("Decode(%q) expected %v, got %v", token, cursor.ErrInvalidCursor, err)
+			}
+		}
+	})
+
+	t.Run("should not decode the cursors of other listings", func(t *testing.T) {
+		token := sut.Encode(c, scope)
+
+		for _, other := range []string{
+			cursor.Scope("transactions", "COMMON"),
+			cursor.Scope("users", "MERCHANT"),
+			cursor.Scope("users", "COMMON", ""),
+			cursor.Scope("users"),
+		} {
+			if _, err := sut.Decode(token, other); err != cursor.ErrInvalidCursor {
+				t.Errorf("Decode in %q expected %v, got %v", other, cursor.ErrInvalidCursor, err)
+			}
+		}
+	})
+}
+
+func TestPaginate(t *testing.T) {
+	position := func(n int) models.Cursor {
+		return models.Cursor{CreatedAt: time.Unix(int64(n), 0)}
+	}
+	at := func(n int, backward bool) *models.Cursor {
+		c := position(n)
+		c.Backward = backward
+		return &c
+	}
+
+	testCases := []struct {
+		name   string
+		items  []int
+		cursor *models.Cursor
+		want   []int
+		next   *models.Cursor
+		prev   *models.Cursor
+	}{
+		{"first page", []int{1, 2, 3}, nil, []int{1, 2}, at(2, false), nil},
+		{"single page", []int{1, 2}, nil, []int{1, 2}, nil, nil},
+		{"middle page", []int{3, 4, 5}, at(2, false), []int{3, 4}, at(4, false), at(3, true)},
+		{"last page", []int{5}, at(4, false), []int{5}, nil, at(5, true)},
+		{"page before", []int{1, 2, 3}, at(4, true), []int{2, 3}, at(3, false), at(2, true)},
+		{"first page before", []int{1}, at(2, true), []int{1}, at(1, false), nil},
+		{"past the end", nil, at(5, false), nil, nil, at(5, true)},
+	}
+
+	for _, tc := range testCases {
+		page := cursor.Paginate(tc.items, tc.cursor, 2, position)
+
+		if len(page.Items) != len(tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, page.Items)
+			continue
+		}
+
+		for i := range tc.want {
+			if page.Items[i] != tc.want[i] {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, page.Items)
+			}
+		}
+
+		if !equalCursor(page.Next, tc.next) {
+			t.Errorf("%s: expected next %+v, got %+v", tc.name, tc.next, page.Next)
+		}
+
+		if !equalCursor(page.Prev, tc.prev) {
+			t.Errorf("%s: expected prev %+v, got %+v", tc.name, tc.prev, page.Prev)
+		}
+	}
+}
+
+func equalCursor(a, b *models.Cursor) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.CreatedAt.Equal(b.CreatedAt) && a.ID == b.ID && a.Backward == b.Backward
+}
@@ -0,0 +1,220 @@
+package deposit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/google/uuid"
+)
+
+// Funding is the answer of a funding source to a deposit request.
+type Funding struct {
+	Status       models.DepositStatus
+	Reference    string
+	Instructions string
+}
+
+// FundingProvider collects the money of a deposit. The request is
+// forwarded as is so providers can read their own details, like the
+// card data, which is never stored.
+type FundingProvider interface {
+	Fund(ctx context.Context, deposit models.Deposit, req dtos.DepositDTO) (Funding, error)
+}
+
+type depositsRepository interface {
+	Create(ctx context.Context, deposit models.Deposit) (uuid.UUID, error)
+	FindByID(ctx context.Context, id uuid.UUID) (models.Deposit, error)
+	Settle(ctx context.Context, deposit models.Deposit) (bool, error)
+}
+
+type transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type userService interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+}
+
+type walletService interface {
+	Balance(ctx context.Context, user *models.User, currency string) (*money.Money, error)
+	Add(ctx context.Context, userID uuid.UUID, amount *money.Money) (wallet.Change, error)
+}
+
+type Service struct {
+	repo      depositsRepository
+	tx        transactor
+	user      userService
+	wallet    walletService
+	providers map[models.FundingSource]FundingProvider
+}
+
+func NewService(
+	repo depositsRepository,
+	tx transactor,
+	user userService,
+	wallet walletService,
+	providers map[models.FundingSource]FundingProvider,
+) *Service {
+	return &Service{
+		repo,
+		tx,
+		user,
+		wallet,
+		providers,
+	}
+}
+
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrWalletNotFound    = errors.New("wallet not found")
+	ErrUnsupportedSource = errors.New("unsupported funding source")
+	ErrPaymentDeclined   = errors.New("payment declined")
+	ErrDepositNotFound   = errors.New("deposit not found")
+	ErrDepositNotPending = errors.New("deposit is not pending")
+)
+
+// Create asks the funding source for the money and registers the deposit.
+// Sources that settle immediately credit the user wallet right away, in
+// the same database transaction, returning how the balance changed, the
+// others keep the deposit pending until it is confirmed.
+func (s *Service) Create(
+	ctx context.Context,
+	depositDTO dtos.DepositDTO,
+) (models.Deposit, Funding, wallet.Change, error) {
+	provider, ok := s.providers[depositDTO.Source]
+	if !ok {
+		return models.Deposit{}, Funding{}, wallet.Change{}, ErrUnsupportedSource
+	}
+
+	user, err := s.user.FindByID(ctx, depositDTO.User)
+	if err != nil {
+		return models.Deposit{}, Funding{}, wallet.Change{}, ErrUserNotFound
+	}
+
+	currency := depositDTO.Currency
+	if currency == "" {
+		currency = wallet.DefaultCurrency
+	}
+
+	if _, err := s.wallet.Balance(ctx, &user, currency); err != nil {
+		return models.Deposit{}, Funding{}, wallet.Change{}, ErrWalletNotFound
+	}
+
+	deposit := models.Deposit{
+		UserID:   user.ID,
+		Amount:   money.NewFromFloat(depositDTO.Amount, currency).AsMajorUnits(),
+		Currency: currency,
+		Source:   depositDTO.Source,
+	}
+
+	funding, err := provider.Fund(ctx, deposit, depositDTO)
+	if err != nil {
+		return models.Deposit{}, Funding{}, wallet.Change{}, err
+	}
+
+	deposit.Status = funding.Status
+	deposit.Reference = funding.Reference
+
+	var (
+		id      uuid.UUID
+		balance wallet.Change
+	)
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.repo.Create(ctx, deposit)
+		if err != nil || deposit.Status != models.DepositConfirmed {
+			return err
+		}
+
+		amount := money.NewFromFloat(deposit.Amount, deposit.Currency)
+		balance, err = s.wallet.Add(ctx, deposit.UserID, amount)
+		return err
+	})
+	if err != nil {
+		return models.Deposit{}, Funding{}, wallet.Change{}, err
+	}
+
+	deposit, err = s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.Deposit{}, Funding{}, wallet.Change{}, err
+	}
+
+	if deposit.Status == models.DepositFailed {
+		return deposit, funding, wallet.Change{}, ErrPaymentDeclined
+	}
+
+	return deposit, funding, balance, nil
+}
+
+// ConfirmFunding confirms the deposit on the notice of its funding source,
+// which must name the reference it gave to the deposit.
+func (s *Service) ConfirmFunding(
+	ctx context.Context,
+	id uuid.UUID,
+	reference string,
+) (models.Deposit, wallet.Change, error) {
+	deposit, err := s.repo.FindByID(ctx, id)
+	if err != nil || deposit.Reference != reference {
+		return models.Deposit{}, wallet.Change{}, ErrDepositNotFound
+	}
+
+	return s.Confirm(ctx, id)
+}
+
+// Confirm settles a pending deposit once the money was received, on the
+// notice of the funding source or by the staff. The deposit is settled
+// and credited in a single database transaction, returning how the
+// balance changed.
+func (s *Service) Confirm(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.Deposit, wallet.Change, error) {
+	deposit, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.Deposit{}, wallet.Change{}, ErrDepositNotFound
+	}
+
+	var balance wallet.Change
+	deposit.Status = models.DepositConfirmed
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		ok, err := s.repo.Settle(ctx, deposit)
+		if err != nil {
+			return err
+		}
+
+		// The status update guards against crediting the same deposit twice
+		if !ok {
+			return ErrDepositNotPending
+		}
+
+		amount := money.NewFromFloat(deposit.Amount, deposit.Currency)
+		balance, err = s.wallet.Add(ctx, deposit.UserID, amount)
+		return err
+	})
+	if err != nil {
+		return models.Deposit{}, wallet.Change{}, err
+	}
+
+	deposit, err = s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.Deposit{}, wallet.Change{}, err
+	}
+
+	return deposit, balance, nil
+}
+
+func (s *Service) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.Deposit, error) {
+	deposit, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.Deposit{}, ErrDepositNotFound
+	}
+
+	return deposit, nil
+}
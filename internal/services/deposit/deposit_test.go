@@ -0,0 +1,151 @@
+package deposit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/deposit"
+	"github.com/edulustosa/go-pay/internal/services/user"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+)
+
+func TestDepositService(t *testing.T) {
+	depositsRepository := &repo.InMemoryDepositsRepository{}
+	userRepository := &repo.InMemoryUserRepository{}
+	sut := deposit.NewService(
+		depositsRepository,
+		repo.InMemoryTransactor{},
+		user.NewService(userRepository, user.DefaultConfig()),
+		wallet.NewService(&repo.InMemoryWalletsRepository{}, userRepository),
+		map[models.FundingSource]deposit.FundingProvider{
+			models.SourceBankTransfer: deposit.SimulatedBankTransfer{},
+			models.SourceCard:         deposit.SimulatedCard{},
+		},
+	)
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+	})
+
+	card := &dtos.CardDTO{
+		Number:     "4111111111111111",
+		HolderName: "John Doe",
+		ExpMonth:   12,
+		ExpYear:    time.Now().Year() + 1,
+		CVV:        "123",
+	}
+
+	t.Run("should credit card deposits immediately", func(t *testing.T) {
+		d, _, balance, err := sut.Create(ctx, dtos.DepositDTO{
+			User:   userID,
+			Amount: 100,
+			Source: models.SourceCard,
+			Card:   card,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if d.Status != models.DepositConfirmed {
+			t.Errorf("expected status to be %v, got %v", models.DepositConfirmed, d.Status)
+		}
+
+		userModel, _ := userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 100 {
+			t.Errorf("expected balance to be 100, got %v", userModel.Balance)
+		}
+
+		if balance.Before != 0 || balance.After != 100 {
+			t.Errorf("expected the balance to change from 0 to 100, got %+v", balance)
+		}
+	})
+
+	t.Run("should not credit declined cards", func(t *testing.T) {
+		declined := *card
+		declined.Number = deposit.DeclinedCardNumber
+
+		d, _, _, err := sut.Create(ctx, dtos.DepositDTO{
+			User:   userID,
+			Amount: 100,
+			Source: models.SourceCard,
+			Card:   &declined,
+		})
+		if err != deposit.ErrPaymentDeclined {
+			t.Fatalf("expected error to be ErrPaymentDeclined, got %v", err)
+		}
+
+		if d.Status != models.DepositFailed {
+			t.Errorf("expected status to be %v, got %v", models.DepositFailed, d.Status)
+		}
+
+		userModel, _ := userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 100 {
+			t.Errorf("expected balance to be 100, got %v", userModel.Balance)
+		}
+	})
+
+	t.Run("should credit bank transfers only once confirmed", func(t *testing.T) {
+		d, funding, _, err := sut.Create(ctx, dtos.DepositDTO{
+			User:   userID,
+			Amount: 50,
+			Source: models.SourceBankTransfer,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if d.Status != models.DepositPending || funding.Instructions == "" {
+			t.Errorf("expected pending deposit with instructions, got %+v", funding)
+		}
+
+		userModel, _ := userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 100 {
+			t.Errorf("expected balance to be 100, got %v", userModel.Balance)
+		}
+
+		if _, _, err := sut.Confirm(ctx, d.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, _, err := sut.Confirm(ctx, d.ID); err != deposit.ErrDepositNotPending {
+			t.Errorf("expected error to be ErrDepositNotPending, got %v", err)
+		}
+
+		userModel, _ = userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 150 {
+			t.Errorf("expected balance to be 150, got %v", userModel.Balance)
+		}
+	})
+
+	t.Run("should only confirm the funding with the deposit reference", func(t *testing.T) {
+		d, _, _, err := sut.Create(ctx, dtos.DepositDTO{
+			User:   userID,
+			Amount: 50,
+			Source: models.SourceBankTransfer,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, _, err := sut.ConfirmFunding(ctx, d.ID, "BT-OTHER"); err != deposit.ErrDepositNotFound {
+			t.Fatalf("expected error to be ErrDepositNotFound, got %v", err)
+		}
+
+		if _, _, err := sut.ConfirmFunding(ctx, d.ID, d.Reference); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		userModel, _ := userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 200 {
+			t.Errorf("expected balance to be 200, got %v", userModel.Balance)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package deposit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+)
+
+func newReference(prefix string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return prefix + strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// SimulatedBankTransfer issues transfer instructions and leaves the
+// deposit pending until the transfer is confirmed.
+type SimulatedBankTransfer struct{}
+
+func (SimulatedBankTransfer) Fund(
+	_ context.Context,
+	deposit models.Deposit,
+	_ dtos.DepositDTO,
+) (Funding, error) {
+	reference, err := newReference("BT")
+	if err != nil {
+		return Funding{}, err
+	}
+
+	return Funding{
+		Status:    models.DepositPending,
+		Reference: reference,
+		Instructions: fmt.Sprintf(
+			"transfer %.2f %s to bank 999, branch 0001, account 123456-7 using the reference %s",
+			deposit.Amount,
+			deposit.Currency,
+			reference,
+		),
+	}, nil
+}
+
+// DeclinedCardNumber is always declined by the simulated card acquirer.
+const DeclinedCardNumber = "4000000000000002"
+
+// SimulatedCard charges the card instantly, declining expired cards,
+// invalid numbers and the DeclinedCardNumber.
+type SimulatedCard struct{}
+
+func (SimulatedCard) Fund(
+	_ context.Context,
+	_ models.Deposit,
+	req dtos.DepositDTO,
+) (Funding, error) {
+	reference, err := newReference("CH")
+	if err != nil {
+		return Funding{}, err
+	}
+
+	funding := Funding{
+		Status:    models.DepositConfirmed,
+		Reference: reference,
+	}
+
+	card := req.Card
+	if card == nil ||
+		card.Number == DeclinedCardNumber ||
+		!validLuhn(card.Number) ||
+		expired(card.ExpMonth, card.ExpYear) {
+		funding.Status = models.DepositFailed
+	}
+
+	return funding, nil
+}
+
+func validLuhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+func expired(month, year int) bool {
+	// Cards are valid until the last day of the expiration month
+	expiration := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	return !time.Now().Before(expiration)
+}
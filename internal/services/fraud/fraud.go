@@ -0,0 +1,221 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Decision string
+
+const (
+	DecisionAllow  Decision = "ALLOW"
+	DecisionReview Decision = "REVIEW"
+	DecisionDeny   Decision = "DENY"
+)
+
+// Attempt is a transfer that has passed the basic validations
+// and is about to be authorized. The amount is in the payer currency
+// while the rules thresholds are compared against its equivalent
+// in the default currency.
+type Attempt struct {
+	Payer         models.User
+	Payee         models.User
+	Amount        float64
+	Currency      string
+	PayeeCurrency string
+	Equivalent    float64
+	At            time.Time
+}
+
+// Hit is a rule that matched an attempt.
+type Hit struct {
+	Rule   string
+	Score  int
+	Reason string
+}
+
+type Result struct {
+	Decision Decision
+	Score    int
+	Hits     []Hit
+}
+
+func (r Result) Reasons() []string {
+	reasons := make([]string, len(r.Hits))
+	for i, hit := range r.Hits {
+		reasons[i] = hit.Reason
+	}
+
+	return reasons
+}
+
+// Rule evaluates an attempt and returns a hit if it matches.
+type Rule interface {
+	Evaluate(ctx context.Context, attempt Attempt) (hit Hit, matched bool, err error)
+}
+
+type transactionsRepository interface {
+	CountByPayerSince(ctx context.Context, payer uuid.UUID, since time.Time) (int, error)
+	CountBetween(ctx context.Context, payer, payee uuid.UUID, since time.Time) (int, error)
+}
+
+type reviewsRepository interface {
+	Create(ctx context.Context, review models.FraudReview) (uuid.UUID, error)
+	FindByID(ctx context.Context, id uuid.UUID) (models.FraudReview, error)
+	FindByStatus(ctx context.Context, status models.ReviewStatus, page int) ([]models.FraudReview, error)
+	Resolve(ctx context.Context, review models.FraudReview) (bool, error)
+	Link(ctx context.Context, id, transactionID uuid.UUID) error
+}
+
+type Service struct {
+	repo   reviewsRepository
+	rules  []Rule
+	config Config
+}
+
+func NewService(
+	repo reviewsRepository,
+	transactions transactionsRepository,
+	config Config,
+) *Service {
+	return &Service{
+		repo,
+		config.Rules(transactions),
+		config,
+	}
+}
+
+var (
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrReviewNotPending = errors.New("review already resolved")
+)
+
+// Screen evaluates every configured rule against the attempt and
+// decides whether the transfer can go through based on the total score.
+func (s *Service) Screen(ctx context.Context, attempt Attempt) (Result, error) {
+	if attempt.At.IsZero() {
+		attempt.At = time.Now()
+	}
+
+	var result Result
+	for _, rule := range s.rules {
+		hit, matched, err := rule.Evaluate(ctx, attempt)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if matched {
+			result.Hits = append(result.Hits, hit)
+			result.Score += hit.Score
+		}
+	}
+
+	switch {
+	case result.Score >= s.config.DenyScore:
+		result.Decision = DecisionDeny
+	case result.Score >= s.config.ReviewScore:
+		result.Decision = DecisionReview
+	default:
+		result.Decision = DecisionAllow
+	}
+
+	return result, nil
+}
+
+// Enqueue holds the attempt in the review queue.
+func (s *Service) Enqueue(
+	ctx context.Context,
+	attempt Attempt,
+	result Result,
+) (uuid.UUID, error) {
+	review := models.FraudReview{
+		Payer:         attempt.Payer.ID,
+		Payee:         attempt.Payee.ID,
+		Amount:        attempt.Amount,
+		Score:         result.Score,
+		Reasons:       result.Reasons(),
+		Currency:      attempt.Currency,
+		PayeeCurrency: attempt.PayeeCurrency,
+	}
+
+	return s.repo.Create(ctx, review)
+}
+
+func (s *Service) FindPending(
+	ctx context.Context,
+	page int,
+) ([]models.FraudReview, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	return s.repo.FindByStatus(ctx, models.ReviewPending, page)
+}
+
+// FindPendingByID returns the review only if it still awaits a decision.
+func (s *Service) FindPendingByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.FraudReview, error) {
+	review, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.FraudReview{}, ErrReviewNotFound
+	}
+
+	if review.Status != models.ReviewPending {
+		return models.FraudReview{}, ErrReviewNotPending
+	}
+
+	return review, nil
+}
+
+// Approve claims the review for its transfer, only one of the concurrent
+// approvals or rejections gets it, the others fail with ErrReviewNotPending.
+// It must run in the database transaction of the transfer, which is linked
+// once made, so a failed transfer leaves the review pending.
+func (s *Service) Approve(
+	ctx context.Context,
+	review models.FraudReview,
+	note string,
+) error {
+	review.Status = models.ReviewApproved
+	review.TransactionID = pgtype.UUID{}
+	review.ResolutionNote = note
+
+	return s.resolve(ctx, review)
+}
+
+// Link sets the transaction made after the approval of the review.
+func (s *Service) Link(ctx context.Context, reviewID, transactionID uuid.UUID) error {
+	return s.repo.Link(ctx, reviewID, transactionID)
+}
+
+func (s *Service) Reject(ctx context.Context, id uuid.UUID, note string) error {
+	review, err := s.FindPendingByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	review.Status = models.ReviewRejected
+	review.ResolutionNote = note
+
+	return s.resolve(ctx, review)
+}
+
+func (s *Service) resolve(ctx context.Context, review models.FraudReview) error {
+	resolved, err := s.repo.Resolve(ctx, review)
+	if err != nil {
+		return err
+	}
+
+	if !resolved {
+		return ErrReviewNotPending
+	}
+
+	return nil
+}
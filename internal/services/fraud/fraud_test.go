@@ -0,0 +1,124 @@
+package fraud_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/fraud"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestFraudService_Screen(t *testing.T) {
+	transactionsRepository := &repo.InMemoryTransactionsRepository{}
+	reviewsRepository := &repo.InMemoryFraudReviewsRepository{}
+	sut := fraud.NewService(reviewsRepository, transactionsRepository, fraud.DefaultConfig())
+
+	ctx := context.Background()
+	oldAccount := pgtype.Timestamp{Time: time.Now().Add(-30 * 24 * time.Hour)}
+	payer := models.User{ID: uuid.New(), CreatedAt: oldAccount}
+	payee := models.User{ID: uuid.New(), CreatedAt: oldAccount}
+
+	t.Run("should allow ordinary transfers", func(t *testing.T) {
+		attempt := fraud.Attempt{Payer: payer, Payee: payee, Amount: 100, Equivalent: 100}
+		result, err := sut.Screen(ctx, attempt)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if result.Decision != fraud.DecisionAllow {
+			t.Errorf("expected %v, got %v", fraud.DecisionAllow, result.Decision)
+		}
+	})
+
+	t.Run("should review payers above the velocity limit", func(t *testing.T) {
+		transactionsRepository.Transaction = []models.Transaction{}
+		for range 10 {
+			_, _ = transactionsRepository.Create(ctx, models.Transaction{
+				Amount: 10,
+				Payer:  payer.ID,
+				Payee:  uuid.New(),
+			})
+		}
+
+		attempt := fraud.Attempt{Payer: payer, Payee: payee, Amount: 10, Equivalent: 10}
+		result, err := sut.Screen(ctx, attempt)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if result.Decision != fraud.DecisionReview {
+			t.Errorf("expected %v, got %v", fraud.DecisionReview, result.Decision)
+		}
+	})
+
+	t.Run("should deny new accounts sending large amounts to new payees", func(t *testing.T) {
+		transactionsRepository.Transaction = []models.Transaction{}
+		newPayer := models.User{ID: uuid.New(), CreatedAt: pgtype.Timestamp{Time: time.Now()}}
+
+		attempt := fraud.Attempt{Payer: newPayer, Payee: payee, Amount: 10000, Equivalent: 10000}
+		result, err := sut.Screen(ctx, attempt)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if result.Decision != fraud.DecisionDeny {
+			t.Errorf("expected %v, got %v", fraud.DecisionDeny, result.Decision)
+		}
+
+		if len(result.Hits) != 2 {
+			t.Errorf("expected 2 hits, got %+v", result.Hits)
+		}
+	})
+
+	t.Run("should flag transfers back to a recent payer", func(t *testing.T) {
+		transactionsRepository.Transaction = []models.Transaction{}
+		_, _ = transactionsRepository.Create(ctx, models.Transaction{
+			Amount: 500,
+			Payer:  payee.ID,
+			Payee:  payer.ID,
+		})
+
+		attempt := fraud.Attempt{Payer: payer, Payee: payee, Amount: 500, Equivalent: 500}
+		result, err := sut.Screen(ctx, attempt)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(result.Hits) != 1 || result.Hits[0].Rule != "round_trip" {
+			t.Errorf("expected round trip hit, got %+v", result.Hits)
+		}
+	})
+}
+
+func TestFraudService_Reject(t *testing.T) {
+	reviewsRepository := &repo.InMemoryFraudReviewsRepository{}
+	sut := fraud.NewService(
+		reviewsRepository,
+		&repo.InMemoryTransactionsRepository{},
+		fraud.DefaultConfig(),
+	)
+
+	ctx := context.Background()
+	attempt := fraud.Attempt{
+		Payer:  models.User{ID: uuid.New()},
+		Payee:  models.User{ID: uuid.New()},
+		Amount: 100,
+	}
+
+	id, err := sut.Enqueue(ctx, attempt, fraud.Result{Decision: fraud.DecisionReview, Score: 50})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := sut.Reject(ctx, id, "suspicious"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := sut.Reject(ctx, id, "suspicious"); err != fraud.ErrReviewNotPending {
+		t.Errorf("expected %v, got %v", fraud.ErrReviewNotPending, err)
+	}
+}
@@ -0,0 +1,182 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Config holds the thresholds used to decide on an attempt and the
+// parameters of each rule. A rule with a zero score is disabled.
+type Config struct {
+	ReviewScore int
+	DenyScore   int
+	Velocity    VelocityRule
+	NewPayee    NewPayeeRule
+	NewAccount  NewAccountRule
+	RoundTrip   RoundTripRule
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ReviewScore: 50,
+		DenyScore:   80,
+		Velocity: VelocityRule{
+			MaxTransfers: 10,
+			Window:       10 * time.Minute,
+			Score:        50,
+		},
+		NewPayee: NewPayeeRule{
+			MinAmount: 5000,
+			Score:     30,
+		},
+		NewAccount: NewAccountRule{
+			MaxAge:    24 * time.Hour,
+			MinAmount: 1000,
+			Score:     50,
+		},
+		RoundTrip: RoundTripRule{
+			Window: time.Hour,
+			Score:  30,
+		},
+	}
+}
+
+// Rules returns the enabled rules bound to the transactions repository.
+func (c Config) Rules(transactions transactionsRepository) []Rule {
+	var rules []Rule
+
+	if c.Velocity.Score > 0 {
+		rule := c.Velocity
+		rule.repo = transactions
+		rules = append(rules, rule)
+	}
+
+	if c.NewPayee.Score > 0 {
+		rule := c.NewPayee
+		rule.repo = transactions
+		rules = append(rules, rule)
+	}
+
+	if c.NewAccount.Score > 0 {
+		rules = append(rules, c.NewAccount)
+	}
+
+	if c.RoundTrip.Score > 0 {
+		rule := c.RoundTrip
+		rule.repo = transactions
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// VelocityRule matches payers that made MaxTransfers or more
+// transfers within the window.
+type VelocityRule struct {
+	MaxTransfers int
+	Window       time.Duration
+	Score        int
+
+	repo transactionsRepository
+}
+
+func (r VelocityRule) Evaluate(ctx context.Context, attempt Attempt) (Hit, bool, error) {
+	count, err := r.repo.CountByPayerSince(ctx, attempt.Payer.ID, attempt.At.Add(-r.Window))
+	if err != nil {
+		return Hit{}, false, err
+	}
+
+	if count < r.MaxTransfers {
+		return Hit{}, false, nil
+	}
+
+	return Hit{
+		Rule:   "velocity",
+		Score:  r.Score,
+		Reason: fmt.Sprintf("%d transfers in the last %s", count, r.Window),
+	}, true, nil
+}
+
+// NewPayeeRule matches the first transfer to a payee above MinAmount.
+type NewPayeeRule struct {
+	MinAmount float64
+	Score     int
+
+	repo transactionsRepository
+}
+
+func (r NewPayeeRule) Evaluate(ctx context.Context, attempt Attempt) (Hit, bool, error) {
+	if attempt.Equivalent < r.MinAmount {
+		return Hit{}, false, nil
+	}
+
+	count, err := r.repo.CountBetween(ctx, attempt.Payer.ID, attempt.Payee.ID, time.Time{})
+	if err != nil {
+		return Hit{}, false, err
+	}
+
+	if count > 0 {
+		return Hit{}, false, nil
+	}
+
+	return Hit{
+		Rule:   "new_payee",
+		Score:  r.Score,
+		Reason: fmt.Sprintf("first transfer to payee above %.2f", r.MinAmount),
+	}, true, nil
+}
+
+// NewAccountRule matches accounts younger than MaxAge sending MinAmount or more.
+type NewAccountRule struct {
+	MaxAge    time.Duration
+	MinAmount float64
+	Score     int
+}
+
+func (r NewAccountRule) Evaluate(_ context.Context, attempt Attempt) (Hit, bool, error) {
+	if attempt.Equivalent < r.MinAmount {
+		return Hit{}, false, nil
+	}
+
+	if attempt.At.Sub(attempt.Payer.CreatedAt.Time) >= r.MaxAge {
+		return Hit{}, false, nil
+	}
+
+	return Hit{
+		Rule:   "new_account",
+		Score:  r.Score,
+		Reason: fmt.Sprintf("account younger than %s sending %.2f", r.MaxAge, attempt.Equivalent),
+	}, true, nil
+}
+
+// RoundTripRule matches transfers back to someone who sent money
+// to the payer within the window.
+type RoundTripRule struct {
+	Window time.Duration
+	Score  int
+
+	repo transactionsRepository
+}
+
+func (r RoundTripRule) Evaluate(ctx context.Context, attempt Attempt) (Hit, bool, error) {
+	count, err := r.repo.CountBetween(
+		ctx,
+		attempt.Payee.ID,
+		attempt.Payer.ID,
+		attempt.At.Add(-r.Window),
+	)
+	if err != nil {
+		return Hit{}, false, err
+	}
+
+	if count == 0 {
+		return Hit{}, false, nil
+	}
+
+	return Hit{
+		Rule:   "round_trip",
+		Score:  r.Score,
+		Reason: fmt.Sprintf("payee sent money to payer in the last %s", r.Window),
+	}, true, nil
+}
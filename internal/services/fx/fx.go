@@ -0,0 +1,152 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/Rhymond/go-money"
+)
+
+// RateProvider returns how many units of the target currency
+// one unit of the source currency buys.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	ErrInvalidRate  = errors.New("invalid exchange rate")
+)
+
+// Rates maps a source currency to its rates by target currency,
+// e.g. {"USD": {"BRL": 5.42}}.
+type Rates map[string]map[string]float64
+
+// Find looks for the direct rate and falls back to the inverse one.
+func (r Rates) Find(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	if rate, ok := r[from][to]; ok {
+		if rate <= 0 {
+			return 0, ErrInvalidRate
+		}
+		return rate, nil
+	}
+
+	if rate, ok := r[to][from]; ok {
+		if rate <= 0 {
+			return 0, ErrInvalidRate
+		}
+		return 1 / rate, nil
+	}
+
+	return 0, ErrRateNotFound
+}
+
+// StaticProvider serves a fixed set of rates, mostly useful for tests.
+type StaticProvider struct {
+	Rates Rates
+}
+
+func NewStaticProvider(rates Rates) *StaticProvider {
+	return &StaticProvider{
+		rates,
+	}
+}
+
+func (p *StaticProvider) Rate(_ context.Context, from, to string) (float64, error) {
+	return p.Rates.Find(from, to)
+}
+
+// FileProvider reads the rates from a local JSON file on every
+// lookup, so the file can be updated without restarting the server.
+type FileProvider struct {
+	Path string
+}
+
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{
+		path,
+	}
+}
+
+func (p *FileProvider) Rate(_ context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	if p.Path == "" {
+		return 0, ErrRateNotFound
+	}
+
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return 0, fmt.Errorf("read rates file: %w", err)
+	}
+
+	var rates Rates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return 0, fmt.Errorf("decode rates file: %w", err)
+	}
+
+	return rates.Find(from, to)
+}
+
+// Quote is the result of a conversion, the rate is the one given by
+// the provider and the spread is the fraction kept on the conversion.
+type Quote struct {
+	Rate   float64
+	Spread float64
+	Amount *money.Money
+}
+
+type Service struct {
+	provider RateProvider
+	spread   float64
+}
+
+func NewService(provider RateProvider, spread float64) *Service {
+	return &Service{
+		provider,
+		spread,
+	}
+}
+
+// Convert converts the amount to the target currency applying the spread.
+func (s *Service) Convert(
+	ctx context.Context,
+	amount *money.Money,
+	to string,
+) (Quote, error) {
+	from := amount.Currency()
+	if from.Code == to {
+		return Quote{Rate: 1, Amount: amount}, nil
+	}
+
+	rate, err := s.provider.Rate(ctx, from.Code, to)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	target := money.GetCurrency(to)
+	if target == nil {
+		return Quote{}, ErrRateNotFound
+	}
+
+	// Amounts are kept in minor units, so the value has to be scaled
+	// when the currencies have a different number of decimal places.
+	scale := math.Pow10(target.Fraction - from.Fraction)
+	converted := float64(amount.Amount()) * rate * (1 - s.spread) * scale
+
+	return Quote{
+		Rate:   rate,
+		Spread: s.spread,
+		Amount: money.New(int64(math.Round(converted)), to),
+	}, nil
+}
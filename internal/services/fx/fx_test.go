@@ -0,0 +1,64 @@
+package fx_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/services/fx"
+)
+
+func TestExchangeService_Convert(t *testing.T) {
+	ctx := context.Background()
+	sut := fx.NewService(fx.NewStaticProvider(fx.Rates{"USD": {"BRL": 5}}), 0.02)
+
+	testCases := []struct {
+		amount *money.Money
+		to     string
+		want   int64
+	}{
+		{money.New(10000, money.USD), money.BRL, 49000},
+		{money.New(50000, money.BRL), money.USD, 9800},
+		{money.New(100, money.BRL), money.BRL, 100},
+		{money.New(10000, money.USD), money.JPY, 0},
+	}
+
+	for _, tc := range testCases {
+		from := tc.amount.Display()
+
+		quote, err := sut.Convert(ctx, tc.amount, tc.to)
+		if tc.want == 0 {
+			if err != fx.ErrRateNotFound {
+				t.Errorf("Convert(%s, %s) got %v, want %v", from, tc.to, err, fx.ErrRateNotFound)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Convert(%s, %s) got %v, want nil", from, tc.to, err)
+		}
+
+		if got := quote.Amount.Amount(); got != tc.want {
+			t.Errorf("Convert(%s, %s) got %d, want %d", from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"EUR": {"BRL": 6.1}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sut := fx.NewFileProvider(path)
+	rate, err := sut.Rate(context.Background(), money.EUR, money.BRL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if rate != 6.1 {
+		t.Errorf("expected rate to be 6.1, got %v", rate)
+	}
+}
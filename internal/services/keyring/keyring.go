@@ -0,0 +1,155 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Keyring encrypts personal data with AES-GCM. Every ciphertext carries
+// the ID of its key, so rotating keys only requires adding a new one and
+// making it current, the old ones are kept to decrypt the existing data.
+//
+// Encrypted values can't be searched, so a blind index, a keyed HMAC of
+// the plaintext, is stored along with them for lookups and uniqueness.
+type Keyring struct {
+	current  string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+var (
+	ErrUnknownKey        = errors.New("unknown encryption key")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Length of the AES-256 and HMAC-SHA256 keys
+const keySize = 32
+
+// New creates a keyring that encrypts with the key of the current ID.
+func New(current string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+	if len(indexKey) < keySize {
+		return nil, fmt.Errorf("index key must have at least %d bytes", keySize)
+	}
+
+	k := &Keyring{
+		current:  current,
+		keys:     make(map[string]cipher.AEAD, len(keys)),
+		indexKey: indexKey,
+	}
+
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %s must have %d bytes", id, keySize)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		k.keys[id], err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := k.keys[current]; !ok {
+		return nil, fmt.Errorf("current key %q: %w", current, ErrUnknownKey)
+	}
+
+	return k, nil
+}
+
+// Parse loads the keyring from keys, a comma separated list of id:base64
+// keys, the ID of the current key, which encrypts new data, and the base64
+// key of the blind index.
+func Parse(keys, current, indexKey string) (*Keyring, error) {
+	decoded := make(map[string][]byte)
+	for _, entry := range strings.Split(keys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, errors.New("keys must be a comma separated list of id:base64 keys")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+
+		decoded[id] = key
+	}
+
+	index, err := base64.StdEncoding.DecodeString(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("index key: %w", err)
+	}
+
+	return New(current, decoded, index)
+}
+
+// Encrypt returns the ciphertext as <key id>:<base64 nonce and sealed data>
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead := k.keys[k.current]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.current))
+	return k.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	id, encoded, ok := strings.Cut(ciphertext, ":")
+	if !ok {
+		return "", ErrInvalidCiphertext
+	}
+
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("key %q: %w", id, ErrUnknownKey)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	// The key id is authenticated so it can't be swapped
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(plaintext), nil
+}
+
+// CurrentPrefix starts the ciphertexts encrypted with the current key,
+// the ones without it should be encrypted again after a rotation.
+func (k *Keyring) CurrentPrefix() string {
+	return k.current + ":"
+}
+
+// BlindIndex returns the hex encoded HMAC-SHA256 of the value. The index
+// key is not rotated with the encryption keys, as it would change the
+// index of every value at once.
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,93 @@
+package keyring_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/edulustosa/go-pay/internal/services/keyring"
+)
+
+var (
+	oldKey   = bytes.Repeat([]byte{1}, 32)
+	newKey   = bytes.Repeat([]byte{2}, 32)
+	indexKey = bytes.Repeat([]byte{3}, 32)
+)
+
+func TestKeyring(t *testing.T) {
+	old, err := keyring.New("k1", map[string][]byte{"k1": oldKey}, indexKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rotated, err := keyring.New("k2", map[string][]byte{"k1": oldKey, "k2": newKey}, indexKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	t.Run("should encrypt with a random nonce", func(t *testing.T) {
+		first, _ := old.Encrypt("52998224725")
+		second, _ := old.Encrypt("52998224725")
+
+		if first == second || strings.Contains(first, "52998224725") {
+			t.Errorf("expected distinct opaque ciphertexts, got %s and %s", first, second)
+		}
+
+		plaintext, err := old.Decrypt(first)
+		if err != nil || plaintext != "52998224725" {
+			t.Errorf("expected the document back, got %q, %v", plaintext, err)
+		}
+	})
+
+	t.Run("should decrypt data of rotated keys", func(t *testing.T) {
+		ciphertext, _ := old.Encrypt("52998224725")
+		if !strings.HasPrefix(ciphertext, old.CurrentPrefix()) ||
+			strings.HasPrefix(ciphertext, rotated.CurrentPrefix()) {
+			t.Errorf("expected %s to be stale after the rotation", ciphertext)
+		}
+
+		if plaintext, err := rotated.Decrypt(ciphertext); err != nil || plaintext != "52998224725" {
+			t.Errorf("expected the document back, got %q, %v", plaintext, err)
+		}
+
+		ciphertext, _ = rotated.Encrypt("52998224725")
+		if _, err := old.Decrypt(ciphertext); !errors.Is(err, keyring.ErrUnknownKey) {
+			t.Errorf("expected %v, got %v", keyring.ErrUnknownKey, err)
+		}
+	})
+
+	t.Run("should reject tampered ciphertexts", func(t *testing.T) {
+		ciphertext, _ := rotated.Encrypt("52998224725")
+
+		// The key id is authenticated along with the data
+		swapped := "k1" + strings.TrimPrefix(ciphertext, "k2")
+		if _, err := rotated.Decrypt(swapped); !errors.Is(err, keyring.ErrInvalidCiphertext) {
+			t.Errorf("expected %v, got %v", keyring.ErrInvalidCiphertext, err)
+		}
+
+		if _, err := rotated.Decrypt("52998224725"); !errors.Is(err, keyring.ErrInvalidCiphertext) {
+			t.Errorf("expected %v, got %v", keyring.ErrInvalidCiphertext, err)
+		}
+	})
+
+	t.Run("should keep the blind index across rotations", func(t *testing.T) {
+		if old.BlindIndex("52998224725") != rotated.BlindIndex("52998224725") {
+			t.Errorf("expected the same index with the same index key")
+		}
+
+		if old.BlindIndex("52998224725") == old.BlindIndex("16899535009") {
+			t.Errorf("expected distinct indexes for distinct documents")
+		}
+	})
+
+	t.Run("should validate the keys", func(t *testing.T) {
+		if _, err := keyring.New("k3", map[string][]byte{"k1": oldKey}, indexKey); !errors.Is(err, keyring.ErrUnknownKey) {
+			t.Errorf("expected %v, got %v", keyring.ErrUnknownKey, err)
+		}
+
+		if _, err := keyring.New("k1", map[string][]byte{"k1": oldKey[:16]}, indexKey); err == nil {
+			t.Errorf("expected short keys to be rejected")
+		}
+	})
+}
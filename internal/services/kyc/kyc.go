@@ -0,0 +1,263 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type userRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+	UpdateKYCLevel(ctx context.Context, id uuid.UUID, level models.KYCLevel) error
+}
+
+type submissionsRepository interface {
+	Create(ctx context.Context, submission models.KYCSubmission) (uuid.UUID, error)
+	FindByID(ctx context.Context, id uuid.UUID) (models.KYCSubmission, error)
+	FindLatestByUser(ctx context.Context, userID uuid.UUID) (models.KYCSubmission, error)
+	FindByStatus(ctx context.Context, status models.ReviewStatus, page int) ([]models.KYCSubmission, error)
+	HasPending(ctx context.Context, userID uuid.UUID) (bool, error)
+	Resolve(ctx context.Context, submission models.KYCSubmission) (bool, error)
+}
+
+// Service promotes users through the KYC levels once the support
+// staff reviews the data they submitted.
+type Service struct {
+	repo  submissionsRepository
+	users userRepository
+}
+
+func NewService(repo submissionsRepository, users userRepository) *Service {
+	return &Service{
+		repo,
+		users,
+	}
+}
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrSubmissionNotFound   = errors.New("kyc submission not found")
+	ErrSubmissionNotPending = errors.New("kyc submission already reviewed")
+	ErrSubmissionPending    = errors.New("kyc submission awaiting review")
+	ErrInvalidLevel         = errors.New("kyc levels must be reached in order")
+	ErrUnderage             = errors.New("users must be at least 18 years old")
+	ErrSelfReview           = errors.New("staff cannot review their own submission")
+	ErrReasonRequired       = errors.New("rejection reason required")
+)
+
+const minimumAge = 18
+
+// Limits are the features unlocked by a KYC level, MaxTransfer is the
+// BRL equivalent allowed per transfer and zero means no limit.
+type Limits struct {
+	MaxTransfer float64
+	Withdrawals bool
+}
+
+var levels = []models.KYCLevel{
+	models.KYCBasic,
+	models.KYCIntermediate,
+	models.KYCFull,
+}
+
+var limits = map[models.KYCLevel]Limits{
+	models.KYCBasic:        {MaxTransfer: 1000},
+	models.KYCIntermediate: {MaxTransfer: 10000, Withdrawals: true},
+	models.KYCFull:         {Withdrawals: true},
+}
+
+// LimitsFor returns the limits of the level, unknown levels get
+// the ones of the basic level.
+func LimitsFor(level models.KYCLevel) Limits {
+	if l, ok := limits[level]; ok {
+		return l
+	}
+
+	return limits[models.KYCBasic]
+}
+
+func next(level models.KYCLevel) models.KYCLevel {
+	for i, l := range levels[:len(levels)-1] {
+		if l == level {
+			return levels[i+1]
+		}
+	}
+
+	return ""
+}
+
+// Submit sends the data of the next level of the user for review, an
+// user only has one submission awaiting review at a time.
+func (s *Service) Submit(
+	ctx context.Context,
+	userID uuid.UUID,
+	submissionDTO dtos.KYCSubmissionDTO,
+) (models.KYCSubmission, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return models.KYCSubmission{}, ErrUserNotFound
+	}
+
+	if submissionDTO.Level != next(user.KYCLevel) {
+		return models.KYCSubmission{}, ErrInvalidLevel
+	}
+
+	pending, err := s.repo.HasPending(ctx, userID)
+	if err != nil {
+		return models.KYCSubmission{}, err
+	}
+
+	if pending {
+		return models.KYCSubmission{}, ErrSubmissionPending
+	}
+
+	submission := models.KYCSubmission{
+		UserID:    userID,
+		Level:     submissionDTO.Level,
+		Documents: submissionDTO.Documents,
+		Status:    models.ReviewPending,
+	}
+
+	if submissionDTO.Level == models.KYCIntermediate {
+		birthDate, err := time.Parse(time.DateOnly, submissionDTO.BirthDate)
+		if err != nil {
+			return models.KYCSubmission{}, err
+		}
+
+		if birthDate.AddDate(minimumAge, 0, 0).After(time.Now()) {
+			return models.KYCSubmission{}, ErrUnderage
+		}
+
+		submission.BirthDate = pgtype.Date{Time: birthDate, Valid: true}
+		submission.AddressLine = submissionDTO.Address.Line
+		submission.City = submissionDTO.Address.City
+		submission.State = submissionDTO.Address.State
+		submission.PostalCode = strings.ReplaceAll(submissionDTO.Address.PostalCode, "-", "")
+	}
+
+	if submission.Documents == nil {
+		submission.Documents = []models.KYCDocument{}
+	}
+
+	submission.ID, err = s.repo.Create(ctx, submission)
+	if err != nil {
+		return models.KYCSubmission{}, err
+	}
+
+	return submission, nil
+}
+
+// Status returns the level of the user and its latest submission,
+// which is empty if the user never submitted any data.
+func (s *Service) Status(
+	ctx context.Context,
+	userID uuid.UUID,
+) (models.KYCLevel, models.KYCSubmission, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return "", models.KYCSubmission{}, ErrUserNotFound
+	}
+
+	submission, err := s.repo.FindLatestByUser(ctx, userID)
+	if err != nil {
+		return user.KYCLevel, models.KYCSubmission{}, nil
+	}
+
+	return user.KYCLevel, submission, nil
+}
+
+func (s *Service) FindPending(
+	ctx context.Context,
+	page int,
+) ([]models.KYCSubmission, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	return s.repo.FindByStatus(ctx, models.ReviewPending, page)
+}
+
+func (s *Service) findPendingByID(
+	ctx context.Context,
+	id, reviewerID uuid.UUID,
+) (models.KYCSubmission, error) {
+	submission, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.KYCSubmission{}, ErrSubmissionNotFound
+	}
+
+	if submission.Status != models.ReviewPending {
+		return models.KYCSubmission{}, ErrSubmissionNotPending
+	}
+
+	if submission.UserID == reviewerID {
+		return models.KYCSubmission{}, ErrSelfReview
+	}
+
+	return submission, nil
+}
+
+// Approve promotes the user to the level of the submission.
+func (s *Service) Approve(
+	ctx context.Context,
+	id, reviewerID uuid.UUID,
+	note string,
+) error {
+	submission, err := s.findPendingByID(ctx, id, reviewerID)
+	if err != nil {
+		return err
+	}
+
+	submission.Status = models.ReviewApproved
+	submission.ReviewedBy = pgtype.UUID{Bytes: reviewerID, Valid: true}
+	submission.ReviewNote = note
+
+	resolved, err := s.repo.Resolve(ctx, submission)
+	if err != nil {
+		return err
+	}
+
+	if !resolved {
+		return ErrSubmissionNotPending
+	}
+
+	return s.users.UpdateKYCLevel(ctx, submission.UserID, submission.Level)
+}
+
+// Reject requires a reason, which is shown to the user so
+// it can fix the data before submitting it again.
+func (s *Service) Reject(
+	ctx context.Context,
+	id, reviewerID uuid.UUID,
+	reason string,
+) error {
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+
+	submission, err := s.findPendingByID(ctx, id, reviewerID)
+	if err != nil {
+		return err
+	}
+
+	submission.Status = models.ReviewRejected
+	submission.ReviewedBy = pgtype.UUID{Bytes: reviewerID, Valid: true}
+	submission.ReviewNote = reason
+
+	resolved, err := s.repo.Resolve(ctx, submission)
+	if err != nil {
+		return err
+	}
+
+	if !resolved {
+		return ErrSubmissionNotPending
+	}
+
+	return nil
+}
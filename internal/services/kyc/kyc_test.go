@@ -0,0 +1,114 @@
+package kyc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/kyc"
+	"github.com/google/uuid"
+)
+
+func TestKYCService(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	submissionsRepository := &repo.InMemoryKYCSubmissionsRepository{}
+	sut := kyc.NewService(submissionsRepository, userRepository)
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+	})
+	reviewerID := uuid.New()
+
+	intermediate := dtos.KYCSubmissionDTO{
+		Level:     models.KYCIntermediate,
+		BirthDate: "1990-05-20",
+		Address: &dtos.KYCAddressDTO{
+			Line:       "Av. Paulista, 1000",
+			City:       "São Paulo",
+			State:      "SP",
+			PostalCode: "01310-100",
+		},
+	}
+
+	t.Run("should only submit the next level", func(t *testing.T) {
+		_, err := sut.Submit(ctx, userID, dtos.KYCSubmissionDTO{Level: models.KYCFull})
+		if err != kyc.ErrInvalidLevel {
+			t.Errorf("expected %v, got %v", kyc.ErrInvalidLevel, err)
+		}
+	})
+
+	t.Run("should not accept underage users", func(t *testing.T) {
+		underage := intermediate
+		underage.BirthDate = time.Now().AddDate(-17, 0, 0).Format(time.DateOnly)
+
+		if _, err := sut.Submit(ctx, userID, underage); err != kyc.ErrUnderage {
+			t.Errorf("expected %v, got %v", kyc.ErrUnderage, err)
+		}
+	})
+
+	t.Run("should keep one submission pending per user", func(t *testing.T) {
+		submission, err := sut.Submit(ctx, userID, intermediate)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if submission.PostalCode != "01310100" || !submission.BirthDate.Valid {
+			t.Errorf("expected normalized address data, got %+v", submission)
+		}
+
+		if _, err := sut.Submit(ctx, userID, intermediate); err != kyc.ErrSubmissionPending {
+			t.Errorf("expected %v, got %v", kyc.ErrSubmissionPending, err)
+		}
+	})
+
+	t.Run("should require a reason to reject", func(t *testing.T) {
+		id := submissionsRepository.Submissions[0].ID
+
+		if err := sut.Reject(ctx, id, reviewerID, " "); err != kyc.ErrReasonRequired {
+			t.Errorf("expected %v, got %v", kyc.ErrReasonRequired, err)
+		}
+
+		if err := sut.Reject(ctx, id, reviewerID, "address proof unreadable"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sut.Approve(ctx, id, reviewerID, ""); err != kyc.ErrSubmissionNotPending {
+			t.Errorf("expected %v, got %v", kyc.ErrSubmissionNotPending, err)
+		}
+	})
+
+	t.Run("should promote the user when approved", func(t *testing.T) {
+		submission, err := sut.Submit(ctx, userID, intermediate)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sut.Approve(ctx, submission.ID, userID, ""); err != kyc.ErrSelfReview {
+			t.Errorf("expected %v, got %v", kyc.ErrSelfReview, err)
+		}
+
+		if err := sut.Approve(ctx, submission.ID, reviewerID, "ok"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		level, latest, err := sut.Status(ctx, userID)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if level != models.KYCIntermediate || latest.Status != models.ReviewApproved {
+			t.Errorf("expected approved intermediate level, got %v %+v", level, latest)
+		}
+
+		if !kyc.LimitsFor(level).Withdrawals {
+			t.Errorf("expected withdrawals to be allowed")
+		}
+	})
+}
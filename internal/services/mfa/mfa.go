@@ -0,0 +1,307 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+type factorsRepository interface {
+	Save(ctx context.Context, factor models.TOTPFactor) (bool, error)
+	FindByUser(ctx context.Context, userID uuid.UUID) (models.TOTPFactor, error)
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	Delete(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error)
+	RecordFailure(ctx context.Context, userID uuid.UUID, maxAttempts int, lockout time.Duration) (bool, error)
+	ResetFailures(ctx context.Context, userID uuid.UUID) error
+}
+
+type userService interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+}
+
+// Config sets which operations require a fresh code, besides the
+// ones that always do, like creating API keys and changing passwords.
+type Config struct {
+	// Transfers with a BRL equivalent above it require a code
+	TransferThreshold float64
+
+	// After MaxAttempts invalid codes in a row every code is rejected
+	// for the Lockout, so the codes cannot be guessed by brute force
+	MaxAttempts int
+	Lockout     time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TransferThreshold: 5000,
+		MaxAttempts:       5,
+		Lockout:           15 * time.Minute,
+	}
+}
+
+type Service struct {
+	repo   factorsRepository
+	user   userService
+	config Config
+}
+
+func NewService(repo factorsRepository, user userService, config Config) *Service {
+	return &Service{
+		repo,
+		user,
+		config,
+	}
+}
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrAlreadyEnrolled  = errors.New("two-factor authentication already enabled")
+	ErrNotEnrolled      = errors.New("two-factor authentication is not enabled")
+	ErrCodeRequired     = errors.New("two-factor authentication code required")
+	ErrInvalidCode      = errors.New("invalid two-factor authentication code")
+	ErrEnrollmentNeeded = errors.New("two-factor authentication must be enabled for this operation")
+	ErrTooManyAttempts  = errors.New("too many invalid two-factor authentication codes, try again later")
+)
+
+const issuer = "go-pay"
+
+const recoveryCodesCount = 10
+
+// Enrollment is shown once to the user to set up the authenticator.
+type Enrollment struct {
+	Secret string
+	URI    string
+}
+
+// Enroll generates a new secret for the user, which only protects the
+// account after being confirmed with a code from the authenticator.
+func (s *Service) Enroll(ctx context.Context, userID uuid.UUID) (Enrollment, error) {
+	user, err := s.user.FindByID(ctx, userID)
+	if err != nil {
+		return Enrollment{}, ErrUserNotFound
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	saved, err := s.repo.Save(ctx, models.TOTPFactor{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if !saved {
+		return Enrollment{}, ErrAlreadyEnrolled
+	}
+
+	return Enrollment{
+		Secret: secret,
+		URI:    keyURI(issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables two-factor authentication with the first code of the
+// authenticator and returns the recovery codes, which are only shown once.
+func (s *Service) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	factor, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, ErrNotEnrolled
+	}
+
+	if factor.ConfirmedAt.Valid {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	err = s.attempt(ctx, factor, func() error {
+		return s.useCode(ctx, factor, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(ctx, userID)
+}
+
+func (s *Service) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	factor, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return false, nil
+	}
+
+	return factor.ConfirmedAt.Valid, nil
+}
+
+// Verify accepts either a code of the authenticator or an unused
+// recovery code, each of them only once.
+func (s *Service) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	factor, err := s.repo.FindByUser(ctx, userID)
+	if err != nil || !factor.ConfirmedAt.Valid {
+		return ErrNotEnrolled
+	}
+
+	if code == "" {
+		return ErrCodeRequired
+	}
+
+	return s.attempt(ctx, factor, func() error {
+		if len(code) == digits {
+			return s.useCode(ctx, factor, code)
+		}
+
+		used, err := s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+
+		if !used {
+			return ErrInvalidCode
+		}
+
+		return nil
+	})
+}
+
+// attempt checks a code with check unless the factor is locked, counting
+// the invalid ones and locking the factor after too many in a row.
+func (s *Service) attempt(ctx context.Context, factor models.TOTPFactor, check func() error) error {
+	if factor.LockedUntil.Valid && factor.LockedUntil.Time.After(time.Now()) {
+		return ErrTooManyAttempts
+	}
+
+	err := check()
+	if errors.Is(err, ErrInvalidCode) {
+		locked, failErr := s.repo.RecordFailure(ctx, factor.UserID, s.config.MaxAttempts, s.config.Lockout)
+		if failErr != nil {
+			return failErr
+		}
+
+		if locked {
+			return ErrTooManyAttempts
+		}
+
+		return err
+	}
+
+	if err == nil && factor.FailedAttempts > 0 {
+		return s.repo.ResetFailures(ctx, factor.UserID)
+	}
+
+	return err
+}
+
+// StepUp requires a fresh code from users about to make a sensitive
+// operation, users without two-factor authentication must enable it first.
+func (s *Service) StepUp(ctx context.Context, userID uuid.UUID, code string) error {
+	err := s.Verify(ctx, userID, code)
+	if errors.Is(err, ErrNotEnrolled) {
+		return ErrEnrollmentNeeded
+	}
+
+	return err
+}
+
+// StepUpIfEnabled only requires a fresh code from users with two-factor
+// authentication enabled, for operations every user must be able to make.
+func (s *Service) StepUpIfEnabled(ctx context.Context, userID uuid.UUID, code string) error {
+	err := s.Verify(ctx, userID, code)
+	if errors.Is(err, ErrNotEnrolled) {
+		return nil
+	}
+
+	return err
+}
+
+// StepUpTransfer only requires a code for transfers above the threshold.
+func (s *Service) StepUpTransfer(
+	ctx context.Context,
+	userID uuid.UUID,
+	equivalent float64,
+	code string,
+) error {
+	if equivalent <= s.config.TransferThreshold {
+		return nil
+	}
+
+	return s.StepUp(ctx, userID, code)
+}
+
+// Disable requires a code so a stolen session cannot turn it off.
+func (s *Service) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, userID)
+}
+
+// RegenerateRecoveryCodes invalidates the previous recovery codes.
+func (s *Service) RegenerateRecoveryCodes(
+	ctx context.Context,
+	userID uuid.UUID,
+	code string,
+) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	return s.newRecoveryCodes(ctx, userID)
+}
+
+func (s *Service) useCode(ctx context.Context, factor models.TOTPFactor, code string) error {
+	step, ok := validate(factor.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	used, err := s.repo.UseStep(ctx, factor.UserID, step)
+	if err != nil {
+		return err
+	}
+
+	// The code, or a later one, was already used
+	if !used {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Recovery codes are formatted as xxxxx-xxxxx to be easier to write down.
+func (s *Service) newRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		c := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = c[:5] + "-" + c[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,205 @@
+package mfa_test
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/mfa"
+	"github.com/edulustosa/go-pay/internal/services/user"
+)
+
+func TestCode(t *testing.T) {
+	// SHA1 test vectors of RFC 6238, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	secret = strings.TrimRight(secret, "=")
+
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range testCases {
+		got, err := mfa.Code(secret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if got != tc.want {
+			t.Errorf("Code(%d) got %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestMFAService(t *testing.T) {
+	factorsRepository := &repo.InMemoryTOTPFactorsRepository{}
+	userRepository := &repo.InMemoryUserRepository{}
+	sut := mfa.NewService(factorsRepository, user.NewService(userRepository, user.DefaultConfig()), mfa.DefaultConfig())
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+	})
+
+	enrollment, err := sut.Enroll(ctx, userID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/go-pay:johndoe@email.com?") {
+		t.Errorf("expected otpauth uri, got %s", enrollment.URI)
+	}
+
+	if err := sut.StepUp(ctx, userID, "000000"); !errors.Is(err, mfa.ErrEnrollmentNeeded) {
+		t.Errorf("expected error %v before confirmation, got %v", mfa.ErrEnrollmentNeeded, err)
+	}
+
+	code, _ := mfa.Code(enrollment.Secret, time.Now().Add(-30*time.Second))
+	recoveryCodes, err := sut.Confirm(ctx, userID, code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(recoveryCodes) != 10 {
+		t.Fatalf("expected 10 recovery codes, got %d", len(recoveryCodes))
+	}
+
+	t.Run("should not enroll twice", func(t *testing.T) {
+		if _, err := sut.Enroll(ctx, userID); !errors.Is(err, mfa.ErrAlreadyEnrolled) {
+			t.Errorf("expected error %v, got %v", mfa.ErrAlreadyEnrolled, err)
+		}
+	})
+
+	t.Run("should accept each code only once", func(t *testing.T) {
+		code, _ := mfa.Code(enrollment.Secret, time.Now())
+		if err := sut.StepUp(ctx, userID, code); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sut.StepUp(ctx, userID, code); !errors.Is(err, mfa.ErrInvalidCode) {
+			t.Errorf("expected error %v, got %v", mfa.ErrInvalidCode, err)
+		}
+	})
+
+	t.Run("should accept each recovery code only once", func(t *testing.T) {
+		if err := sut.Verify(ctx, userID, strings.ToUpper(recoveryCodes[0])); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sut.Verify(ctx, userID, recoveryCodes[0]); !errors.Is(err, mfa.ErrInvalidCode) {
+			t.Errorf("expected error %v, got %v", mfa.ErrInvalidCode, err)
+		}
+	})
+
+	t.Run("should only require a code above the transfer threshold", func(t *testing.T) {
+		if err := sut.StepUpTransfer(ctx, userID, 5000, ""); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		if err := sut.StepUpTransfer(ctx, userID, 5000.01, ""); !errors.Is(err, mfa.ErrCodeRequired) {
+			t.Errorf("expected error %v, got %v", mfa.ErrCodeRequired, err)
+		}
+	})
+
+	t.Run("should disable with a recovery code", func(t *testing.T) {
+		if err := sut.Disable(ctx, userID, recoveryCodes[1]); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if enabled, _ := sut.Enabled(ctx, userID); enabled {
+			t.Error("expected two-factor authentication to be disabled")
+		}
+
+		if len(factorsRepository.RecoveryCodes) != 0 {
+			t.Errorf("expected recovery codes to be removed, got %d", len(factorsRepository.RecoveryCodes))
+		}
+	})
+}
+
+func TestMFAService_Lockout(t *testing.T) {
+	factorsRepository := &repo.InMemoryTOTPFactorsRepository{}
+	userRepository := &repo.InMemoryUserRepository{}
+	config := mfa.DefaultConfig()
+	config.MaxAttempts = 3
+	sut := mfa.NewService(factorsRepository, user.NewService(userRepository, user.DefaultConfig()), config)
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+	})
+
+	enrollment, _ := sut.Enroll(ctx, userID)
+	code, _ := mfa.Code(enrollment.Secret, time.Now().Add(-30*time.Second))
+	recoveryCodes, err := sut.Confirm(ctx, userID, code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	wrongCode := func() string {
+		code, _ := mfa.Code(enrollment.Secret, time.Now())
+		if code == "000000" {
+			return "111111"
+		}
+		return "000000"
+	}
+
+	t.Run("should start counting over after a valid code", func(t *testing.T) {
+		for range config.MaxAttempts - 1 {
+			if err := sut.Verify(ctx, userID, wrongCode()); !errors.Is(err, mfa.ErrInvalidCode) {
+				t.Fatalf("expected error %v, got %v", mfa.ErrInvalidCode, err)
+			}
+		}
+
+		if err := sut.Verify(ctx, userID, recoveryCodes[0]); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sut.Verify(ctx, userID, wrongCode()); !errors.Is(err, mfa.ErrInvalidCode) {
+			t.Errorf("expected error %v, got %v", mfa.ErrInvalidCode, err)
+		}
+	})
+
+	t.Run("should lock the codes after too many invalid ones", func(t *testing.T) {
+		for range config.MaxAttempts - 2 {
+			_ = sut.Verify(ctx, userID, wrongCode())
+		}
+
+		if err := sut.Verify(ctx, userID, wrongCode()); !errors.Is(err, mfa.ErrTooManyAttempts) {
+			t.Fatalf("expected error %v, got %v", mfa.ErrTooManyAttempts, err)
+		}
+
+		// Not even valid codes are accepted while locked
+		code, _ := mfa.Code(enrollment.Secret, time.Now())
+		if err := sut.StepUp(ctx, userID, code); !errors.Is(err, mfa.ErrTooManyAttempts) {
+			t.Errorf("expected error %v, got %v", mfa.ErrTooManyAttempts, err)
+		}
+
+		if err := sut.Disable(ctx, userID, recoveryCodes[1]); !errors.Is(err, mfa.ErrTooManyAttempts) {
+			t.Errorf("expected error %v, got %v", mfa.ErrTooManyAttempts, err)
+		}
+	})
+
+	t.Run("should accept codes again after the lockout", func(t *testing.T) {
+		factorsRepository.Factors[0].LockedUntil.Time = time.Now().Add(-time.Second)
+
+		if err := sut.Verify(ctx, userID, recoveryCodes[1]); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters of RFC 6238 supported by every authenticator app.
+const (
+	period      = 30
+	digits      = 6
+	secretBytes = 20
+	// Steps before and after the current one accepted
+	// to tolerate clock drift between the devices.
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+func step(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+// HOTP of RFC 4226 for the given step.
+func hotp(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%uint32(math.Pow10(digits))), nil
+}
+
+// Code returns the code shown by an authenticator with the secret at t.
+func Code(secret string, t time.Time) (string, error) {
+	return hotp(secret, step(t))
+}
+
+// Returns the step the code was generated for, if it is valid at t.
+func validate(secret, candidate string, t time.Time) (int64, bool) {
+	if len(candidate) != digits {
+		return 0, false
+	}
+
+	current := step(t)
+	for s := current - skew; s <= current+skew; s++ {
+		expected, err := hotp(secret, s)
+		if err != nil {
+			return 0, false
+		}
+
+		if hmac.Equal([]byte(expected), []byte(candidate)) {
+			return s, true
+		}
+	}
+
+	return 0, false
+}
+
+// Key URI understood by authenticator apps, usually shown as a QR code.
+func keyURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(digits)},
+		"period":    {fmt.Sprint(period)},
+	}
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func normalizeCode(c string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(c))
+}
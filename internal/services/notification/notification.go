@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/dtos"
@@ -12,7 +13,20 @@ import (
 
 var ErrNotificationUnavailable = errors.New("notification service unavailable")
 
-func Send(user *models.User, message string) error {
+// Client sends the messages through the external notification service.
+type Client struct {
+	url  string
+	http *http.Client
+}
+
+func NewClient(url string, timeout time.Duration) *Client {
+	return &Client{
+		url,
+		&http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) Notify(user *models.User, message string) error {
 	notification := dtos.NotificationDTO{
 		Email:   user.Email,
 		Message: message,
@@ -25,7 +39,7 @@ func Send(user *models.User, message string) error {
 
 	req, err := http.NewRequest(
 		http.MethodPost,
-		"https://util.devi.tools/api/v1/notify",
+		c.url,
 		bytes.NewReader(reqBytes),
 	)
 	if err != nil {
@@ -33,8 +47,7 @@ func Send(user *models.User, message string) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return err
 	}
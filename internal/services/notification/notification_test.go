@@ -2,6 +2,7 @@ package notification_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/services/notification"
@@ -19,7 +20,8 @@ func TestNotificationService(t *testing.T) {
 		Role:      models.RoleCommon,
 	}
 
-	if err := notification.Send(user, "test message"); err != nil {
+	sut := notification.NewClient("https://util.devi.tools/api/v1/notify", 10*time.Second)
+	if err := sut.Notify(user, "test message"); err != nil {
 		if err == notification.ErrNotificationUnavailable {
 			t.Skip("notification service unavailable")
 		}
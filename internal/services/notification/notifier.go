@@ -0,0 +1,16 @@
+package notification
+
+import "github.com/edulustosa/go-pay/internal/database/models"
+
+// Notifier delivers a message to the user, services depend on it
+// instead of Client so the messages can be captured in tests.
+type Notifier interface {
+	Notify(user *models.User, message string) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(user *models.User, message string) error
+
+func (f NotifierFunc) Notify(user *models.User, message string) error {
+	return f(user, message)
+}
@@ -0,0 +1,228 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/services/account"
+	"github.com/edulustosa/go-pay/internal/services/audit"
+	"github.com/google/uuid"
+)
+
+type reconciliationRepository interface {
+	Discrepancies(ctx context.Context, each func(models.Discrepancy) error) error
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]models.Discrepancy, error)
+}
+
+type accountService interface {
+	Freeze(ctx context.Context, userID uuid.UUID, reason string, frozenBy uuid.UUID) error
+}
+
+type auditService interface {
+	Record(ctx context.Context, entry audit.Entry) error
+}
+
+type Config struct {
+	// Freeze freezes the accounts with a discrepancy until staff
+	// looks into them, otherwise they are only reported.
+	Freeze bool
+
+	// Recheck is how long to wait before checking the discrepancies
+	// again. The balances and the ledger are written one after the
+	// other, so an operation in flight looks like a discrepancy.
+	Recheck time.Duration
+}
+
+// Service finds the balances that drifted from the ones rebuilt from
+// the transfers, deposits and withdrawals of the users.
+type Service struct {
+	repo     reconciliationRepository
+	accounts accountService
+	audit    auditService
+	config   Config
+}
+
+func NewService(
+	repo reconciliationRepository,
+	accounts accountService,
+	audit auditService,
+	config Config,
+) *Service {
+	return &Service{
+		repo,
+		accounts,
+		audit,
+		config,
+	}
+}
+
+// The reason of the accounts frozen by the reconciliation
+const freezeReason = "balance reconciliation discrepancy"
+
+type Report struct {
+	StartedAt     time.Time            `json:"startedAt"`
+	FinishedAt    time.Time            `json:"finishedAt"`
+	Discrepancies []models.Discrepancy `json:"discrepancies"`
+	Frozen        []uuid.UUID          `json:"frozen"`
+}
+
+// Difference is how much the balance exceeds the expected one, in minor
+// units, it is negative when money is missing from the balance.
+func Difference(d models.Discrepancy) int64 {
+	expected := money.NewFromFloat(d.Expected, d.Currency)
+	actual := money.NewFromFloat(d.Actual, d.Currency)
+
+	return actual.Amount() - expected.Amount()
+}
+
+// Reconcile reports the discrepancies found twice, Recheck apart, with the
+// same difference, freezing their accounts if configured. The ones that
+// changed meanwhile are left to the next run.
+func (s *Service) Reconcile(ctx context.Context) (Report, error) {
+	report := Report{
+		StartedAt:     time.Now(),
+		Discrepancies: []models.Discrepancy{},
+		Frozen:        []uuid.UUID{},
+	}
+
+	var users []uuid.UUID
+	candidates := make(map[uuid.UUID][]models.Discrepancy)
+	err := s.repo.Discrepancies(ctx, func(d models.Discrepancy) error {
+		if _, ok := candidates[d.UserID]; !ok {
+			users = append(users, d.UserID)
+		}
+		candidates[d.UserID] = append(candidates[d.UserID], d)
+
+		return nil
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	if len(users) > 0 {
+		select {
+		case <-ctx.Done():
+			return Report{}, ctx.Err()
+		case <-time.After(s.config.Recheck):
+		}
+	}
+
+	for _, userID := range users {
+		current, err := s.repo.FindByUser(ctx, userID)
+		if err != nil {
+			return Report{}, err
+		}
+
+		confirmed := confirm(candidates[userID], current)
+		if len(confirmed) == 0 {
+			continue
+		}
+
+		for _, d := range confirmed {
+			slog.Warn(
+				"balance discrepancy",
+				"user", d.UserID,
+				"currency", d.Currency,
+				"expected", d.Expected,
+				"actual", d.Actual,
+			)
+		}
+		report.Discrepancies = append(report.Discrepancies, confirmed...)
+
+		if s.config.Freeze {
+			frozen, err := s.freeze(ctx, userID, confirmed)
+			if err != nil {
+				return Report{}, err
+			}
+
+			if frozen {
+				report.Frozen = append(report.Frozen, userID)
+			}
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// confirm returns the discrepancies of current found before with the same difference.
+func confirm(before, current []models.Discrepancy) []models.Discrepancy {
+	var confirmed []models.Discrepancy
+	for _, c := range current {
+		for _, b := range before {
+			if b.Currency == c.Currency && Difference(b) == Difference(c) {
+				confirmed = append(confirmed, c)
+				break
+			}
+		}
+	}
+
+	return confirmed
+}
+
+// freeze returns false if the account was not active.
+func (s *Service) freeze(
+	ctx context.Context,
+	userID uuid.UUID,
+	discrepancies []models.Discrepancy,
+) (bool, error) {
+	err := s.accounts.Freeze(ctx, userID, freezeReason, uuid.Nil)
+	if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrUserNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to freeze account %s: %w", userID, err)
+	}
+
+	slog.Warn("account frozen", "user", userID, "reason", freezeReason)
+
+	// Frozen by the system, the event has no actor
+	err = s.audit.Record(ctx, audit.Entry{
+		Action: models.AuditUserFreeze,
+		Target: userID,
+		Before: map[string]any{"status": models.StatusActive},
+		After: map[string]any{
+			"status":        models.StatusFrozen,
+			"reason":        freezeReason,
+			"discrepancies": discrepancies,
+		},
+	})
+	if err != nil {
+		slog.Error("failed to record audit event", "error", err, "user", userID)
+	}
+
+	return true, nil
+}
+
+// Run reconciles the balances on every interval until the context is done.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Reconcile(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("failed to reconcile balances", "error", err)
+				}
+				continue
+			}
+
+			slog.Info(
+				"balances reconciled",
+				"discrepancies", len(report.Discrepancies),
+				"frozen", len(report.Frozen),
+				"duration", report.FinishedAt.Sub(report.StartedAt),
+			)
+		}
+	}
+}
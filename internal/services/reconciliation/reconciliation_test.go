@@ -0,0 +1,202 @@
+package reconciliation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/account"
+	"github.com/edulustosa/go-pay/internal/services/audit"
+	"github.com/edulustosa/go-pay/internal/services/reconciliation"
+	"github.com/google/uuid"
+)
+
+// settling runs after the first pass over the discrepancies, as
+// an operation finishing between the passes would.
+type settling struct {
+	*repo.InMemoryReconciliationRepository
+	after func()
+}
+
+func (s *settling) Discrepancies(ctx context.Context, each func(models.Discrepancy) error) error {
+	err := s.InMemoryReconciliationRepository.Discrepancies(ctx, each)
+	if s.after != nil {
+		s.after()
+	}
+
+	return err
+}
+
+type fixture struct {
+	repo   *settling
+	users  *repo.InMemoryUserRepository
+	events *repo.InMemoryAuditEventsRepository
+	ids    []uuid.UUID
+}
+
+func setup(t *testing.T) fixture {
+	t.Helper()
+
+	ctx := context.Background()
+	f := fixture{
+		users:  &repo.InMemoryUserRepository{},
+		events: &repo.InMemoryAuditEventsRepository{},
+	}
+	statementsRepository := &repo.InMemoryStatementsRepository{}
+	walletsRepository := &repo.InMemoryWalletsRepository{}
+	f.repo = &settling{InMemoryReconciliationRepository: &repo.InMemoryReconciliationRepository{
+		Statements: statementsRepository,
+		Users:      f.users,
+		Wallets:    walletsRepository,
+	}}
+
+	for i, email := range []string{"johndoe@email.com", "janedoe@email.com"} {
+		id, _ := f.users.Create(ctx, models.User{
+			FirstName: "John",
+			LastName:  "Doe",
+			Email:     email,
+			Document:  "1234567890" + string(rune('0'+i)),
+			Balance:   70.3,
+			Status:    models.StatusActive,
+		})
+		walletsRepository.Create(ctx, models.Wallet{UserID: id, Currency: "USD"})
+		walletsRepository.Wallets[i].Balance = 10
+
+		for _, entry := range []struct {
+			currency string
+			amount   float64
+		}{{"BRL", 100}, {"BRL", -29.8}, {"BRL", 0.1}, {"USD", 10}} {
+			statementsRepository.Ledger = append(statementsRepository.Ledger, repo.InMemoryLedgerEntry{
+				UserID:   id,
+				Currency: entry.currency,
+				StatementEntry: models.StatementEntry{
+					ID:     uuid.New(),
+					Kind:   models.EntryDeposit,
+					Amount: entry.amount,
+				},
+			})
+		}
+
+		f.ids = append(f.ids, id)
+	}
+
+	return f
+}
+
+func (f fixture) service(freeze bool) *reconciliation.Service {
+	accountService := account.NewService(
+		f.users,
+		&repo.InMemoryWalletsRepository{},
+		&repo.InMemorySessionsRepository{},
+		&repo.InMemoryAPIKeysRepository{},
+	)
+
+	return reconciliation.NewService(
+		f.repo,
+		accountService,
+		audit.NewService(f.events),
+		reconciliation.Config{Freeze: freeze, Recheck: time.Millisecond},
+	)
+}
+
+func TestReconciliationService_Reconcile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should find no discrepancies on matching balances", func(t *testing.T) {
+		f := setup(t)
+
+		report, err := f.service(true).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(report.Discrepancies) != 0 || len(report.Frozen) != 0 {
+			t.Errorf("expected no discrepancies, got %+v", report)
+		}
+	})
+
+	t.Run("should report the drifted balances", func(t *testing.T) {
+		f := setup(t)
+		f.users.Users[1].Balance = 80.3
+
+		report, err := f.service(false).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(report.Discrepancies) != 1 {
+			t.Fatalf("expected 1 discrepancy, got %+v", report.Discrepancies)
+		}
+
+		d := report.Discrepancies[0]
+		if d.UserID != f.ids[1] || d.Currency != "BRL" || d.Expected != 70.3 || d.Actual != 80.3 {
+			t.Errorf("expected the BRL balance of the second user, got %+v", d)
+		}
+
+		if reconciliation.Difference(d) != 1000 {
+			t.Errorf("expected a difference of 1000, got %d", reconciliation.Difference(d))
+		}
+
+		if len(report.Frozen) != 0 || f.users.Users[1].Status != models.StatusActive {
+			t.Errorf("expected no account to be frozen")
+		}
+	})
+
+	t.Run("should freeze the accounts with discrepancies", func(t *testing.T) {
+		f := setup(t)
+		f.users.Users[0].Balance = 0
+
+		report, err := f.service(true).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(report.Frozen) != 1 || report.Frozen[0] != f.ids[0] {
+			t.Fatalf("expected the first user to be frozen, got %+v", report.Frozen)
+		}
+
+		user := f.users.Users[0]
+		if user.Status != models.StatusFrozen || user.FrozenBy.Valid {
+			t.Errorf("expected the account frozen by the system, got %s by %v", user.Status, user.FrozenBy)
+		}
+
+		if len(f.events.Events) != 1 || f.events.Events[0].Action != models.AuditUserFreeze {
+			t.Fatalf("expected the freeze to be audited, got %+v", f.events.Events)
+		}
+
+		if f.events.Events[0].ActorID.Valid {
+			t.Errorf("expected no actor")
+		}
+
+		report, err = f.service(true).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(report.Discrepancies) != 1 || len(report.Frozen) != 0 {
+			t.Errorf("expected the frozen account to be reported again only, got %+v", report)
+		}
+	})
+
+	t.Run("should ignore operations in flight", func(t *testing.T) {
+		f := setup(t)
+		f.users.Users[0].Balance = 50.3
+		f.users.Users[1].Balance = 50.3
+		f.repo.after = func() {
+			// The first one settles, the second one moves again
+			f.users.Users[0].Balance = 70.3
+			f.users.Users[1].Balance = 60.3
+		}
+
+		report, err := f.service(true).Reconcile(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(report.Discrepancies) != 0 || len(report.Frozen) != 0 {
+			t.Errorf("expected no discrepancies, got %+v", report)
+		}
+	})
+}
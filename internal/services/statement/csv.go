@@ -0,0 +1,46 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/Rhymond/go-money"
+)
+
+// csvWriter writes a row per entry between the opening and closing
+// balance rows, which have no amount.
+type csvWriter struct {
+	w      *csv.Writer
+	header Header
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Begin(h Header) error {
+	c.header = h
+
+	return c.w.WriteAll([][]string{
+		{"date", "type", "description", "reference", "amount", "balance"},
+		{h.From.Format(time.RFC3339), "OPENING_BALANCE", "Opening balance", "", "", decimal(h.Opening)},
+	})
+}
+
+func (c *csvWriter) Line(l Line) error {
+	return c.w.Write([]string{
+		l.Entry.CreatedAt.Time.UTC().Format(time.RFC3339),
+		string(l.Entry.Kind),
+		description(l.Entry),
+		l.Entry.ID.String(),
+		decimal(l.Amount),
+		decimal(l.Balance),
+	})
+}
+
+func (c *csvWriter) End(closing *money.Money) error {
+	return c.w.WriteAll([][]string{
+		{c.header.To.Format(time.RFC3339), "CLOSING_BALANCE", "Closing balance", "", "", decimal(closing)},
+	})
+}
@@ -0,0 +1,37 @@
+package statement
+
+import "io"
+
+type Format string
+
+const (
+	FormatCSV Format = "csv"
+	FormatOFX Format = "ofx"
+	FormatPDF Format = "pdf"
+)
+
+// ContentType is the media type of the statements in the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// NewWriter returns the writer of the statements in the format.
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatOFX:
+		return newOFXWriter(w), nil
+	case FormatPDF:
+		return newPDFWriter(w), nil
+	default:
+		return nil, ErrInvalidFormat
+	}
+}
@@ -0,0 +1,118 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+)
+
+// The bank of the OFX accounts, there is no routing number to use
+const ofxBankID = "GOPAY"
+
+// Longest NAME of an OFX transaction
+const ofxNameSize = 32
+
+// ofxWriter writes an OFX 2.2 bank statement. OFX has no opening balance,
+// the entries are followed by the closing one as the ledger balance.
+type ofxWriter struct {
+	w      *bufio.Writer
+	header Header
+}
+
+func newOFXWriter(w io.Writer) *ofxWriter {
+	return &ofxWriter{w: bufio.NewWriter(w)}
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+func ofxText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (o *ofxWriter) Begin(h Header) error {
+	o.header = h
+
+	fmt.Fprint(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`+"\n")
+	fmt.Fprint(o.w, `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n")
+	fmt.Fprintf(o.w, `<OFX>
+<SIGNONMSGSRSV1><SONRS>
+<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<DTSERVER>%s</DTSERVER><LANGUAGE>POR</LANGUAGE>
+</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS>
+<TRNUID>0</TRNUID>
+<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS>
+<CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`,
+		ofxTime(time.Now()),
+		h.Currency,
+		ofxBankID,
+		h.UserID,
+		ofxTime(h.From),
+		ofxTime(h.To),
+	)
+
+	return o.w.Flush()
+}
+
+func (o *ofxWriter) Line(l Line) error {
+	trnType := "CREDIT"
+	if l.Amount.IsNegative() {
+		trnType = "DEBIT"
+	}
+
+	// A failed withdrawal has its hold and its reversal, which
+	// need different ids as they are in the same statement
+	fitID := l.Entry.ID.String()
+	if l.Entry.Kind == models.EntryWithdrawalReversal {
+		fitID += "-R"
+	}
+
+	name := description(l.Entry)
+	if l.Entry.Counterparty.Valid {
+		name = string(l.Entry.Kind)
+	}
+
+	_, err := fmt.Fprintf(o.w, `<STMTTRN>
+<TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT>
+<FITID>%s</FITID><NAME>%s</NAME><MEMO>%s</MEMO>
+</STMTTRN>
+`,
+		trnType,
+		ofxTime(l.Entry.CreatedAt.Time),
+		decimal(l.Amount),
+		fitID,
+		ofxText(name[:min(len(name), ofxNameSize)]),
+		ofxText(description(l.Entry)),
+	)
+
+	return err
+}
+
+func (o *ofxWriter) End(closing *money.Money) error {
+	fmt.Fprintf(o.w, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+</STMTRS>
+</STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`,
+		decimal(closing),
+		ofxTime(o.header.To),
+	)
+
+	return o.w.Flush()
+}
@@ -0,0 +1,187 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Rhymond/go-money"
+)
+
+// Layout of the A4 pages, in points, with Courier so the columns align
+const (
+	pdfWidth       = 595
+	pdfHeight      = 842
+	pdfMargin      = 40
+	pdfFontSize    = 9
+	pdfLeading     = 12
+	pdfLinesOnPage = (pdfHeight - 2*pdfMargin) / pdfLeading
+)
+
+// Objects written before the pages, the pages tree is written
+// last, once every page is known, but its number is reserved.
+const (
+	pdfCatalogObject = 1
+	pdfPagesObject   = 2
+	pdfFontObject    = 3
+)
+
+// pdfWriter writes a simple text PDF, page by page, so only the current
+// page is kept in memory. The offsets of the objects are tracked to write
+// the cross-reference table at the end.
+type pdfWriter struct {
+	w       io.Writer
+	written int
+	offsets map[int]int
+	objects int
+	pages   []int
+	page    bytes.Buffer
+	lines   int
+	header  Header
+	err     error
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{
+		w:       w,
+		offsets: make(map[int]int),
+		objects: pdfFontObject,
+	}
+}
+
+func (p *pdfWriter) write(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.written += n
+	p.err = err
+}
+
+func (p *pdfWriter) object(number int, body string) {
+	p.offsets[number] = p.written
+	p.write("%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+// pdfText escapes the text of a string literal. Courier only has the
+// Latin-1 characters of WinAnsiEncoding, the others are replaced.
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 0x20 && r < 0x7f || r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
+
+func (p *pdfWriter) line(text string) {
+	if p.lines == pdfLinesOnPage {
+		p.flushPage()
+	}
+
+	if p.lines == 0 {
+		fmt.Fprintf(&p.page, "BT /F1 %d Tf %d TL %d %d Td\n",
+			pdfFontSize, pdfLeading, pdfMargin, pdfHeight-pdfMargin)
+	}
+
+	fmt.Fprintf(&p.page, "(%s) Tj T*\n", pdfText(text))
+	p.lines++
+}
+
+func (p *pdfWriter) flushPage() {
+	if p.lines == 0 {
+		return
+	}
+	p.page.WriteString("ET")
+
+	content := p.objects + 1
+	page := p.objects + 2
+	p.objects += 2
+
+	p.offsets[content] = p.written
+	p.write("%d 0 obj\n<< /Length %d >>\nstream\n", content, p.page.Len())
+	p.write("%s\nendstream\nendobj\n", p.page.Bytes())
+
+	p.object(page, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObject, pdfWidth, pdfHeight, pdfFontObject, content,
+	))
+	p.pages = append(p.pages, page)
+
+	p.page.Reset()
+	p.lines = 0
+}
+
+// Columns of the entries: date, description, amount and balance
+const pdfRow = "%-16s  %-50s  %12s  %12s"
+
+func (p *pdfWriter) Begin(h Header) error {
+	p.header = h
+
+	p.write("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	p.object(pdfCatalogObject, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObject))
+	p.object(pdfFontObject,
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	p.line("Account statement")
+	p.line("")
+	p.line("Holder:   " + h.Holder)
+	p.line("Account:  " + h.UserID.String())
+	p.line(fmt.Sprintf("Period:   %s to %s",
+		h.From.Format(time.DateOnly),
+		h.To.AddDate(0, 0, -1).Format(time.DateOnly),
+	))
+	p.line("Currency: " + h.Currency)
+	p.line("")
+	p.line(fmt.Sprintf(pdfRow, "Date", "Description", "Amount", "Balance"))
+	p.line(fmt.Sprintf(pdfRow, h.From.Format("2006-01-02 15:04"), "Opening balance", "", decimal(h.Opening)))
+
+	return p.err
+}
+
+func (p *pdfWriter) Line(l Line) error {
+	p.line(fmt.Sprintf(pdfRow,
+		l.Entry.CreatedAt.Time.UTC().Format("2006-01-02 15:04"),
+		description(l.Entry),
+		decimal(l.Amount),
+		decimal(l.Balance),
+	))
+
+	return p.err
+}
+
+func (p *pdfWriter) End(closing *money.Money) error {
+	p.line(fmt.Sprintf(pdfRow, p.header.To.Format("2006-01-02 15:04"), "Closing balance", "", decimal(closing)))
+	p.flushPage()
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	p.object(pdfPagesObject, fmt.Sprintf(
+		"<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(p.pages),
+	))
+
+	xref := p.written
+	p.write("xref\n0 %d\n0000000000 65535 f \n", p.objects+1)
+	for number := 1; number <= p.objects; number++ {
+		p.write("%010d 00000 n \n", p.offsets[number])
+	}
+	p.write("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		p.objects+1, pdfCatalogObject, xref)
+
+	return p.err
+}
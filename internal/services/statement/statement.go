@@ -0,0 +1,171 @@
+package statement
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+type statementsRepository interface {
+	BalanceAt(
+		ctx context.Context,
+		userID uuid.UUID,
+		currency string,
+		at time.Time,
+	) (float64, error)
+	Entries(
+		ctx context.Context,
+		userID uuid.UUID,
+		currency string,
+		from, to time.Time,
+		each func(models.StatementEntry) error,
+	) error
+}
+
+type userService interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+}
+
+type Service struct {
+	repo  statementsRepository
+	users userService
+}
+
+func NewService(repo statementsRepository, users userService) *Service {
+	return &Service{
+		repo,
+		users,
+	}
+}
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrInvalidRange    = errors.New("the start of the period must not be after its end")
+	ErrInvalidCurrency = errors.New("invalid currency")
+	ErrInvalidFormat   = errors.New("invalid statement format")
+)
+
+// Header opens a statement, the period includes From and excludes To.
+type Header struct {
+	UserID   uuid.UUID
+	Holder   string
+	Currency string
+	From     time.Time
+	To       time.Time
+	Opening  *money.Money
+}
+
+// Line is an entry of the statement with the balance right after it.
+type Line struct {
+	Entry   models.StatementEntry
+	Amount  *money.Money
+	Balance *money.Money
+}
+
+// Writer renders a statement in a format as it is generated, the
+// lines are written as they are read and are not kept.
+type Writer interface {
+	Begin(h Header) error
+	Line(l Line) error
+	End(closing *money.Money) error
+}
+
+// Prepare validates the statement of the user between the first and the
+// last day, both included, and computes its opening balance. Nothing is
+// written yet, so its errors can still be returned to the client.
+func (s *Service) Prepare(
+	ctx context.Context,
+	userID uuid.UUID,
+	currency string,
+	firstDay, lastDay time.Time,
+) (Header, error) {
+	if money.GetCurrency(currency) == nil {
+		return Header{}, ErrInvalidCurrency
+	}
+
+	if firstDay.After(lastDay) {
+		return Header{}, ErrInvalidRange
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return Header{}, ErrUserNotFound
+	}
+
+	from := day(firstDay)
+	opening, err := s.repo.BalanceAt(ctx, userID, currency, from)
+	if err != nil {
+		return Header{}, err
+	}
+
+	return Header{
+		UserID:   user.ID,
+		Holder:   user.FirstName + " " + user.LastName,
+		Currency: currency,
+		From:     from,
+		To:       day(lastDay).AddDate(0, 0, 1),
+		Opening:  money.NewFromFloat(opening, currency),
+	}, nil
+}
+
+// day is the start of the day of the time in UTC
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Write streams the statement to the writer, every entry with the
+// balance after it, closing with the balance at the end of the period.
+func (s *Service) Write(ctx context.Context, h Header, w Writer) error {
+	if err := w.Begin(h); err != nil {
+		return err
+	}
+
+	balance := h.Opening
+	err := s.repo.Entries(ctx, h.UserID, h.Currency, h.From, h.To,
+		func(entry models.StatementEntry) error {
+			amount := money.NewFromFloat(entry.Amount, h.Currency)
+
+			var err error
+			balance, err = balance.Add(amount)
+			if err != nil {
+				return err
+			}
+
+			return w.Line(Line{Entry: entry, Amount: amount, Balance: balance})
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return w.End(balance)
+}
+
+// decimal formats the amount with a dot and without thousands
+// separators, the way every format expects it.
+func decimal(m *money.Money) string {
+	return strconv.FormatFloat(m.AsMajorUnits(), 'f', m.Currency().Fraction, 64)
+}
+
+func description(entry models.StatementEntry) string {
+	switch entry.Kind {
+	case models.EntryTransferOut:
+		return "Transfer to " + uuid.UUID(entry.Counterparty.Bytes).String()
+	case models.EntryTransferIn:
+		return "Transfer from " + uuid.UUID(entry.Counterparty.Bytes).String()
+	case models.EntryDeposit:
+		return "Deposit"
+	case models.EntryWithdrawal:
+		return "Withdrawal"
+	case models.EntryWithdrawalReversal:
+		return "Withdrawal reversal"
+	default:
+		return string(entry.Kind)
+	}
+}
@@ -0,0 +1,236 @@
+package statement_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/statement"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func day(d int, hour int) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC), Valid: true}
+}
+
+func setup(t *testing.T) (*statement.Service, *repo.InMemoryStatementsRepository, uuid.UUID) {
+	t.Helper()
+
+	userRepository := &repo.InMemoryUserRepository{}
+	userID, _ := userRepository.Create(context.Background(), models.User{
+		FirstName: "João",
+		LastName:  "Silva",
+		Email:     "joao@email.com",
+		Document:  "12345678900",
+	})
+
+	payee := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	withdrawalID := uuid.New()
+	statementsRepository := &repo.InMemoryStatementsRepository{}
+	for _, entry := range []models.StatementEntry{
+		{ID: uuid.New(), Kind: models.EntryDeposit, Amount: 100, CreatedAt: day(1, 10)},
+		{ID: uuid.New(), Kind: models.EntryTransferOut, Amount: -30.1, CreatedAt: day(5, 10), Counterparty: payee},
+		{ID: uuid.New(), Kind: models.EntryTransferIn, Amount: 0.2, CreatedAt: day(6, 10), Counterparty: payee},
+		{ID: withdrawalID, Kind: models.EntryWithdrawal, Amount: -50, CreatedAt: day(10, 9)},
+		{ID: withdrawalID, Kind: models.EntryWithdrawalReversal, Amount: 50, CreatedAt: day(10, 10)},
+		{ID: uuid.New(), Kind: models.EntryDeposit, Amount: 5, CreatedAt: day(20, 10)},
+	} {
+		statementsRepository.Ledger = append(statementsRepository.Ledger, repo.InMemoryLedgerEntry{
+			UserID:         userID,
+			Currency:       "BRL",
+			StatementEntry: entry,
+		})
+	}
+
+	return statement.NewService(statementsRepository, userRepository), statementsRepository, userID
+}
+
+func generate(t *testing.T, sut *statement.Service, userID uuid.UUID, format statement.Format) []byte {
+	t.Helper()
+
+	ctx := context.Background()
+	header, err := sut.Prepare(ctx, userID, "BRL", day(5, 0).Time, day(10, 0).Time)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	writer, err := statement.NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := sut.Write(ctx, header, writer); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestStatementService_Prepare(t *testing.T) {
+	sut, _, userID := setup(t)
+	ctx := context.Background()
+
+	t.Run("should not prepare invalid statements", func(t *testing.T) {
+		testCases := []struct {
+			user     uuid.UUID
+			currency string
+			from, to time.Time
+			want     error
+		}{
+			{userID, "BRL", day(10, 0).Time, day(5, 0).Time, statement.ErrInvalidRange},
+			{userID, "XYZ", day(5, 0).Time, day(10, 0).Time, statement.ErrInvalidCurrency},
+			{uuid.New(), "BRL", day(5, 0).Time, day(10, 0).Time, statement.ErrUserNotFound},
+		}
+
+		for _, tc := range testCases {
+			_, err := sut.Prepare(ctx, tc.user, tc.currency, tc.from, tc.to)
+			if err != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, err)
+			}
+		}
+	})
+
+	t.Run("should include the last day", func(t *testing.T) {
+		header, err := sut.Prepare(ctx, userID, "BRL", day(5, 15).Time, day(5, 15).Time)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !header.From.Equal(day(5, 0).Time) || !header.To.Equal(day(6, 0).Time) {
+			t.Errorf("expected the whole day 5, got [%v, %v)", header.From, header.To)
+		}
+
+		if header.Opening.Amount() != 10000 || header.Holder != "João Silva" {
+			t.Errorf("expected the opening balance of 100.00, got %+v", header)
+		}
+	})
+}
+
+func TestStatementService_CSV(t *testing.T) {
+	sut, _, userID := setup(t)
+
+	rows, err := csv.NewReader(bytes.NewReader(generate(t, sut, userID, statement.FormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("expected a valid csv, got %v", err)
+	}
+
+	want := [][2]string{
+		{"OPENING_BALANCE", "100.00"},
+		{"TRANSFER_OUT", "69.90"},
+		{"TRANSFER_IN", "70.10"},
+		{"WITHDRAWAL", "20.10"},
+		{"WITHDRAWAL_REVERSAL", "70.10"},
+		{"CLOSING_BALANCE", "70.10"},
+	}
+
+	if len(rows) != len(want)+1 {
+		t.Fatalf("expected a header and %d rows, got %v", len(want), rows)
+	}
+
+	for i, w := range want {
+		row := rows[i+1]
+		if row[1] != w[0] || row[5] != w[1] {
+			t.Errorf("row %d: expected %s with balance %s, got %v", i, w[0], w[1], row)
+		}
+	}
+}
+
+func TestStatementService_OFX(t *testing.T) {
+	sut, _, userID := setup(t)
+	ofx := generate(t, sut, userID, statement.FormatOFX)
+
+	var doc struct {
+		Transactions []struct {
+			Type   string `xml:"TRNTYPE"`
+			Amount string `xml:"TRNAMT"`
+			FITID  string `xml:"FITID"`
+		} `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>BANKTRANLIST>STMTTRN"`
+		Balance string `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS>LEDGERBAL>BALAMT"`
+	}
+	if err := xml.Unmarshal(ofx, &doc); err != nil {
+		t.Fatalf("expected a valid ofx, got %v", err)
+	}
+
+	if len(doc.Transactions) != 4 || doc.Balance != "70.10" {
+		t.Fatalf("expected 4 transactions and a balance of 70.10, got %+v", doc)
+	}
+
+	if doc.Transactions[0].Type != "DEBIT" || doc.Transactions[0].Amount != "-30.10" {
+		t.Errorf("expected a debit of -30.10, got %+v", doc.Transactions[0])
+	}
+
+	if doc.Transactions[2].FITID == doc.Transactions[3].FITID {
+		t.Errorf("expected unique ids, got %s twice", doc.Transactions[2].FITID)
+	}
+}
+
+func TestStatementService_PDF(t *testing.T) {
+	sut, statementsRepository, userID := setup(t)
+
+	// Enough entries for a few pages
+	for i := range 200 {
+		statementsRepository.Ledger = append(statementsRepository.Ledger, repo.InMemoryLedgerEntry{
+			UserID:   userID,
+			Currency: "BRL",
+			StatementEntry: models.StatementEntry{
+				ID:        uuid.New(),
+				Kind:      models.EntryDeposit,
+				Amount:    1,
+				CreatedAt: pgtype.Timestamp{Time: day(7, 0).Time.Add(time.Duration(i) * time.Minute), Valid: true},
+			},
+		})
+	}
+
+	pdf := generate(t, sut, userID, statement.FormatPDF)
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("expected a pdf document")
+	}
+
+	if !bytes.Contains(pdf, []byte("Jo\xe3o Silva")) || !bytes.Contains(pdf, []byte("270.10")) {
+		t.Errorf("expected the holder and the closing balance of 270.10")
+	}
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if match == nil {
+		t.Fatalf("expected the cross-reference offset")
+	}
+
+	xrefOffset, _ := strconv.Atoi(string(match[1]))
+	xref := string(pdf[xrefOffset:])
+	if !strings.HasPrefix(xref, "xref\n") {
+		t.Fatalf("expected the cross-reference table at %d", xrefOffset)
+	}
+
+	var first, count int
+	fmt.Sscanf(xref, "xref\n%d %d\n", &first, &count)
+	if count < 7 || !bytes.Contains(pdf, []byte(fmt.Sprintf("/Count %d", (count-4)/2))) {
+		t.Fatalf("expected many pages, got %d objects", count)
+	}
+
+	entries := strings.Split(xref, "\n")[3 : 3+count-1]
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[:10])
+		object := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(pdf[offset:], []byte(object)) {
+			t.Errorf("expected object %d at %d", i+1, offset)
+		}
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	if _, err := statement.NewWriter("xls", io.Discard); err != statement.ErrInvalidFormat {
+		t.Errorf("expected %v, got %v", statement.ErrInvalidFormat, err)
+	}
+}
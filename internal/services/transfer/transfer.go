@@ -6,11 +6,17 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/Rhymond/go-money"
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/cursor"
+	"github.com/edulustosa/go-pay/internal/services/fraud"
+	"github.com/edulustosa/go-pay/internal/services/fx"
+	"github.com/edulustosa/go-pay/internal/services/kyc"
 	"github.com/edulustosa/go-pay/internal/services/notification"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
 	"github.com/google/uuid"
 )
 
@@ -19,25 +25,81 @@ type transactionsRepository interface {
 		ctx context.Context,
 		transaction models.Transaction,
 	) (uuid.UUID, error)
+	FindByUser(
+		ctx context.Context,
+		userID uuid.UUID,
+		cursor *models.Cursor,
+		limit int,
+	) ([]models.Transaction, error)
+	CountByUser(ctx context.Context, userID uuid.UUID) (int, error)
 }
 
 type userService interface {
 	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
-	UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error
+}
+
+type walletService interface {
+	Balance(ctx context.Context, user *models.User, currency string) (*money.Money, error)
+	Add(ctx context.Context, userID uuid.UUID, amount *money.Money) (wallet.Change, error)
+}
+
+type exchangeService interface {
+	Convert(ctx context.Context, amount *money.Money, to string) (fx.Quote, error)
+}
+
+type transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactionAuthorizer interface {
+	Authorize(ctx context.Context) error
+}
+
+type fraudService interface {
+	Screen(ctx context.Context, attempt fraud.Attempt) (fraud.Result, error)
+	Enqueue(ctx context.Context, attempt fraud.Attempt, result fraud.Result) (uuid.UUID, error)
+	FindPendingByID(ctx context.Context, id uuid.UUID) (models.FraudReview, error)
+	Approve(ctx context.Context, review models.FraudReview, note string) error
+	Link(ctx context.Context, reviewID, transactionID uuid.UUID) error
+}
+
+type stepUpService interface {
+	StepUpTransfer(ctx context.Context, userID uuid.UUID, equivalent float64, code string) error
 }
 
 type Service struct {
-	repo transactionsRepository
-	user userService
+	repo       transactionsRepository
+	tx         transactor
+	user       userService
+	wallet     walletService
+	exchange   exchangeService
+	authorizer transactionAuthorizer
+	fraud      fraudService
+	stepUp     stepUpService
+	notifier   notification.Notifier
 }
 
 func NewService(
 	repo transactionsRepository,
+	tx transactor,
 	user userService,
+	wallet walletService,
+	exchange exchangeService,
+	authorizer transactionAuthorizer,
+	fraud fraudService,
+	stepUp stepUpService,
+	notifier notification.Notifier,
 ) *Service {
 	return &Service{
 		repo,
+		tx,
 		user,
+		wallet,
+		exchange,
+		authorizer,
+		fraud,
+		stepUp,
+		notifier,
 	}
 }
 
@@ -46,14 +108,46 @@ var (
 	ErrInsufficientFunds        = errors.New("insufficient funds")
 	ErrTransactionNotAuthorized = errors.New("transaction not authorized")
 	ErrUserNotFound             = errors.New("user not found")
+	ErrTransactionDenied        = errors.New("transaction denied")
+	ErrTransactionUnderReview   = errors.New("transaction under review")
+	ErrWalletNotFound           = errors.New("wallet not found")
+	ErrConversionUnavailable    = errors.New("currency conversion unavailable")
+	ErrEmailNotVerified         = errors.New("payer email not verified")
+	ErrAccountFrozen            = errors.New("account frozen")
+	ErrKYCLevelRequired         = errors.New("kyc level does not allow this amount")
 )
 
-func validateTransaction(payer *models.User, amount *money.Money) error {
+// order is a transfer ready to be executed, the quote holds the
+// amount credited to the payee in the payee currency and the
+// equivalent its value in the default currency.
+type order struct {
+	payer      models.User
+	payee      models.User
+	amount     *money.Money
+	quote      fx.Quote
+	equivalent *money.Money
+}
+
+// Receipt is a transfer made, along with how it changed the balances
+// of both users and the exchange rate and spread of its conversion.
+type Receipt struct {
+	TransactionID uuid.UUID
+	Payer         wallet.Change
+	Payee         wallet.Change
+	FXRate        float64
+	FXSpread      float64
+}
+
+func validateTransaction(payer *models.User, balance, amount *money.Money) error {
 	if payer.Role == models.RoleMerchant {
 		return ErrMerchantNotAllowed
 	}
 
-	balance := money.NewFromFloat(payer.Balance, money.BRL)
+	// Unverified users can receive but not send money
+	if !payer.EmailVerifiedAt.Valid {
+		return ErrEmailNotVerified
+	}
+
 	ok, err := balance.LessThan(amount)
 	if err != nil {
 		return err
@@ -68,77 +162,285 @@ func validateTransaction(payer *models.User, amount *money.Money) error {
 func (s *Service) NewTransaction(
 	ctx context.Context,
 	transactionDTO dtos.TransactionDTO,
-) (uuid.UUID, error) {
-	payer, err := s.user.FindByID(ctx, transactionDTO.Payer)
+) (Receipt, error) {
+	currency := transactionDTO.Currency
+	if currency == "" {
+		currency = wallet.DefaultCurrency
+	}
+
+	payeeCurrency := transactionDTO.PayeeCurrency
+	if payeeCurrency == "" {
+		payeeCurrency = currency
+	}
+
+	o, err := s.prepare(
+		ctx,
+		transactionDTO.Payer,
+		transactionDTO.Payee,
+		money.NewFromFloat(transactionDTO.Value, currency),
+		payeeCurrency,
+	)
 	if err != nil {
-		return uuid.Nil, ErrUserNotFound
+		return Receipt{}, err
 	}
 
-	payee, err := s.user.FindByID(ctx, transactionDTO.Payee)
+	// The held transfers are only enqueued after the step-up, so
+	// their approval does not ask the payer for a code again
+	err = s.stepUp.StepUpTransfer(
+		ctx,
+		o.payer.ID,
+		o.equivalent.AsMajorUnits(),
+		transactionDTO.OTP,
+	)
 	if err != nil {
-		return uuid.Nil, ErrUserNotFound
+		return Receipt{}, err
 	}
 
-	amount := money.NewFromFloat(transactionDTO.Value, money.BRL)
-	if err = validateTransaction(&payer, amount); err != nil {
-		return uuid.Nil, err
+	attempt := fraud.Attempt{
+		Payer:         o.payer,
+		Payee:         o.payee,
+		Amount:        o.amount.AsMajorUnits(),
+		Currency:      currency,
+		PayeeCurrency: payeeCurrency,
+		Equivalent:    o.equivalent.AsMajorUnits(),
 	}
+	result, err := s.fraud.Screen(ctx, attempt)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	switch result.Decision {
+	case fraud.DecisionDeny:
+		slog.Warn(
+			"transaction denied by fraud screening",
+			"payer", o.payer.ID,
+			"payee", o.payee.ID,
+			"score", result.Score,
+			"reasons", result.Reasons(),
+		)
+		return Receipt{}, ErrTransactionDenied
+	case fraud.DecisionReview:
+		if _, err := s.fraud.Enqueue(ctx, attempt, result); err != nil {
+			return Receipt{}, err
+		}
+		return Receipt{}, ErrTransactionUnderReview
+	}
+
+	return s.execute(ctx, o, func(ctx context.Context) (Receipt, error) {
+		return s.transfer(ctx, o)
+	})
+}
 
-	if err = authorizeTransaction(); err != nil {
-		return uuid.Nil, ErrTransactionNotAuthorized
+// Loads both users, checks the payer funds and kyc limit and quotes
+// the amount in the payee currency. Both the new transfers and the
+// approved reviews go through it, so neither skips the checks.
+func (s *Service) prepare(
+	ctx context.Context,
+	payerID, payeeID uuid.UUID,
+	amount *money.Money,
+	payeeCurrency string,
+) (order, error) {
+	payer, err := s.user.FindByID(ctx, payerID)
+	if err != nil {
+		return order{}, ErrUserNotFound
 	}
 
-	// The amount is negative because it is being subtracted from the payer
-	err = s.updateBalance(ctx, &payer, amount.Negative())
+	payee, err := s.user.FindByID(ctx, payeeID)
 	if err != nil {
-		return uuid.Nil, err
+		return order{}, ErrUserNotFound
 	}
 
-	err = s.updateBalance(ctx, &payee, amount)
+	if payer.Status == models.StatusFrozen || payee.Status == models.StatusFrozen {
+		return order{}, ErrAccountFrozen
+	}
+
+	balance, err := s.wallet.Balance(ctx, &payer, amount.Currency().Code)
 	if err != nil {
-		return uuid.Nil, err
+		return order{}, ErrWalletNotFound
 	}
 
-	transaction := models.Transaction{
-		Amount: amount.AsMajorUnits(),
-		Payer:  payer.ID,
-		Payee:  payee.ID,
+	if err = validateTransaction(&payer, balance, amount); err != nil {
+		return order{}, err
 	}
 
-	id, err := s.repo.Create(ctx, transaction)
+	equivalent, err := s.exchange.Convert(ctx, amount, wallet.DefaultCurrency)
 	if err != nil {
-		return uuid.Nil, err
+		return order{}, ErrConversionUnavailable
+	}
+
+	limits := kyc.LimitsFor(payer.KYCLevel)
+	if limits.MaxTransfer > 0 && equivalent.Amount.AsMajorUnits() > limits.MaxTransfer {
+		return order{}, ErrKYCLevelRequired
+	}
+
+	if _, err := s.wallet.Balance(ctx, &payee, payeeCurrency); err != nil {
+		return order{}, ErrWalletNotFound
+	}
+
+	quote, err := s.exchange.Convert(ctx, amount, payeeCurrency)
+	if err != nil {
+		return order{}, ErrConversionUnavailable
+	}
+
+	return order{
+		payer:      payer,
+		payee:      payee,
+		amount:     amount,
+		quote:      quote,
+		equivalent: equivalent.Amount,
+	}, nil
+}
+
+// ApproveReview makes the transfer held by the given review, skipping the
+// fraud screening but still checking the payer funds, the kyc limit of
+// the payer, which may have changed meanwhile, and the authorizer. The
+// review is claimed in the same database transaction that moves the
+// funds, so it is never paid twice.
+func (s *Service) ApproveReview(
+	ctx context.Context,
+	reviewID uuid.UUID,
+	note string,
+) (Receipt, error) {
+	review, err := s.fraud.FindPendingByID(ctx, reviewID)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	o, err := s.prepare(
+		ctx,
+		review.Payer,
+		review.Payee,
+		money.NewFromFloat(review.Amount, review.Currency),
+		review.PayeeCurrency,
+	)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	return s.execute(ctx, o, func(ctx context.Context) (Receipt, error) {
+		// Claimed first, a concurrent approval or rejection of the
+		// review fails here without moving the funds
+		if err := s.fraud.Approve(ctx, review, note); err != nil {
+			return Receipt{}, err
+		}
+
+		receipt, err := s.transfer(ctx, o)
+		if err != nil {
+			return Receipt{}, err
+		}
+
+		return receipt, s.fraud.Link(ctx, review.ID, receipt.TransactionID)
+	})
+}
+
+// Authorizes the transaction, makes it in a single database transaction
+// and notifies both users.
+func (s *Service) execute(
+	ctx context.Context,
+	o order,
+	run func(ctx context.Context) (Receipt, error),
+) (Receipt, error) {
+	if err := s.authorizer.Authorize(ctx); err != nil {
+		return Receipt{}, ErrTransactionNotAuthorized
+	}
+
+	var receipt Receipt
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		receipt, err = run(ctx)
+		return err
+	})
+	if err != nil {
+		return Receipt{}, err
 	}
 
 	// Send notifications in parallel
 	go func() {
-		err := notification.Send(&payer, "Transaction completed successfully")
+		err := s.notifier.Notify(&o.payer, "Transaction completed successfully")
 		if err != nil {
 			slog.Error("failed to send notification", "error", err)
 		}
-		err = notification.Send(&payee, "Transaction received successfully")
+		err = s.notifier.Notify(&o.payee, "Transaction received successfully")
 		if err != nil {
 			slog.Error("failed to send notification", "error", err)
 		}
 	}()
 
-	return id, nil
+	return receipt, nil
+}
+
+// Debits the payer, credits the payee and registers the transaction,
+// it must run in a database transaction so they are made all or none.
+func (s *Service) transfer(ctx context.Context, o order) (Receipt, error) {
+	// The amount is negative because it is being subtracted from the payer.
+	// The balance was checked before, but a concurrent operation may have
+	// spent it meanwhile, the debit itself never overdraws it.
+	payer, err := s.wallet.Add(ctx, o.payer.ID, o.amount.Negative())
+	if errors.Is(err, wallet.ErrInsufficientFunds) {
+		return Receipt{}, ErrInsufficientFunds
+	}
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	payee, err := s.wallet.Add(ctx, o.payee.ID, o.quote.Amount)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	id, err := s.repo.Create(ctx, models.Transaction{
+		Amount:        o.amount.AsMajorUnits(),
+		Payer:         o.payer.ID,
+		Payee:         o.payee.ID,
+		Currency:      o.amount.Currency().Code,
+		PayeeAmount:   o.quote.Amount.AsMajorUnits(),
+		PayeeCurrency: o.quote.Amount.Currency().Code,
+		FXRate:        o.quote.Rate,
+		FXSpread:      o.quote.Spread,
+	})
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	return Receipt{
+		TransactionID: id,
+		Payer:         payer,
+		Payee:         payee,
+		FXRate:        o.quote.Rate,
+		FXSpread:      o.quote.Spread,
+	}, nil
 }
 
-// Updates the user balance by the given amount
-func (s *Service) updateBalance(
+// FindTransactions lists pageSize transactions of the user next
+// to the cursor, or the most recent ones without it.
+func (s *Service) FindTransactions(
 	ctx context.Context,
-	user *models.User,
-	amount *money.Money,
-) error {
-	balance := money.NewFromFloat(user.Balance, money.BRL)
+	userID uuid.UUID,
+	after *models.Cursor,
+	pageSize int,
+) (models.Page[models.Transaction], error) {
+	if _, err := s.user.FindByID(ctx, userID); err != nil {
+		return models.Page[models.Transaction]{}, ErrUserNotFound
+	}
 
-	balance, err := balance.Add(amount)
+	total, err := s.repo.CountByUser(ctx, userID)
 	if err != nil {
-		return err
+		return models.Page[models.Transaction]{}, err
 	}
 
-	return s.user.UpdateBalance(ctx, user.ID, balance.AsMajorUnits())
+	transactions, err := s.repo.FindByUser(ctx, userID, after, pageSize+1)
+	if err != nil {
+		return models.Page[models.Transaction]{}, err
+	}
+
+	page := cursor.Paginate(transactions, after, pageSize,
+		func(t models.Transaction) models.Cursor {
+			return models.Cursor{CreatedAt: t.CreatedAt.Time, ID: t.ID}
+		},
+	)
+	page.Total = total
+
+	return page, nil
 }
 
 type Authorizer struct {
@@ -148,9 +450,28 @@ type Authorizer struct {
 	} `json:"data"`
 }
 
+// ExternalAuthorizer authorizes transactions through the external
+// authorization service.
+type ExternalAuthorizer struct {
+	URL    string
+	client *http.Client
+}
+
+func NewExternalAuthorizer(url string, timeout time.Duration) *ExternalAuthorizer {
+	return &ExternalAuthorizer{
+		URL:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
 // Service to authorize a transaction
-func authorizeTransaction() error {
-	resp, err := http.Get("https://util.devi.tools/api/v2/authorize")
+func (a *ExternalAuthorizer) Authorize(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.Do(req)
 	if err != nil {
 		return err
 	}
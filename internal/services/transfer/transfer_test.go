@@ -2,37 +2,90 @@ package transfer_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/Rhymond/go-money"
 	"github.com/edulustosa/go-pay/helpers"
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/database/repo"
 	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/fraud"
+	"github.com/edulustosa/go-pay/internal/services/fx"
+	"github.com/edulustosa/go-pay/internal/services/mfa"
 	"github.com/edulustosa/go-pay/internal/services/transfer"
 	"github.com/edulustosa/go-pay/internal/services/user"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var verified = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+type authorizerStub struct {
+	err error
+}
+
+func (a authorizerStub) Authorize(_ context.Context) error {
+	return a.err
+}
+
+// The notifications are sent in background, they are discarded
+type notifierStub struct{}
+
+func (notifierStub) Notify(_ *models.User, _ string) error {
+	return nil
+}
+
 func TestTransferService(t *testing.T) {
 	transactionsRepository := &repo.InMemoryTransactionsRepository{}
 	userRepository := &repo.InMemoryUserRepository{}
-	userService := user.NewService(userRepository)
-	sut := transfer.NewService(transactionsRepository, userService)
+	walletsRepository := &repo.InMemoryWalletsRepository{}
+	userService := user.NewService(userRepository, user.DefaultConfig())
+	walletService := wallet.NewService(walletsRepository, userRepository)
+	exchangeService := fx.NewService(
+		fx.NewStaticProvider(fx.Rates{"USD": {"BRL": 5}}),
+		0.01,
+	)
+	fraudService := fraud.NewService(
+		&repo.InMemoryFraudReviewsRepository{},
+		transactionsRepository,
+		fraud.DefaultConfig(),
+	)
+	mfaService := mfa.NewService(
+		&repo.InMemoryTOTPFactorsRepository{},
+		userService,
+		mfa.DefaultConfig(),
+	)
+	sut := transfer.NewService(
+		transactionsRepository,
+		repo.InMemoryTransactor{},
+		userService,
+		walletService,
+		exchangeService,
+		authorizerStub{},
+		fraudService,
+		mfaService,
+		notifierStub{},
+	)
 
 	ctx := context.Background()
 	t.Run("should be able to make a transfer between users", func(t *testing.T) {
 		user1, _ := userRepository.Create(ctx, models.User{
-			FirstName: "John",
-			LastName:  "Doe",
-			Email:     "johndoe@email.com",
-			Document:  "12345678900",
-			Balance:   1000,
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+			Balance:         1000,
 		})
 		user2, _ := userRepository.Create(ctx, models.User{
-			FirstName: "Jane",
-			LastName:  "Doe",
-			Email:     "janedoe@email.com",
-			Document:  "09876543211",
-			Balance:   500,
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
 		})
 
 		transaction := dtos.TransactionDTO{
@@ -41,7 +94,7 @@ func TestTransferService(t *testing.T) {
 			Payee: user2,
 		}
 
-		transactionID, err := sut.NewTransaction(ctx, transaction)
+		receipt, err := sut.NewTransaction(ctx, transaction)
 		if err != nil {
 			if err == transfer.ErrTransactionNotAuthorized {
 				t.Log("transaction not authorized")
@@ -52,7 +105,15 @@ func TestTransferService(t *testing.T) {
 			t.Fatalf("expected no error, got %v", err)
 		}
 
-		t.Logf("transactionID: %v", transactionID)
+		t.Logf("transactionID: %v", receipt.TransactionID)
+
+		if receipt.Payer.Before != 1000 || receipt.Payer.After != 900 {
+			t.Errorf("expected the payer balance to change from 1000 to 900, got %+v", receipt.Payer)
+		}
+
+		if receipt.Payee.Before != 500 || receipt.Payee.After != 600 {
+			t.Errorf("expected the payee balance to change from 500 to 600, got %+v", receipt.Payee)
+		}
 
 		user1Model, _ := userRepository.FindByID(ctx, user1)
 		user2Model, _ := userRepository.FindByID(ctx, user2)
@@ -74,18 +135,20 @@ func TestTransferService(t *testing.T) {
 			userRepository.Users = []models.User{}
 
 			user1, _ := userRepository.Create(ctx, models.User{
-				FirstName: "John",
-				LastName:  "Doe",
-				Email:     "johndoe@email.com",
-				Document:  "12345678900",
-				Balance:   90,
+				FirstName:       "John",
+				LastName:        "Doe",
+				Email:           "johndoe@email.com",
+				Document:        "12345678900",
+				EmailVerifiedAt: verified,
+				Balance:         90,
 			})
 			user2, _ := userRepository.Create(ctx, models.User{
-				FirstName: "Jane",
-				LastName:  "Doe",
-				Email:     "janedoe@email.com",
-				Document:  "09876543211",
-				Balance:   500,
+				FirstName:       "Jane",
+				LastName:        "Doe",
+				Email:           "janedoe@email.com",
+				Document:        "09876543211",
+				EmailVerifiedAt: verified,
+				Balance:         500,
 			})
 
 			transactionDTO := dtos.TransactionDTO{
@@ -118,19 +181,21 @@ func TestTransferService(t *testing.T) {
 		userRepository.Users = []models.User{}
 
 		user1, _ := userRepository.Create(ctx, models.User{
-			FirstName: "John",
-			LastName:  "Doe",
-			Email:     "johndoe@email.com",
-			Document:  "12345678900",
-			Balance:   1000,
-			Role:      models.RoleMerchant,
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+			Balance:         1000,
+			Role:            models.RoleMerchant,
 		})
 		user2, _ := userRepository.Create(ctx, models.User{
-			FirstName: "Jane",
-			LastName:  "Doe",
-			Email:     "janedoe@email.com",
-			Document:  "09876543211",
-			Balance:   500,
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
 		})
 
 		transactionDTO := dtos.TransactionDTO{
@@ -157,4 +222,394 @@ func TestTransferService(t *testing.T) {
 			t.Errorf("expected user2 balance to be 500, got %v", user2Model.Balance)
 		}
 	})
+	t.Run("should limit the amount by the kyc level of the payer", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+
+		user1, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+			Balance:         5000,
+		})
+		user2, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
+		})
+
+		transactionDTO := dtos.TransactionDTO{
+			Value: 1001,
+			Payer: user1,
+			Payee: user2,
+		}
+
+		_, err := sut.NewTransaction(ctx, transactionDTO)
+		if err != transfer.ErrKYCLevelRequired {
+			t.Errorf("expected error to be ErrKYCLevelRequired, got %v", err)
+		}
+
+		user1Model, _ := userRepository.FindByID(ctx, user1)
+		if user1Model.Balance != 5000 {
+			t.Errorf("expected user1 balance to be 5000, got %v", user1Model.Balance)
+		}
+	})
+
+	t.Run("should hold transfers flagged by the fraud screening for review", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+		reviewsRepository := &repo.InMemoryFraudReviewsRepository{}
+		sut := transfer.NewService(
+			transactionsRepository,
+			repo.InMemoryTransactor{},
+			userService,
+			walletService,
+			exchangeService,
+			authorizerStub{},
+			fraud.NewService(reviewsRepository, transactionsRepository, fraud.DefaultConfig()),
+			mfaService,
+			notifierStub{},
+		)
+
+		user1, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+			KYCLevel:        models.KYCIntermediate,
+			Balance:         5000,
+		})
+		user2, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
+		})
+
+		transactionDTO := dtos.TransactionDTO{
+			Value: 2000,
+			Payer: user1,
+			Payee: user2,
+		}
+
+		_, err := sut.NewTransaction(ctx, transactionDTO)
+		if err != transfer.ErrTransactionUnderReview {
+			t.Fatalf("expected error to be ErrTransactionUnderReview, got %v", err)
+		}
+
+		if len(reviewsRepository.Reviews) != 1 {
+			t.Fatalf("expected 1 review, got %d", len(reviewsRepository.Reviews))
+		}
+
+		user1Model, _ := userRepository.FindByID(ctx, user1)
+		if user1Model.Balance != 5000 {
+			t.Errorf("expected user1 balance to be 5000, got %v", user1Model.Balance)
+		}
+
+		_, err = sut.ApproveReview(ctx, reviewsRepository.Reviews[0].ID, "known customer")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		user1Model, _ = userRepository.FindByID(ctx, user1)
+		user2Model, _ := userRepository.FindByID(ctx, user2)
+
+		if user1Model.Balance != 3000 {
+			t.Errorf("expected user1 balance to be 3000, got %v", user1Model.Balance)
+		}
+
+		if user2Model.Balance != 2500 {
+			t.Errorf("expected user2 balance to be 2500, got %v", user2Model.Balance)
+		}
+
+		if reviewsRepository.Reviews[0].Status != models.ReviewApproved {
+			t.Errorf("expected review to be approved, got %v", reviewsRepository.Reviews[0].Status)
+		}
+
+		if !reviewsRepository.Reviews[0].TransactionID.Valid {
+			t.Error("expected review to be linked to the transaction")
+		}
+
+		_, err = sut.ApproveReview(ctx, reviewsRepository.Reviews[0].ID, "known customer")
+		if err != fraud.ErrReviewNotPending {
+			t.Fatalf("expected error to be ErrReviewNotPending, got %v", err)
+		}
+
+		user1Model, _ = userRepository.FindByID(ctx, user1)
+		user2Model, _ = userRepository.FindByID(ctx, user2)
+
+		if user1Model.Balance != 3000 {
+			t.Errorf("expected user1 balance to stay 3000, got %v", user1Model.Balance)
+		}
+
+		if user2Model.Balance != 2500 {
+			t.Errorf("expected user2 balance to stay 2500, got %v", user2Model.Balance)
+		}
+	})
+	t.Run("should enforce the kyc limit when approving a held transfer", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+		reviewsRepository := &repo.InMemoryFraudReviewsRepository{}
+		sut := transfer.NewService(
+			transactionsRepository,
+			repo.InMemoryTransactor{},
+			userService,
+			walletService,
+			exchangeService,
+			authorizerStub{},
+			fraud.NewService(reviewsRepository, transactionsRepository, fraud.DefaultConfig()),
+			mfaService,
+			notifierStub{},
+		)
+
+		user1, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+			KYCLevel:        models.KYCIntermediate,
+			Balance:         5000,
+		})
+		user2, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
+		})
+
+		_, err := sut.NewTransaction(ctx, dtos.TransactionDTO{
+			Value: 2000,
+			Payer: user1,
+			Payee: user2,
+		})
+		if err != transfer.ErrTransactionUnderReview {
+			t.Fatalf("expected error to be ErrTransactionUnderReview, got %v", err)
+		}
+
+		// The level of the payer no longer allows the amount
+		userRepository.Users[0].KYCLevel = models.KYCBasic
+
+		_, err = sut.ApproveReview(ctx, reviewsRepository.Reviews[0].ID, "known customer")
+		if err != transfer.ErrKYCLevelRequired {
+			t.Fatalf("expected error to be ErrKYCLevelRequired, got %v", err)
+		}
+
+		user1Model, _ := userRepository.FindByID(ctx, user1)
+		if user1Model.Balance != 5000 {
+			t.Errorf("expected user1 balance to be 5000, got %v", user1Model.Balance)
+		}
+
+		if reviewsRepository.Reviews[0].Status != models.ReviewPending {
+			t.Errorf("expected review to stay pending, got %v", reviewsRepository.Reviews[0].Status)
+		}
+	})
+
+	t.Run("should convert transfers between wallets in different currencies", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+		transactionsRepository.Transaction = []models.Transaction{}
+
+		user1, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+		})
+		user2, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
+		})
+
+		walletID, _ := walletService.Create(ctx, user1, money.USD)
+		_, _, _ = walletsRepository.AddBalance(ctx, walletID, 100)
+
+		transactionDTO := dtos.TransactionDTO{
+			Value:         20,
+			Payer:         user1,
+			Payee:         user2,
+			Currency:      money.USD,
+			PayeeCurrency: money.BRL,
+		}
+
+		_, err := sut.NewTransaction(ctx, transactionDTO)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		usdWallet, _ := walletsRepository.FindByUserAndCurrency(ctx, user1, money.USD)
+		user2Model, _ := userRepository.FindByID(ctx, user2)
+
+		if usdWallet.Balance != 80 {
+			t.Errorf("expected user1 USD balance to be 80, got %v", usdWallet.Balance)
+		}
+
+		// 20 USD at 5 BRL with 1% spread
+		if user2Model.Balance != 599 {
+			t.Errorf("expected user2 balance to be 599, got %v", user2Model.Balance)
+		}
+
+		transaction := transactionsRepository.Transaction[0]
+		if transaction.PayeeAmount != 99 || transaction.FXRate != 5 || transaction.FXSpread != 0.01 {
+			t.Errorf("expected conversion to be recorded, got %+v", transaction)
+		}
+
+		_, err = sut.NewTransaction(ctx, dtos.TransactionDTO{
+			Value:    10,
+			Payer:    user2,
+			Payee:    user1,
+			Currency: money.EUR,
+		})
+		if err != transfer.ErrWalletNotFound {
+			t.Errorf("expected error to be ErrWalletNotFound, got %v", err)
+		}
+	})
+	t.Run("should require a two-factor code for high-value transfers", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+		factorsRepository := &repo.InMemoryTOTPFactorsRepository{}
+		mfaConfig := mfa.DefaultConfig()
+		mfaConfig.TransferThreshold = 100
+		mfaService := mfa.NewService(factorsRepository, userService, mfaConfig)
+		sut := transfer.NewService(
+			transactionsRepository,
+			repo.InMemoryTransactor{},
+			userService,
+			walletService,
+			exchangeService,
+			authorizerStub{},
+			fraudService,
+			mfaService,
+			notifierStub{},
+		)
+
+		user1, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+			Balance:         1000,
+		})
+		user2, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+		})
+
+		transactionDTO := dtos.TransactionDTO{
+			Value: 200,
+			Payer: user1,
+			Payee: user2,
+		}
+
+		_, err := sut.NewTransaction(ctx, transactionDTO)
+		if !errors.Is(err, mfa.ErrEnrollmentNeeded) {
+			t.Fatalf("expected error to be ErrEnrollmentNeeded, got %v", err)
+		}
+
+		enrollment, _ := mfaService.Enroll(ctx, user1)
+		code, _ := mfa.Code(enrollment.Secret, time.Now().Add(-30*time.Second))
+		if _, err := mfaService.Confirm(ctx, user1, code); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		_, err = sut.NewTransaction(ctx, transactionDTO)
+		if !errors.Is(err, mfa.ErrCodeRequired) {
+			t.Fatalf("expected error to be ErrCodeRequired, got %v", err)
+		}
+
+		transactionDTO.OTP, _ = mfa.Code(enrollment.Secret, time.Now())
+		if _, err := sut.NewTransaction(ctx, transactionDTO); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		user1Model, _ := userRepository.FindByID(ctx, user1)
+		if user1Model.Balance != 800 {
+			t.Errorf("expected user1 balance to be 800, got %v", user1Model.Balance)
+		}
+
+		_, err = sut.NewTransaction(ctx, transactionDTO)
+		if !errors.Is(err, mfa.ErrInvalidCode) {
+			t.Errorf("expected a reused code to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("should not be able to send money before verifying the email", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+
+		user1, _ := userRepository.Create(ctx, models.User{
+			FirstName: "John",
+			LastName:  "Doe",
+			Email:     "johndoe@email.com",
+			Document:  "12345678900",
+			Balance:   1000,
+		})
+		user2, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
+		})
+
+		_, err := sut.NewTransaction(ctx, dtos.TransactionDTO{
+			Value: 100,
+			Payer: user1,
+			Payee: user2,
+		})
+		if err != transfer.ErrEmailNotVerified {
+			t.Errorf("expected error to be ErrEmailNotVerified, got %v", err)
+		}
+
+		user1Model, _ := userRepository.FindByID(ctx, user1)
+		if user1Model.Balance != 1000 {
+			t.Errorf("expected user1 balance to be 1000, got %v", user1Model.Balance)
+		}
+	})
+
+	t.Run("should not move money from or to frozen accounts", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+
+		user1, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "John",
+			LastName:        "Doe",
+			Email:           "johndoe@email.com",
+			Document:        "12345678900",
+			EmailVerifiedAt: verified,
+			Balance:         1000,
+		})
+		user2, _ := userRepository.Create(ctx, models.User{
+			FirstName:       "Jane",
+			LastName:        "Doe",
+			Email:           "janedoe@email.com",
+			Document:        "09876543211",
+			EmailVerifiedAt: verified,
+			Balance:         500,
+			Status:          models.StatusFrozen,
+		})
+
+		_, err := sut.NewTransaction(ctx, dtos.TransactionDTO{Value: 100, Payer: user1, Payee: user2})
+		if err != transfer.ErrAccountFrozen {
+			t.Errorf("expected error to be ErrAccountFrozen, got %v", err)
+		}
+
+		_, err = sut.NewTransaction(ctx, dtos.TransactionDTO{Value: 100, Payer: user2, Payee: user1})
+		if err != transfer.ErrAccountFrozen {
+			t.Errorf("expected error to be ErrAccountFrozen, got %v", err)
+		}
+	})
 }
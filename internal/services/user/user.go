@@ -3,11 +3,13 @@ package user
 import (
 	"context"
 	"errors"
-	"strings"
 
+	"github.com/edulustosa/go-pay/helpers"
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/cursor"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -16,21 +18,52 @@ type userRepository interface {
 	FindByDocument(ctx context.Context, document string) (models.User, error)
 	FindByEmail(ctx context.Context, email string) (models.User, error)
 	Create(ctx context.Context, user models.User) (uuid.UUID, error)
-	UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error
-	FindMany(ctx context.Context, page int) ([]models.User, error)
+	FindMany(
+		ctx context.Context,
+		filter models.UserFilter,
+		page int,
+		pageSize int,
+	) ([]models.User, error)
+	FindAfter(
+		ctx context.Context,
+		filter models.UserFilter,
+		cursor *models.Cursor,
+		limit int,
+	) ([]models.User, error)
+	Count(ctx context.Context, filter models.UserFilter) (int, error)
+	UpdateProfile(ctx context.Context, user models.User) error
+}
+
+// Config bounds the page sizes of FindMany and FindManyAfter.
+type Config struct {
+	DefaultPageSize int
+	MaxPageSize     int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		DefaultPageSize: 20,
+		MaxPageSize:     100,
+	}
 }
 
 type Service struct {
-	repo userRepository
+	repo   userRepository
+	config Config
 }
 
-func NewService(repo userRepository) *Service {
+func NewService(repo userRepository, config Config) *Service {
 	return &Service{
 		repo,
+		config,
 	}
 }
 
-var ErrUserAlreadyExists = errors.New("user already exists")
+var (
+	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidRange      = errors.New("the start of the range must be before its end")
+)
 
 func (s *Service) FindByID(
 	ctx context.Context,
@@ -43,7 +76,8 @@ func (s *Service) Create(
 	ctx context.Context,
 	userDTO dtos.UserDTO,
 ) (uuid.UUID, error) {
-	userDTO.Document = normalizeDocument(userDTO.Document)
+	userDTO.Document = helpers.NormalizeDocument(userDTO.Document)
+	userDTO.Email = helpers.NormalizeEmail(userDTO.Email)
 
 	_, err := s.repo.FindByDocument(ctx, userDTO.Document)
 	if err == nil {
@@ -68,58 +102,171 @@ func (s *Service) Create(
 		userDTO.Role = models.RoleCommon
 	}
 
+	documentType := models.DocumentCPF
+	if len(userDTO.Document) == helpers.CNPJLength {
+		documentType = models.DocumentCNPJ
+	}
+
 	user := models.User{
 		FirstName:    userDTO.FirstName,
 		LastName:     userDTO.LastName,
 		Document:     userDTO.Document,
 		Email:        userDTO.Email,
 		PasswordHash: string(passwordHash),
-		Balance:      userDTO.Balance,
 		Role:         userDTO.Role,
+		DocumentType: documentType,
 	}
 
 	return s.repo.Create(ctx, user)
 }
 
-func normalizeDocument(document string) string {
-	document = strings.ReplaceAll(document, ".", "")
-	document = strings.ReplaceAll(document, "-", "")
-	return document
-}
-
-func (s *Service) UpdateBalance(
+// UpdateProfile changes the fields present in the dto, a new email is
+// only trusted once verified again.
+func (s *Service) UpdateProfile(
 	ctx context.Context,
 	id uuid.UUID,
-	balance float64,
-) error {
-	return s.repo.UpdateBalance(ctx, id, balance)
+	updateDTO dtos.UpdateUserDTO,
+) (models.User, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.User{}, ErrUserNotFound
+	}
+
+	if updateDTO.FirstName != nil {
+		user.FirstName = *updateDTO.FirstName
+	}
+
+	if updateDTO.LastName != nil {
+		user.LastName = *updateDTO.LastName
+	}
+
+	if updateDTO.Email != nil {
+		email := helpers.NormalizeEmail(*updateDTO.Email)
+		updateDTO.Email = &email
+	}
+
+	if updateDTO.Email != nil && *updateDTO.Email != user.Email {
+		_, err := s.repo.FindByEmail(ctx, *updateDTO.Email)
+		if err == nil {
+			return models.User{}, ErrUserAlreadyExists
+		}
+
+		user.Email = *updateDTO.Email
+		user.EmailVerifiedAt = pgtype.Timestamp{}
+	}
+
+	if err := s.repo.UpdateProfile(ctx, user); err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
 }
 
+// staffUserResponse masks the document, the users are listed for the staff
+func staffUserResponse(user models.User) dtos.UserResponseDTO {
+	return dtos.UserResponseDTO{
+		ID:            user.ID,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Document:      helpers.MaskDocument(user.Document),
+		DocumentType:  user.DocumentType,
+		Email:         user.Email,
+		Balance:       user.Balance,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Status:        user.Status,
+	}
+}
+
+// FindMany lists the users for the staff, so their documents are masked.
+// The page size defaults to the configured one and is capped at its maximum.
 func (s *Service) FindMany(
 	ctx context.Context,
+	filter models.UserFilter,
 	page int,
-) ([]dtos.UserResponseDTO, error) {
+	pageSize int,
+) (dtos.UserPageResponseDTO, error) {
+	if filter.CreatedFrom.Valid && filter.CreatedTo.Valid &&
+		!filter.CreatedFrom.Time.Before(filter.CreatedTo.Time) {
+		return dtos.UserPageResponseDTO{}, ErrInvalidRange
+	}
+
 	if page < 1 {
 		page = 1
 	}
 
-	users, err := s.repo.FindMany(ctx, page)
+	if pageSize < 1 {
+		pageSize = s.config.DefaultPageSize
+	}
+	pageSize = min(pageSize, s.config.MaxPageSize)
+
+	total, err := s.repo.Count(ctx, filter)
 	if err != nil {
-		return nil, err
+		return dtos.UserPageResponseDTO{}, err
+	}
+
+	users, err := s.repo.FindMany(ctx, filter, page, pageSize)
+	if err != nil {
+		return dtos.UserPageResponseDTO{}, err
 	}
 
 	usersDTO := make([]dtos.UserResponseDTO, len(users))
 	for i, user := range users {
-		usersDTO[i] = dtos.UserResponseDTO{
-			ID:        user.ID,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Document:  user.Document,
-			Email:     user.Email,
-			Balance:   user.Balance,
-			Role:      user.Role,
-		}
+		usersDTO[i] = staffUserResponse(user)
+	}
+
+	return dtos.UserPageResponseDTO{
+		Users: usersDTO,
+		Pagination: dtos.PaginationDTO{
+			Page:       page,
+			PageSize:   pageSize,
+			Total:      total,
+			TotalPages: (total + pageSize - 1) / pageSize,
+		},
+	}, nil
+}
+
+// FindManyAfter lists the users next to the cursor, sorted by creation.
+// The page size is bounded like the one of FindMany.
+func (s *Service) FindManyAfter(
+	ctx context.Context,
+	filter models.UserFilter,
+	after *models.Cursor,
+	pageSize int,
+) (models.Page[dtos.UserResponseDTO], error) {
+	if filter.CreatedFrom.Valid && filter.CreatedTo.Valid &&
+		!filter.CreatedFrom.Time.Before(filter.CreatedTo.Time) {
+		return models.Page[dtos.UserResponseDTO]{}, ErrInvalidRange
+	}
+
+	if pageSize < 1 {
+		pageSize = s.config.DefaultPageSize
+	}
+	pageSize = min(pageSize, s.config.MaxPageSize)
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return models.Page[dtos.UserResponseDTO]{}, err
+	}
+
+	users, err := s.repo.FindAfter(ctx, filter, after, pageSize+1)
+	if err != nil {
+		return models.Page[dtos.UserResponseDTO]{}, err
+	}
+
+	page := cursor.Paginate(users, after, pageSize, func(u models.User) models.Cursor {
+		return models.Cursor{CreatedAt: u.CreatedAt.Time, ID: u.ID}
+	})
+
+	usersDTO := make([]dtos.UserResponseDTO, len(page.Items))
+	for i, user := range page.Items {
+		usersDTO[i] = staffUserResponse(user)
 	}
 
-	return usersDTO, nil
+	return models.Page[dtos.UserResponseDTO]{
+		Items: usersDTO,
+		Total: total,
+		Next:  page.Next,
+		Prev:  page.Prev,
+	}, nil
 }
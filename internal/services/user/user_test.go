@@ -2,17 +2,22 @@ package user_test
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/edulustosa/go-pay/internal/database/models"
 	"github.com/edulustosa/go-pay/internal/database/repo"
 	"github.com/edulustosa/go-pay/internal/dtos"
 	"github.com/edulustosa/go-pay/internal/services/user"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func TestUserService_Create(t *testing.T) {
 	userRepository := repo.InMemoryUserRepository{}
-	sut := user.NewService(&userRepository)
+	sut := user.NewService(&userRepository, user.DefaultConfig())
 
 	ctx := context.Background()
 	t.Run("should be able to create a new user", func(t *testing.T) {
@@ -104,4 +109,337 @@ func TestUserService_Create(t *testing.T) {
 
 		t.Logf("error: %v", err)
 	})
+
+	t.Run("should compare the emails ignoring the case", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+
+		id, err := sut.Create(ctx, dtos.UserDTO{
+			FirstName: "John",
+			LastName:  "Doe",
+			Email:     " JohnDoe@Email.com ",
+			Document:  "12345678900",
+			Password:  "123456",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		created, _ := userRepository.FindByID(ctx, id)
+		if created.Email != "johndoe@email.com" {
+			t.Errorf("expected the email to be normalized, got %q", created.Email)
+		}
+
+		_, err = sut.Create(ctx, dtos.UserDTO{
+			FirstName: "Jane",
+			LastName:  "Doe",
+			Email:     "JOHNDOE@EMAIL.COM",
+			Document:  "12345678901",
+			Password:  "123456",
+		})
+		if err != user.ErrUserAlreadyExists {
+			t.Errorf("expected %v, got %v", user.ErrUserAlreadyExists, err)
+		}
+	})
+
+	t.Run("should store the normalized CNPJ of merchants", func(t *testing.T) {
+		userRepository.Users = []models.User{}
+
+		merchant := dtos.UserDTO{
+			FirstName: "Acme",
+			LastName:  "Store",
+			Email:     "acme@email.com",
+			Document:  "12.abc.345/01de-35",
+			Password:  "123456",
+			Role:      models.RoleMerchant,
+		}
+
+		if problems := merchant.Valid(); len(problems) > 0 {
+			t.Fatalf("expected no problems, got %v", problems)
+		}
+
+		userID, err := sut.Create(ctx, merchant)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		createdUser, _ := userRepository.FindByID(ctx, userID)
+		if createdUser.Document != "12ABC34501DE35" || createdUser.DocumentType != models.DocumentCNPJ {
+			t.Errorf("expected CNPJ 12ABC34501DE35, got %s %s", createdUser.DocumentType, createdUser.Document)
+		}
+	})
+
+	t.Run("should require the document type matching the role", func(t *testing.T) {
+		testCases := []struct {
+			role     models.Role
+			document string
+			want     bool
+		}{
+			{"", "529.982.247-25", true},
+			{models.RoleCommon, "11.222.333/0001-81", false},
+			{models.RoleMerchant, "11.222.333/0001-81", true},
+			{models.RoleMerchant, "529.982.247-25", false},
+			{"ADMIN", "529.982.247-25", false},
+		}
+
+		for _, tc := range testCases {
+			problems := dtos.UserDTO{
+				FirstName: "John",
+				LastName:  "Doe",
+				Email:     "johndoe@email.com",
+				Document:  tc.document,
+				Password:  "123456",
+				Role:      tc.role,
+			}.Valid()
+			if valid := len(problems) == 0; valid != tc.want {
+				t.Errorf("Valid(%s, %s) got %v, want valid %v", tc.role, tc.document, problems, tc.want)
+			}
+		}
+	})
+}
+
+func TestUserService_UpdateProfile(t *testing.T) {
+	userRepository := repo.InMemoryUserRepository{}
+	sut := user.NewService(&userRepository, user.DefaultConfig())
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName:       "John",
+		LastName:        "Doe",
+		Email:           "johndoe@email.com",
+		Document:        "12345678900",
+		EmailVerifiedAt: pgtype.Timestamp{Time: time.Now(), Valid: true},
+	})
+	userRepository.Create(ctx, models.User{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "janedoe@email.com",
+		Document:  "09876543211",
+	})
+
+	t.Run("should keep the email verified when only the name changes", func(t *testing.T) {
+		firstName := "Johnny"
+
+		updated, err := sut.UpdateProfile(ctx, userID, dtos.UpdateUserDTO{FirstName: &firstName})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if updated.FirstName != firstName || updated.LastName != "Doe" || !updated.EmailVerifiedAt.Valid {
+			t.Errorf("expected only the first name to change, got %+v", updated)
+		}
+	})
+
+	t.Run("should not use the email of another user", func(t *testing.T) {
+		email := "janedoe@email.com"
+
+		_, err := sut.UpdateProfile(ctx, userID, dtos.UpdateUserDTO{Email: &email})
+		if err != user.ErrUserAlreadyExists {
+			t.Errorf("expected %v, got %v", user.ErrUserAlreadyExists, err)
+		}
+	})
+
+	t.Run("should require verifying a new email", func(t *testing.T) {
+		email := "johnny@email.com"
+
+		if _, err := sut.UpdateProfile(ctx, userID, dtos.UpdateUserDTO{Email: &email}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		updated, _ := userRepository.FindByID(ctx, userID)
+		if updated.Email != email || updated.EmailVerifiedAt.Valid {
+			t.Errorf("expected unverified %s, got %+v", email, updated)
+		}
+	})
+}
+
+func TestUserService_FindMany(t *testing.T) {
+	userRepository := repo.InMemoryUserRepository{}
+	config := user.Config{DefaultPageSize: 10, MaxPageSize: 50}
+	sut := user.NewService(&userRepository, config)
+
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Carol", "Alice", "Bob", "Alan"} {
+		role := models.RoleCommon
+		if name == "Bob" {
+			role = models.RoleMerchant
+		}
+
+		userRepository.Create(ctx, models.User{
+			FirstName: name,
+			LastName:  "Doe",
+			Email:     strings.ToLower(name) + "@email.com",
+			Document:  fmt.Sprintf("1234567890%d", i),
+			Role:      role,
+			CreatedAt: pgtype.Timestamp{Time: start.AddDate(0, 0, i), Valid: true},
+		})
+	}
+	closedID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "Dave",
+		LastName:  "Doe",
+		Email:     "dave@email.com",
+		Document:  "12345678909",
+	})
+	userRepository.Close(ctx, closedID)
+
+	names := func(page dtos.UserPageResponseDTO) []string {
+		names := make([]string, len(page.Users))
+		for i, u := range page.Users {
+			names[i] = u.FirstName
+		}
+		return names
+	}
+
+	t.Run("should sort by creation and exclude closed users", func(t *testing.T) {
+		page, err := sut.FindMany(ctx, models.UserFilter{}, 1, 0)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		want := []string{"Carol", "Alice", "Bob", "Alan"}
+		if !slices.Equal(names(page), want) {
+			t.Errorf("expected %v, got %v", want, names(page))
+		}
+
+		if page.Pagination.PageSize != config.DefaultPageSize || page.Pagination.Total != 4 {
+			t.Errorf("expected the default page size and 4 users, got %+v", page.Pagination)
+		}
+	})
+
+	t.Run("should paginate with the total count", func(t *testing.T) {
+		filter := models.UserFilter{Sort: models.UserSortName, Descending: true}
+
+		page, err := sut.FindMany(ctx, filter, 2, 3)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		want := dtos.PaginationDTO{Page: 2, PageSize: 3, Total: 4, TotalPages: 2}
+		if !slices.Equal(names(page), []string{"Alan"}) || page.Pagination != want {
+			t.Errorf("expected [Alan] and %+v, got %v and %+v", want, names(page), page.Pagination)
+		}
+	})
+
+	t.Run("should filter the users", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			filter models.UserFilter
+			want   []string
+		}{
+			{"role", models.UserFilter{Role: models.RoleMerchant}, []string{"Bob"}},
+			{"name prefix", models.UserFilter{NamePrefix: "al"}, []string{"Alice", "Alan"}},
+			{"email", models.UserFilter{Email: "CAROL@email.com"}, []string{"Carol"}},
+			{"status", models.UserFilter{Status: models.StatusClosed}, []string{"Dave"}},
+			{
+				"creation range",
+				models.UserFilter{
+					CreatedFrom: pgtype.Timestamp{Time: start.AddDate(0, 0, 1), Valid: true},
+					CreatedTo:   pgtype.Timestamp{Time: start.AddDate(0, 0, 3), Valid: true},
+				},
+				[]string{"Alice", "Bob"},
+			},
+		}
+
+		for _, tc := range testCases {
+			page, err := sut.FindMany(ctx, tc.filter, 1, 20)
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", tc.name, err)
+			}
+
+			if !slices.Equal(names(page), tc.want) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, names(page))
+			}
+		}
+	})
+
+	t.Run("should cap the page size", func(t *testing.T) {
+		page, _ := sut.FindMany(ctx, models.UserFilter{}, 1, 1000)
+		if page.Pagination.PageSize != config.MaxPageSize {
+			t.Errorf("expected %d, got %d", config.MaxPageSize, page.Pagination.PageSize)
+		}
+	})
+
+	t.Run("should not accept an empty creation range", func(t *testing.T) {
+		filter := models.UserFilter{
+			CreatedFrom: pgtype.Timestamp{Time: start, Valid: true},
+			CreatedTo:   pgtype.Timestamp{Time: start, Valid: true},
+		}
+
+		if _, err := sut.FindMany(ctx, filter, 1, 20); err != user.ErrInvalidRange {
+			t.Errorf("expected %v, got %v", user.ErrInvalidRange, err)
+		}
+	})
+}
+
+func TestUserService_FindManyAfter(t *testing.T) {
+	userRepository := repo.InMemoryUserRepository{}
+	sut := user.NewService(&userRepository, user.DefaultConfig())
+
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		userRepository.Create(ctx, models.User{
+			FirstName: fmt.Sprintf("User%d", i),
+			Email:     fmt.Sprintf("user%d@email.com", i),
+			Document:  fmt.Sprintf("1234567890%d", i),
+			CreatedAt: pgtype.Timestamp{Time: start.AddDate(0, 0, i), Valid: true},
+		})
+	}
+
+	names := func(page models.Page[dtos.UserResponseDTO]) []string {
+		names := make([]string, len(page.Items))
+		for i, u := range page.Items {
+			names[i] = u.FirstName
+		}
+		return names
+	}
+
+	t.Run("should walk the pages forward and back", func(t *testing.T) {
+		first, err := sut.FindManyAfter(ctx, models.UserFilter{}, nil, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !slices.Equal(names(first), []string{"User0", "User1"}) || first.Prev != nil || first.Total != 5 {
+			t.Fatalf("expected the first page, got %v, prev %v", names(first), first.Prev)
+		}
+
+		// Inserted before the cursor, it must not shift the next pages
+		userRepository.Create(ctx, models.User{
+			FirstName: "Early",
+			Email:     "early@email.com",
+			Document:  "12345678909",
+			CreatedAt: pgtype.Timestamp{Time: start.AddDate(-1, 0, 0), Valid: true},
+		})
+
+		second, _ := sut.FindManyAfter(ctx, models.UserFilter{}, first.Next, 2)
+		if !slices.Equal(names(second), []string{"User2", "User3"}) {
+			t.Fatalf("expected [User2 User3], got %v", names(second))
+		}
+
+		last, _ := sut.FindManyAfter(ctx, models.UserFilter{}, second.Next, 2)
+		if !slices.Equal(names(last), []string{"User4"}) || last.Next != nil {
+			t.Fatalf("expected the last page, got %v, next %v", names(last), last.Next)
+		}
+
+		back, _ := sut.FindManyAfter(ctx, models.UserFilter{}, last.Prev, 2)
+		if !slices.Equal(names(back), []string{"User2", "User3"}) || back.Prev == nil {
+			t.Errorf("expected [User2 User3] with a prev cursor, got %v", names(back))
+		}
+	})
+
+	t.Run("should walk in descending order", func(t *testing.T) {
+		filter := models.UserFilter{Descending: true, Email: "user4@email.com"}
+
+		page, _ := sut.FindManyAfter(ctx, filter, nil, 2)
+		if !slices.Equal(names(page), []string{"User4"}) || page.Next != nil {
+			t.Errorf("expected [User4] without next, got %v", names(page))
+		}
+
+		page, _ = sut.FindManyAfter(ctx, models.UserFilter{Descending: true}, nil, 3)
+		page, _ = sut.FindManyAfter(ctx, models.UserFilter{Descending: true}, page.Next, 3)
+		if !slices.Equal(names(page), []string{"User1", "User0", "Early"}) {
+			t.Errorf("expected [User1 User0 Early], got %v", names(page))
+		}
+	})
 }
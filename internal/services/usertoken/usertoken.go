@@ -0,0 +1,115 @@
+package usertoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type userTokensRepository interface {
+	Create(ctx context.Context, token models.UserToken) (uuid.UUID, error)
+	FindByHash(ctx context.Context, hash string) (models.UserToken, error)
+	Use(ctx context.Context, id uuid.UUID) (bool, error)
+	Invalidate(ctx context.Context, userID uuid.UUID, purpose models.TokenPurpose) error
+	CountSince(ctx context.Context, userID uuid.UUID, purpose models.TokenPurpose, since time.Time) (int, error)
+}
+
+// Service issues the single use tokens sent to users, like the ones to
+// reset a password or verify an email.
+type Service struct {
+	repo userTokensRepository
+}
+
+func NewService(repo userTokensRepository) *Service {
+	return &Service{
+		repo,
+	}
+}
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+const tokenBytes = 32
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a token of the purpose sent to the given email,
+// invalidating the previous ones.
+func (s *Service) Issue(
+	ctx context.Context,
+	userID uuid.UUID,
+	email string,
+	purpose models.TokenPurpose,
+	ttl time.Duration,
+) (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := s.repo.Invalidate(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	_, err := s.repo.Create(ctx, models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash(token),
+		Email:     email,
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().Add(ttl), Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Use consumes a token of the purpose and returns it, along with the
+// user and the email it was issued to.
+func (s *Service) Use(
+	ctx context.Context,
+	token string,
+	purpose models.TokenPurpose,
+) (models.UserToken, error) {
+	userToken, err := s.repo.FindByHash(ctx, hash(token))
+	if err != nil ||
+		userToken.Purpose != purpose ||
+		userToken.UsedAt.Valid ||
+		!userToken.ExpiresAt.Time.After(time.Now()) {
+		return models.UserToken{}, ErrInvalidToken
+	}
+
+	used, err := s.repo.Use(ctx, userToken.ID)
+	if err != nil {
+		return models.UserToken{}, err
+	}
+
+	if !used {
+		return models.UserToken{}, ErrInvalidToken
+	}
+
+	return userToken, nil
+}
+
+// CountSince counts the tokens of the purpose issued to the user since the
+// given time, used to limit how often they are sent.
+func (s *Service) CountSince(
+	ctx context.Context,
+	userID uuid.UUID,
+	purpose models.TokenPurpose,
+	since time.Time,
+) (int, error) {
+	return s.repo.CountSince(ctx, userID, purpose, since)
+}
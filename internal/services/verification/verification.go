@@ -0,0 +1,130 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/services/notification"
+	"github.com/edulustosa/go-pay/internal/services/usertoken"
+	"github.com/google/uuid"
+)
+
+type userRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) error
+}
+
+type userTokenService interface {
+	Issue(ctx context.Context, userID uuid.UUID, email string, purpose models.TokenPurpose, ttl time.Duration) (string, error)
+	Use(ctx context.Context, token string, purpose models.TokenPurpose) (models.UserToken, error)
+	CountSince(ctx context.Context, userID uuid.UUID, purpose models.TokenPurpose, since time.Time) (int, error)
+}
+
+// Service verifies that users own their emails, users can receive
+// but not send money until they do.
+type Service struct {
+	repo       userRepository
+	userTokens userTokenService
+	notifier   notification.Notifier
+}
+
+func NewService(
+	repo userRepository,
+	userTokens userTokenService,
+	notifier notification.Notifier,
+) *Service {
+	return &Service{
+		repo,
+		userTokens,
+		notifier,
+	}
+}
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrAlreadyVerified    = errors.New("email already verified")
+	ErrTooManyRequests    = errors.New("verification email sent too recently")
+	ErrInvalidToken       = errors.New("invalid or expired verification token")
+	ErrNotificationFailed = errors.New("failed to send verification email")
+)
+
+const tokenTTL = 24 * time.Hour
+
+// Limits of verification emails sent to an user
+const (
+	resendInterval = time.Minute
+	maxPerHour     = 5
+)
+
+// Send emails a verification token to the user, limited to one every
+// resendInterval and maxPerHour per hour.
+func (s *Service) Send(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		return ErrAlreadyVerified
+	}
+
+	now := time.Now()
+	recent, err := s.userTokens.CountSince(ctx, userID, models.PurposeEmailVerification, now.Add(-resendInterval))
+	if err != nil {
+		return err
+	}
+
+	lastHour, err := s.userTokens.CountSince(ctx, userID, models.PurposeEmailVerification, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if recent > 0 || lastHour >= maxPerHour {
+		return ErrTooManyRequests
+	}
+
+	token, err := s.userTokens.Issue(ctx, userID, user.Email, models.PurposeEmailVerification, tokenTTL)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf(
+		"Use the code %s to verify your email, it expires in %d hours.",
+		token,
+		int(tokenTTL.Hours()),
+	)
+	if err := s.notifier.Notify(&user, message); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotificationFailed, err)
+	}
+
+	return nil
+}
+
+// Verify marks the email of the token owner as verified, as long as it is
+// still the email the token was sent to.
+func (s *Service) Verify(ctx context.Context, token string) (uuid.UUID, error) {
+	userToken, err := s.userTokens.Use(ctx, token, models.PurposeEmailVerification)
+	if errors.Is(err, usertoken.ErrInvalidToken) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	user, err := s.repo.FindByID(ctx, userToken.UserID)
+	if err != nil {
+		return uuid.Nil, ErrUserNotFound
+	}
+
+	// The tokens sent to a previous email of the user do not verify the new one
+	if !strings.EqualFold(userToken.Email, user.Email) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	return user.ID, s.repo.MarkEmailVerified(ctx, user.ID, user.Email)
+}
@@ -0,0 +1,128 @@
+package verification_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/usertoken"
+	"github.com/edulustosa/go-pay/internal/services/verification"
+)
+
+type notifierStub struct {
+	messages []string
+}
+
+func (n *notifierStub) Notify(_ *models.User, message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func TestVerificationService(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	userTokensRepository := &repo.InMemoryUserTokensRepository{}
+	notifier := &notifierStub{}
+	sut := verification.NewService(
+		userRepository,
+		usertoken.NewService(userTokensRepository),
+		notifier,
+	)
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+	})
+
+	t.Run("should limit how often the token is sent", func(t *testing.T) {
+		if err := sut.Send(ctx, userID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sut.Send(ctx, userID); !errors.Is(err, verification.ErrTooManyRequests) {
+			t.Errorf("expected %v, got %v", verification.ErrTooManyRequests, err)
+		}
+
+		// Tokens issued more than a minute ago still count for the hourly limit
+		userTokensRepository.Tokens[0].CreatedAt.Time = time.Now().Add(-2 * time.Minute)
+
+		for range 4 {
+			if err := sut.Send(ctx, userID); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			tokens := userTokensRepository.Tokens
+			tokens[len(tokens)-1].CreatedAt.Time = time.Now().Add(-2 * time.Minute)
+		}
+
+		if err := sut.Send(ctx, userID); !errors.Is(err, verification.ErrTooManyRequests) {
+			t.Errorf("expected %v, got %v", verification.ErrTooManyRequests, err)
+		}
+	})
+
+	t.Run("should verify the email with the last token sent", func(t *testing.T) {
+		// Use the code <token> to verify your email...
+		previous := strings.Fields(notifier.messages[len(notifier.messages)-2])[3]
+		token := strings.Fields(notifier.messages[len(notifier.messages)-1])[3]
+
+		if _, err := sut.Verify(ctx, previous); !errors.Is(err, verification.ErrInvalidToken) {
+			t.Errorf("expected %v, got %v", verification.ErrInvalidToken, err)
+		}
+
+		verifiedID, err := sut.Verify(ctx, token)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if verifiedID != userID {
+			t.Errorf("expected the email of %v to be verified, got %v", userID, verifiedID)
+		}
+
+		user, _ := userRepository.FindByID(ctx, userID)
+		if !user.EmailVerifiedAt.Valid {
+			t.Errorf("expected email to be verified")
+		}
+
+		if _, err := sut.Verify(ctx, token); !errors.Is(err, verification.ErrInvalidToken) {
+			t.Errorf("expected %v, got %v", verification.ErrInvalidToken, err)
+		}
+
+		if err := sut.Send(ctx, userID); !errors.Is(err, verification.ErrAlreadyVerified) {
+			t.Errorf("expected %v, got %v", verification.ErrAlreadyVerified, err)
+		}
+	})
+	t.Run("should not verify an email changed after the token was sent", func(t *testing.T) {
+		userID, _ := userRepository.Create(ctx, models.User{
+			FirstName: "Jane",
+			LastName:  "Doe",
+			Email:     "janedoe@email.com",
+			Document:  "09876543211",
+		})
+
+		if err := sut.Send(ctx, userID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		token := strings.Fields(notifier.messages[len(notifier.messages)-1])[3]
+
+		user, _ := userRepository.FindByID(ctx, userID)
+		user.Email = "jane@email.com"
+		if err := userRepository.UpdateProfile(ctx, user); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := sut.Verify(ctx, token); !errors.Is(err, verification.ErrInvalidToken) {
+			t.Errorf("expected %v, got %v", verification.ErrInvalidToken, err)
+		}
+
+		user, _ = userRepository.FindByID(ctx, userID)
+		if user.EmailVerifiedAt.Valid {
+			t.Errorf("expected the new email not to be verified")
+		}
+	})
+}
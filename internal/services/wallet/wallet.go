@@ -0,0 +1,178 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/google/uuid"
+)
+
+// DefaultCurrency is the currency of the user balance, every
+// user holds a wallet in it since the account creation.
+const DefaultCurrency = money.BRL
+
+type walletsRepository interface {
+	Create(ctx context.Context, wallet models.Wallet) (uuid.UUID, error)
+	FindByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency string) (models.Wallet, error)
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]models.Wallet, error)
+	AddBalance(ctx context.Context, id uuid.UUID, amount float64) (float64, bool, error)
+}
+
+type userRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+	AddBalance(ctx context.Context, id uuid.UUID, amount float64) (float64, bool, error)
+}
+
+type Service struct {
+	repo  walletsRepository
+	users userRepository
+}
+
+func NewService(repo walletsRepository, users userRepository) *Service {
+	return &Service{
+		repo,
+		users,
+	}
+}
+
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrWalletAlreadyExists = errors.New("wallet already exists")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+)
+
+func (s *Service) Create(
+	ctx context.Context,
+	userID uuid.UUID,
+	currency string,
+) (uuid.UUID, error) {
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		return uuid.Nil, ErrUserNotFound
+	}
+
+	if currency == DefaultCurrency {
+		return uuid.Nil, ErrWalletAlreadyExists
+	}
+
+	_, err := s.repo.FindByUserAndCurrency(ctx, userID, currency)
+	if err == nil {
+		return uuid.Nil, ErrWalletAlreadyExists
+	}
+
+	return s.repo.Create(ctx, models.Wallet{
+		UserID:   userID,
+		Currency: currency,
+	})
+}
+
+// FindByUser returns every wallet of the user, starting with the default one.
+func (s *Service) FindByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.Wallet, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	wallets, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultWallet := models.Wallet{
+		UserID:    user.ID,
+		Currency:  DefaultCurrency,
+		Balance:   user.Balance,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+
+	return append([]models.Wallet{defaultWallet}, wallets...), nil
+}
+
+// Balance returns the user balance in the given currency.
+func (s *Service) Balance(
+	ctx context.Context,
+	user *models.User,
+	currency string,
+) (*money.Money, error) {
+	if currency == DefaultCurrency {
+		return money.NewFromFloat(user.Balance, currency), nil
+	}
+
+	wallet, err := s.repo.FindByUserAndCurrency(ctx, user.ID, currency)
+	if err != nil {
+		return nil, ErrWalletNotFound
+	}
+
+	return money.NewFromFloat(wallet.Balance, currency), nil
+}
+
+// Change is the balance of a wallet before and after an operation.
+type Change struct {
+	Currency string
+	Before   float64
+	After    float64
+}
+
+func newChange(balance float64, amount *money.Money) (Change, error) {
+	after := money.NewFromFloat(balance, amount.Currency().Code)
+	before, err := after.Subtract(amount)
+	if err != nil {
+		return Change{}, err
+	}
+
+	return Change{
+		Currency: amount.Currency().Code,
+		Before:   before.AsMajorUnits(),
+		After:    after.AsMajorUnits(),
+	}, nil
+}
+
+// Add adds the amount, which may be negative, to the user wallet in
+// the amount currency and returns how its balance changed. The balance
+// is changed by the database in a single statement, so concurrent
+// operations cannot lose an update or take the balance below zero.
+func (s *Service) Add(
+	ctx context.Context,
+	userID uuid.UUID,
+	amount *money.Money,
+) (Change, error) {
+	currency := amount.Currency().Code
+
+	if currency == DefaultCurrency {
+		balance, ok, err := s.users.AddBalance(ctx, userID, amount.AsMajorUnits())
+		if err != nil {
+			return Change{}, err
+		}
+
+		if !ok {
+			if _, err := s.users.FindByID(ctx, userID); err != nil {
+				return Change{}, ErrUserNotFound
+			}
+			return Change{}, ErrInsufficientFunds
+		}
+
+		return newChange(balance, amount)
+	}
+
+	wallet, err := s.repo.FindByUserAndCurrency(ctx, userID, currency)
+	if err != nil {
+		return Change{}, ErrWalletNotFound
+	}
+
+	balance, ok, err := s.repo.AddBalance(ctx, wallet.ID, amount.AsMajorUnits())
+	if err != nil {
+		return Change{}, err
+	}
+
+	if !ok {
+		return Change{}, ErrInsufficientFunds
+	}
+
+	return newChange(balance, amount)
+}
@@ -0,0 +1,78 @@
+package wallet_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/google/uuid"
+)
+
+func TestWalletService_Add(t *testing.T) {
+	userRepository := &repo.InMemoryUserRepository{}
+	walletsRepository := &repo.InMemoryWalletsRepository{}
+	sut := wallet.NewService(walletsRepository, userRepository)
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "johndoe@email.com",
+		Document:  "12345678900",
+		Balance:   100,
+	})
+
+	t.Run("should add to the balance in place", func(t *testing.T) {
+		change, err := sut.Add(ctx, userID, money.NewFromFloat(-30.1, money.BRL))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if change.Before != 100 || change.After != 69.9 || change.Currency != money.BRL {
+			t.Errorf("expected the balance to change from 100 to 69.9 BRL, got %+v", change)
+		}
+
+		user, _ := userRepository.FindByID(ctx, userID)
+		if user.Balance != 69.9 {
+			t.Errorf("expected balance to be 69.9, got %v", user.Balance)
+		}
+	})
+
+	t.Run("should not take the balance below zero", func(t *testing.T) {
+		_, err := sut.Add(ctx, userID, money.NewFromFloat(-70, money.BRL))
+		if !errors.Is(err, wallet.ErrInsufficientFunds) {
+			t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+		}
+
+		user, _ := userRepository.FindByID(ctx, userID)
+		if user.Balance != 69.9 {
+			t.Errorf("expected balance to stay 69.9, got %v", user.Balance)
+		}
+	})
+
+	t.Run("should not overdraw the other wallets", func(t *testing.T) {
+		if _, err := sut.Create(ctx, userID, money.USD); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := sut.Add(ctx, userID, money.NewFromFloat(10, money.USD)); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		_, err := sut.Add(ctx, userID, money.NewFromFloat(-10.01, money.USD))
+		if !errors.Is(err, wallet.ErrInsufficientFunds) {
+			t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+		}
+	})
+
+	t.Run("should not add to unknown users", func(t *testing.T) {
+		_, err := sut.Add(ctx, uuid.New(), money.NewFromFloat(10, money.BRL))
+		if !errors.Is(err, wallet.ErrUserNotFound) {
+			t.Errorf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
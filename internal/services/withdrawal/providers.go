@@ -0,0 +1,30 @@
+package withdrawal
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+)
+
+// BouncedAccountNumber is the account the FakePayout always bounces.
+const BouncedAccountNumber = "99999-9"
+
+// FakePayout settles payouts locally without moving any money,
+// bouncing the ones sent to the BouncedAccountNumber. The reference
+// is derived from the withdrawal ID, so retries get the same one.
+type FakePayout struct{}
+
+func (FakePayout) Payout(
+	_ context.Context,
+	withdrawal models.Withdrawal,
+	account models.BankAccount,
+) (string, error) {
+	if account.AccountNumber == BouncedAccountNumber {
+		return "", fmt.Errorf("%w: account %s does not exist", ErrPayoutBounced, account.AccountNumber)
+	}
+
+	return "PO" + strings.ToUpper(hex.EncodeToString(withdrawal.ID[:8])), nil
+}
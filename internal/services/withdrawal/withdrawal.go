@@ -0,0 +1,408 @@
+package withdrawal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Rhymond/go-money"
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/audit"
+	"github.com/edulustosa/go-pay/internal/services/kyc"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/google/uuid"
+)
+
+// PayoutProvider sends the money of a withdrawal to the bank account and
+// returns its reference. Errors wrapping ErrPayoutBounced are final and
+// release the held funds, any other error is retried later.
+//
+// A withdrawal may be sent again after a crash or a timeout, so providers
+// must be idempotent by the withdrawal ID, paying it out only once and
+// returning the same reference to the retries.
+type PayoutProvider interface {
+	Payout(ctx context.Context, withdrawal models.Withdrawal, account models.BankAccount) (string, error)
+}
+
+type withdrawalsRepository interface {
+	Create(ctx context.Context, withdrawal models.Withdrawal) (uuid.UUID, error)
+	FindByID(ctx context.Context, id uuid.UUID) (models.Withdrawal, error)
+	FindByStatus(ctx context.Context, status models.WithdrawalStatus, limit int) ([]models.Withdrawal, error)
+	UpdateStatus(ctx context.Context, withdrawal models.Withdrawal, from models.WithdrawalStatus) (bool, error)
+	ReleaseStale(ctx context.Context, before time.Time) (int, error)
+}
+
+type bankAccountsRepository interface {
+	Create(ctx context.Context, account models.BankAccount) (uuid.UUID, error)
+	FindByID(ctx context.Context, id uuid.UUID) (models.BankAccount, error)
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]models.BankAccount, error)
+}
+
+type transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type userService interface {
+	FindByID(ctx context.Context, id uuid.UUID) (models.User, error)
+}
+
+type walletService interface {
+	Balance(ctx context.Context, user *models.User, currency string) (*money.Money, error)
+	Add(ctx context.Context, userID uuid.UUID, amount *money.Money) (wallet.Change, error)
+}
+
+type auditService interface {
+	Record(ctx context.Context, entry audit.Entry) error
+}
+
+type Service struct {
+	repo     withdrawalsRepository
+	tx       transactor
+	accounts bankAccountsRepository
+	user     userService
+	wallet   walletService
+	payout   PayoutProvider
+	audit    auditService
+
+	// claimTimeout is how long a withdrawal stays claimed by a worker
+	// before it is given back to the queue, it must be longer than the
+	// payout takes, otherwise it is sent twice.
+	claimTimeout time.Duration
+}
+
+func NewService(
+	repo withdrawalsRepository,
+	tx transactor,
+	accounts bankAccountsRepository,
+	user userService,
+	wallet walletService,
+	payout PayoutProvider,
+	audit auditService,
+	claimTimeout time.Duration,
+) *Service {
+	return &Service{
+		repo,
+		tx,
+		accounts,
+		user,
+		wallet,
+		payout,
+		audit,
+		claimTimeout,
+	}
+}
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrBankAccountNotFound = errors.New("bank account not found")
+	ErrBankAccountExists   = errors.New("bank account already registered")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrWithdrawalNotFound  = errors.New("withdrawal not found")
+	ErrPayoutBounced       = errors.New("payout bounced")
+	ErrEmailNotVerified    = errors.New("email not verified")
+	ErrAccountFrozen       = errors.New("account frozen")
+	ErrKYCLevelRequired    = errors.New("kyc level does not allow withdrawals")
+)
+
+var errWithdrawalAlreadyClaimed = errors.New("withdrawal already claimed")
+
+// Number of pending withdrawals settled on each run
+const batchSize = 50
+
+func (s *Service) RegisterBankAccount(
+	ctx context.Context,
+	userID uuid.UUID,
+	accountDTO dtos.BankAccountDTO,
+) (uuid.UUID, error) {
+	if _, err := s.user.FindByID(ctx, userID); err != nil {
+		return uuid.Nil, ErrUserNotFound
+	}
+
+	accounts, err := s.accounts.FindByUser(ctx, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, account := range accounts {
+		if account.BankCode == accountDTO.BankCode &&
+			account.Branch == accountDTO.Branch &&
+			account.AccountNumber == accountDTO.AccountNumber {
+			return uuid.Nil, ErrBankAccountExists
+		}
+	}
+
+	accountType := accountDTO.AccountType
+	if accountType == "" {
+		accountType = models.AccountChecking
+	}
+
+	return s.accounts.Create(ctx, models.BankAccount{
+		UserID:        userID,
+		BankCode:      accountDTO.BankCode,
+		Branch:        accountDTO.Branch,
+		AccountNumber: accountDTO.AccountNumber,
+		AccountType:   accountType,
+	})
+}
+
+func (s *Service) FindBankAccounts(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]models.BankAccount, error) {
+	if _, err := s.user.FindByID(ctx, userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.accounts.FindByUser(ctx, userID)
+}
+
+// Request holds the amount from the user balance, returning how it
+// changed, and leaves the withdrawal pending until it is settled by
+// ProcessPending.
+func (s *Service) Request(
+	ctx context.Context,
+	withdrawalDTO dtos.WithdrawalDTO,
+) (models.Withdrawal, wallet.Change, error) {
+	user, err := s.user.FindByID(ctx, withdrawalDTO.User)
+	if err != nil {
+		return models.Withdrawal{}, wallet.Change{}, ErrUserNotFound
+	}
+
+	if !user.EmailVerifiedAt.Valid {
+		return models.Withdrawal{}, wallet.Change{}, ErrEmailNotVerified
+	}
+
+	if user.Status == models.StatusFrozen {
+		return models.Withdrawal{}, wallet.Change{}, ErrAccountFrozen
+	}
+
+	if !kyc.LimitsFor(user.KYCLevel).Withdrawals {
+		return models.Withdrawal{}, wallet.Change{}, ErrKYCLevelRequired
+	}
+
+	account, err := s.accounts.FindByID(ctx, withdrawalDTO.BankAccount)
+	if err != nil || account.UserID != user.ID {
+		return models.Withdrawal{}, wallet.Change{}, ErrBankAccountNotFound
+	}
+
+	amount := money.NewFromFloat(withdrawalDTO.Amount, wallet.DefaultCurrency)
+	balance, err := s.wallet.Balance(ctx, &user, wallet.DefaultCurrency)
+	if err != nil {
+		return models.Withdrawal{}, wallet.Change{}, err
+	}
+
+	insufficient, err := balance.LessThan(amount)
+	if err != nil {
+		return models.Withdrawal{}, wallet.Change{}, err
+	}
+	if insufficient {
+		return models.Withdrawal{}, wallet.Change{}, ErrInsufficientFunds
+	}
+
+	// The funds are held in the same database transaction that creates
+	// the withdrawal, so a pending withdrawal is never paid out without
+	// its hold. The hold fails if a concurrent operation spent the
+	// balance checked above.
+	var (
+		id     uuid.UUID
+		change wallet.Change
+	)
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		change, err = s.wallet.Add(ctx, user.ID, amount.Negative())
+		if errors.Is(err, wallet.ErrInsufficientFunds) {
+			return ErrInsufficientFunds
+		}
+		if err != nil {
+			return err
+		}
+
+		id, err = s.repo.Create(ctx, models.Withdrawal{
+			UserID:        user.ID,
+			BankAccountID: account.ID,
+			Amount:        amount.AsMajorUnits(),
+		})
+		return err
+	})
+	if err != nil {
+		return models.Withdrawal{}, wallet.Change{}, err
+	}
+
+	withdrawal, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.Withdrawal{}, wallet.Change{}, err
+	}
+
+	return withdrawal, change, nil
+}
+
+func (s *Service) FindByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (models.Withdrawal, error) {
+	withdrawal, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return models.Withdrawal{}, ErrWithdrawalNotFound
+	}
+
+	return withdrawal, nil
+}
+
+// Run settles the pending withdrawals on every interval until the context is done.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ProcessPending(ctx); err != nil {
+				slog.Error("failed to process withdrawals", "error", err)
+			}
+		}
+	}
+}
+
+// ProcessPending sends a batch of pending withdrawals to the payout provider,
+// after giving back to the queue the ones claimed for longer than the
+// timeout. Their worker may have died or failed to record the payout, which
+// the provider then recognizes by the withdrawal ID.
+func (s *Service) ProcessPending(ctx context.Context) error {
+	released, err := s.repo.ReleaseStale(ctx, time.Now().Add(-s.claimTimeout))
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		slog.Warn("stale withdrawals released", "count", released)
+	}
+
+	withdrawals, err := s.repo.FindByStatus(ctx, models.WithdrawalPending, batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, withdrawal := range withdrawals {
+		err := s.settle(ctx, withdrawal)
+		if err != nil && !errors.Is(err, errWithdrawalAlreadyClaimed) {
+			slog.Error("failed to settle withdrawal", "error", err, "withdrawal", withdrawal.ID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) settle(ctx context.Context, withdrawal models.Withdrawal) error {
+	// Claim the withdrawal so concurrent workers do not pay it twice
+	withdrawal.Status = models.WithdrawalProcessing
+	ok, err := s.repo.UpdateStatus(ctx, withdrawal, models.WithdrawalPending)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errWithdrawalAlreadyClaimed
+	}
+
+	account, err := s.accounts.FindByID(ctx, withdrawal.BankAccountID)
+	if err != nil {
+		return s.release(ctx, withdrawal, ErrBankAccountNotFound.Error())
+	}
+
+	reference, err := s.payout.Payout(ctx, withdrawal, account)
+	if errors.Is(err, ErrPayoutBounced) {
+		return s.release(ctx, withdrawal, err.Error())
+	}
+
+	if err != nil {
+		// Give the withdrawal back to the queue to be retried
+		withdrawal.Status = models.WithdrawalPending
+		if _, err := s.repo.UpdateStatus(ctx, withdrawal, models.WithdrawalProcessing); err != nil {
+			return err
+		}
+		return err
+	}
+
+	withdrawal.Status = models.WithdrawalCompleted
+	withdrawal.Reference = reference
+	ok, err = s.repo.UpdateStatus(ctx, withdrawal, models.WithdrawalProcessing)
+	if err != nil || !ok {
+		return err
+	}
+
+	s.record(ctx, models.AuditWithdrawalComplete, withdrawal,
+		map[string]any{"status": models.WithdrawalProcessing},
+		map[string]any{"status": withdrawal.Status, "reference": reference},
+	)
+	return nil
+}
+
+// Fails the withdrawal and gives the held amount back to the user
+// in a single database transaction.
+func (s *Service) release(
+	ctx context.Context,
+	withdrawal models.Withdrawal,
+	reason string,
+) error {
+	withdrawal.Status = models.WithdrawalFailed
+	withdrawal.FailureReason = reason
+
+	var (
+		released bool
+		change   wallet.Change
+	)
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		ok, err := s.repo.UpdateStatus(ctx, withdrawal, models.WithdrawalProcessing)
+		if err != nil || !ok {
+			return err
+		}
+
+		amount := money.NewFromFloat(withdrawal.Amount, wallet.DefaultCurrency)
+		change, err = s.wallet.Add(ctx, withdrawal.UserID, amount)
+		if err != nil {
+			return err
+		}
+
+		released = true
+		return nil
+	})
+	if err != nil || !released {
+		return err
+	}
+
+	s.record(ctx, models.AuditWithdrawalFail, withdrawal,
+		map[string]any{
+			"status":   models.WithdrawalProcessing,
+			"balance":  change.Before,
+			"currency": change.Currency,
+		},
+		map[string]any{
+			"status":   withdrawal.Status,
+			"reason":   reason,
+			"balance":  change.After,
+			"currency": change.Currency,
+		},
+	)
+	return nil
+}
+
+// Settled by the system, the events have no actor. Failures are logged
+// as the payout already happened.
+func (s *Service) record(
+	ctx context.Context,
+	action models.AuditAction,
+	withdrawal models.Withdrawal,
+	before, after map[string]any,
+) {
+	err := s.audit.Record(ctx, audit.Entry{
+		Action: action,
+		Target: withdrawal.ID,
+		Before: before,
+		After:  after,
+	})
+	if err != nil {
+		slog.Error("failed to record audit event", "error", err, "withdrawal", withdrawal.ID)
+	}
+}
@@ -0,0 +1,225 @@
+package withdrawal_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edulustosa/go-pay/internal/database/models"
+	"github.com/edulustosa/go-pay/internal/database/repo"
+	"github.com/edulustosa/go-pay/internal/dtos"
+	"github.com/edulustosa/go-pay/internal/services/audit"
+	"github.com/edulustosa/go-pay/internal/services/user"
+	"github.com/edulustosa/go-pay/internal/services/wallet"
+	"github.com/edulustosa/go-pay/internal/services/withdrawal"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var verified = pgtype.Timestamp{Time: time.Now(), Valid: true}
+
+func TestWithdrawalService(t *testing.T) {
+	withdrawalsRepository := &repo.InMemoryWithdrawalsRepository{}
+	userRepository := &repo.InMemoryUserRepository{}
+	eventsRepository := &repo.InMemoryAuditEventsRepository{}
+	sut := withdrawal.NewService(
+		withdrawalsRepository,
+		repo.InMemoryTransactor{},
+		&repo.InMemoryBankAccountsRepository{},
+		user.NewService(userRepository, user.DefaultConfig()),
+		wallet.NewService(&repo.InMemoryWalletsRepository{}, userRepository),
+		withdrawal.FakePayout{},
+		audit.NewService(eventsRepository),
+		time.Minute,
+	)
+
+	// lastEvent is the audit event of the last settled withdrawal
+	lastEvent := func() models.AuditEvent {
+		if len(eventsRepository.Events) == 0 {
+			return models.AuditEvent{}
+		}
+		return eventsRepository.Events[len(eventsRepository.Events)-1]
+	}
+
+	ctx := context.Background()
+	userID, _ := userRepository.Create(ctx, models.User{
+		FirstName:       "John",
+		LastName:        "Doe",
+		Email:           "johndoe@email.com",
+		Document:        "12345678900",
+		EmailVerifiedAt: verified,
+		KYCLevel:        models.KYCIntermediate,
+		Balance:         1000,
+	})
+
+	accountID, err := sut.RegisterBankAccount(ctx, userID, dtos.BankAccountDTO{
+		BankCode:      "001",
+		Branch:        "1234",
+		AccountNumber: "12345-6",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	bouncedID, err := sut.RegisterBankAccount(ctx, userID, dtos.BankAccountDTO{
+		BankCode:      "001",
+		Branch:        "1234",
+		AccountNumber: withdrawal.BouncedAccountNumber,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	t.Run("should hold the funds until the withdrawal is settled", func(t *testing.T) {
+		wd, balance, err := sut.Request(ctx, dtos.WithdrawalDTO{
+			User:        userID,
+			BankAccount: accountID,
+			Amount:      300,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		userModel, _ := userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 700 {
+			t.Errorf("expected balance to be 700, got %v", userModel.Balance)
+		}
+
+		if balance.Before != 1000 || balance.After != 700 {
+			t.Errorf("expected the balance to change from 1000 to 700, got %+v", balance)
+		}
+
+		if err := sut.ProcessPending(ctx); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		wd, _ = sut.FindByID(ctx, wd.ID)
+		if wd.Status != models.WithdrawalCompleted || wd.Reference == "" {
+			t.Errorf("expected completed withdrawal with reference, got %+v", wd)
+		}
+
+		if event := lastEvent(); event.Action != models.AuditWithdrawalComplete || event.TargetID != wd.ID {
+			t.Errorf("expected the settlement to be audited, got %+v", event)
+		}
+
+		userModel, _ = userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 700 {
+			t.Errorf("expected balance to be 700, got %v", userModel.Balance)
+		}
+	})
+
+	t.Run("should release the funds when the payout bounces", func(t *testing.T) {
+		wd, _, err := sut.Request(ctx, dtos.WithdrawalDTO{
+			User:        userID,
+			BankAccount: bouncedID,
+			Amount:      200,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if err := sut.ProcessPending(ctx); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		wd, _ = sut.FindByID(ctx, wd.ID)
+		if wd.Status != models.WithdrawalFailed || wd.FailureReason == "" {
+			t.Errorf("expected failed withdrawal with reason, got %+v", wd)
+		}
+
+		if event := lastEvent(); event.Action != models.AuditWithdrawalFail || event.TargetID != wd.ID {
+			t.Errorf("expected the bounce to be audited, got %+v", event)
+		}
+
+		userModel, _ := userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 700 {
+			t.Errorf("expected balance to be 700, got %v", userModel.Balance)
+		}
+	})
+
+	t.Run("should not withdraw more than the balance", func(t *testing.T) {
+		_, _, err := sut.Request(ctx, dtos.WithdrawalDTO{
+			User:        userID,
+			BankAccount: accountID,
+			Amount:      701,
+		})
+		if err != withdrawal.ErrInsufficientFunds {
+			t.Errorf("expected error to be ErrInsufficientFunds, got %v", err)
+		}
+	})
+	t.Run("should requeue the withdrawals claimed for longer than the timeout", func(t *testing.T) {
+		stale, _, err := sut.Request(ctx, dtos.WithdrawalDTO{
+			User:        userID,
+			BankAccount: accountID,
+			Amount:      50,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		fresh, _, err := sut.Request(ctx, dtos.WithdrawalDTO{
+			User:        userID,
+			BankAccount: accountID,
+			Amount:      50,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		// Claimed by workers, the first one died two minutes ago
+		for i, wd := range withdrawalsRepository.Withdrawals {
+			claimedAt := time.Now()
+			if wd.ID == stale.ID {
+				claimedAt = claimedAt.Add(-2 * time.Minute)
+			} else if wd.ID != fresh.ID {
+				continue
+			}
+
+			withdrawalsRepository.Withdrawals[i].Status = models.WithdrawalProcessing
+			withdrawalsRepository.Withdrawals[i].ClaimedAt = pgtype.Timestamp{Time: claimedAt, Valid: true}
+		}
+
+		if err := sut.ProcessPending(ctx); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		stale, _ = sut.FindByID(ctx, stale.ID)
+		if stale.Status != models.WithdrawalCompleted {
+			t.Errorf("expected the stale withdrawal to be completed, got %v", stale.Status)
+		}
+
+		fresh, _ = sut.FindByID(ctx, fresh.ID)
+		if fresh.Status != models.WithdrawalProcessing {
+			t.Errorf("expected the fresh withdrawal to stay processing, got %v", fresh.Status)
+		}
+
+		userModel, _ := userRepository.FindByID(ctx, userID)
+		if userModel.Balance != 600 {
+			t.Errorf("expected balance to be 600, got %v", userModel.Balance)
+		}
+	})
+
+	t.Run("should get the same payout reference on retries", func(t *testing.T) {
+		wd := models.Withdrawal{ID: uuid.New()}
+		account := models.BankAccount{AccountNumber: "12345-6"}
+
+		first, _ := withdrawal.FakePayout{}.Payout(ctx, wd, account)
+		second, _ := withdrawal.FakePayout{}.Payout(ctx, wd, account)
+		if first == "" || first != second {
+			t.Errorf("expected the same reference, got %q and %q", first, second)
+		}
+	})
+
+	t.Run("should require the intermediate kyc level", func(t *testing.T) {
+		userRepository.Users[0].KYCLevel = models.KYCBasic
+
+		_, _, err := sut.Request(ctx, dtos.WithdrawalDTO{
+			User:        userID,
+			BankAccount: accountID,
+			Amount:      100,
+		})
+		if err != withdrawal.ErrKYCLevelRequired {
+			t.Errorf("expected error to be ErrKYCLevelRequired, got %v", err)
+		}
+	})
+}